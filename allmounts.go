@@ -0,0 +1,9 @@
+package fsquota
+
+// MountQuotaInfo contains the quota information of an ID on a single filesystem
+type MountQuotaInfo struct {
+	// Mount point of the filesystem
+	MountPoint string
+	// Quota information on the filesystem
+	Info *Info
+}
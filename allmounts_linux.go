@@ -0,0 +1,62 @@
+package fsquota
+
+import (
+	"os"
+	"os/user"
+	"sort"
+	"syscall"
+
+	"github.com/speijnik/go-errortree"
+)
+
+// getInfoAllMounts retrieves the quota information of an ID from every mounted filesystem with quotas enabled.
+// Filesystems access is denied to, or which have no quota for the ID, are skipped.
+func getInfoAllMounts(t quotaCtlType, idString string) (infos []*MountQuotaInfo, err error) {
+	return collectInfoAllMounts(t, idString, quotaMountPoints, getQuota)
+}
+
+// collectInfoAllMounts retrieves the quota information of an ID from all mount points returned by mountPointsFn.
+// Errors other than EPERM and ESRCH are returned keyed by mount point, along with the information retrieved.
+// XFS reports IDs without quota as ENOENT instead of ESRCH, which is skipped as well.
+func collectInfoAllMounts(t quotaCtlType, idString string, mountPointsFn func(quotaCtlType) ([]string, error), getFn getQuotaFn) (infos []*MountQuotaInfo, err error) {
+	var mountPoints []string
+	if mountPoints, err = mountPointsFn(t); err != nil {
+		return
+	}
+
+	for _, mountPoint := range mountPoints {
+		info, getErr := getFn(t, mountPoint, idString)
+		if getErr != nil {
+			if !isSkippedMountError(getErr) {
+				err = errortree.Add(err, mountPoint, getErr)
+			}
+			continue
+		}
+
+		infos = append(infos, &MountQuotaInfo{
+			MountPoint: mountPoint,
+			Info:       info,
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].MountPoint < infos[j].MountPoint
+	})
+	return
+}
+
+// isSkippedMountError reports whether err indicates access to the quota has been denied or no quota exists
+func isSkippedMountError(err error) bool {
+	if scErr, isSCErr := err.(*os.SyscallError); isSCErr {
+		err = scErr.Err
+	}
+	return err == syscall.EPERM || err == syscall.ESRCH || err == syscall.ENOENT
+}
+
+func getUserInfoAllMounts(user *user.User) (infos []*MountQuotaInfo, err error) {
+	return getInfoAllMounts(userQuota, user.Uid)
+}
+
+func getGroupInfoAllMounts(group *user.Group) (infos []*MountQuotaInfo, err error) {
+	return getInfoAllMounts(groupQuota, group.Gid)
+}
@@ -0,0 +1,47 @@
+package fsquota
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/speijnik/go-errortree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectInfoAllMounts(t *testing.T) {
+	mountPointsFn := func(quotaCtlType) ([]string, error) {
+		return []string{"/srv", "/home", "/denied", "/none", "/broken"}, nil
+	}
+
+	brokenErr := errors.New("broken")
+	getFn := func(t quotaCtlType, path string, idString string) (*Info, error) {
+		switch path {
+		case "/denied":
+			return nil, os.NewSyscallError("quotactl", syscall.EPERM)
+		case "/none":
+			return nil, os.NewSyscallError("quotactl", syscall.ESRCH)
+		case "/broken":
+			return nil, brokenErr
+		}
+		return &Info{}, nil
+	}
+
+	infos, err := collectInfoAllMounts(userQuota, "1000", mountPointsFn, getFn)
+	if assert.Len(t, infos, 2) {
+		assert.EqualValues(t, "/home", infos[0].MountPoint)
+		assert.EqualValues(t, "/srv", infos[1].MountPoint)
+	}
+	if assert.Error(t, err) {
+		assert.EqualValues(t, brokenErr, errortree.Get(err, "/broken"))
+		assert.Nil(t, errortree.Get(err, "/denied"))
+		assert.Nil(t, errortree.Get(err, "/none"))
+	}
+
+	mountsErr := errors.New("mountinfo unavailable")
+	_, err = collectInfoAllMounts(userQuota, "1000", func(quotaCtlType) ([]string, error) {
+		return nil, mountsErr
+	}, getFn)
+	assert.EqualValues(t, mountsErr, err)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Operation is an action a grant may permit
+type Operation string
+
+const (
+	// OperationGet permits retrieving the quota of a single ID
+	OperationGet Operation = "get"
+	// OperationReport permits retrieving quota reports
+	OperationReport Operation = "report"
+	// OperationSet permits setting limits
+	OperationSet Operation = "set"
+	// OperationClear permits removing limits
+	OperationClear Operation = "clear"
+	// OperationForce permits changes violating the quota policy
+	OperationForce Operation = "force"
+)
+
+// Wildcard grants all mounts or operations when listed in a grant
+const Wildcard = "*"
+
+// Grant authorizes the holder of a token or client certificate
+type Grant struct {
+	// Name identifies the grant
+	Name string
+	// Token authenticates requests bearing it, if set
+	Token string
+	// CertificateSubject authenticates requests with a verified client certificate of this common name, if set
+	CertificateSubject string
+	// Mounts the grant applies to. Paths are resolved to the filesystem they are on, so a grant covers the whole
+	// filesystem a mount is on, as quotas are managed per filesystem.
+	Mounts []string
+	// Operations permitted on the mounts
+	Operations []Operation
+}
+
+// allowsMount reports whether the filesystem mounted at mountPoint is one of the granted mounts.
+// Granted mounts are resolved to the mount point of their filesystem via mountPointFn, mounts which cannot be resolved
+// grant nothing.
+func (g *Grant) allowsMount(mountPoint string, mountPointFn func(path string) (string, error)) bool {
+	for _, mount := range g.Mounts {
+		if mount == Wildcard {
+			return true
+		}
+
+		if granted, err := mountPointFn(mount); err == nil && granted == mountPoint {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsOperation reports whether op is granted
+func (g *Grant) allowsOperation(op Operation) bool {
+	for _, granted := range g.Operations {
+		if granted == op || granted == Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+}
+
+// authenticate returns the grant matching the token or verified client certificate of r, nil if there is none
+func authenticate(grants []*Grant, r *http.Request) *Grant {
+	if token := bearerToken(r); token != "" {
+		var match *Grant
+		// Every token is compared in constant time, so timing does not reveal which one matched
+		for _, g := range grants {
+			if g.Token != "" && subtle.ConstantTimeCompare([]byte(g.Token), []byte(token)) == 1 && match == nil {
+				match = g
+			}
+		}
+		return match
+	}
+
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	subject := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	for _, g := range grants {
+		if g.CertificateSubject != "" && g.CertificateSubject == subject {
+			return g
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+)
+
+// Client accesses a remote quota API server
+type Client struct {
+	// DryRun makes the server compute changes without applying them
+	DryRun bool
+
+	server     string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the server at the given base URL. The token is sent as bearer token
+// if set, tlsConfig may carry a client certificate and trusted CAs.
+func NewClient(server, token string, tlsConfig *tls.Config) *Client {
+	return &Client{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+}
+
+func quotaURL(path string, t fsquota.QuotaType, id string) string {
+	u := quotasPrefix + t.String()
+	if id != "" {
+		u += "/" + url.PathEscape(id)
+	}
+	return u + "?" + url.Values{"path": []string{path}}.Encode()
+}
+
+// changeURL returns the URL of requests changing the quota of an ID
+func (c *Client) changeURL(path string, t fsquota.QuotaType, id string) string {
+	u := quotaURL(path, t, id)
+	if c.DryRun {
+		u += "&dry_run=true"
+	}
+	return u
+}
+
+// do sends a request and decodes the response into result
+func (c *Client) do(method, requestURL string, body interface{}, result interface{}) (err error) {
+	var bodyReader io.Reader
+	if body != nil {
+		var data []byte
+		if data, err = json.Marshal(body); err != nil {
+			return
+		}
+		bodyReader = bytes.NewReader(data)
+	}
+
+	var request *http.Request
+	if request, err = http.NewRequest(method, c.server+requestURL, bodyReader); err != nil {
+		return
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	var response *http.Response
+	if response, err = c.httpClient.Do(request); err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		apiErr := &Error{}
+		if decodeErr := json.NewDecoder(response.Body).Decode(apiErr); decodeErr != nil {
+			apiErr.Message = response.Status
+		}
+		apiErr.StatusCode = response.StatusCode
+		err = apiErr
+		return
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// Health queries the health endpoint of the server
+func (c *Client) Health() (health *Health, err error) {
+	health = &Health{}
+	if err = c.do(http.MethodGet, "/v1/health", nil, health); err != nil {
+		health = nil
+	}
+	return
+}
+
+// GetQuota retrieves the quota of an ID, given by number or name
+func (c *Client) GetQuota(path string, t fsquota.QuotaType, id string) (quota *Quota, err error) {
+	quota = &Quota{}
+	if err = c.do(http.MethodGet, quotaURL(path, t, id), nil, quota); err != nil {
+		quota = nil
+	}
+	return
+}
+
+// GetReport retrieves the quotas of all IDs of a type
+func (c *Client) GetReport(path string, t fsquota.QuotaType) (report *Report, err error) {
+	report = &Report{}
+	if err = c.do(http.MethodGet, quotaURL(path, t, ""), nil, report); err != nil {
+		report = nil
+	}
+	return
+}
+
+// SetQuota sets the limits of an ID
+func (c *Client) SetQuota(path string, t fsquota.QuotaType, id string, request *SetRequest) (quota *Quota, err error) {
+	quota = &Quota{}
+	if err = c.do(http.MethodPut, c.changeURL(path, t, id), request, quota); err != nil {
+		quota = nil
+	}
+	return
+}
+
+// ClearQuota removes all limits of an ID
+func (c *Client) ClearQuota(path string, t fsquota.QuotaType, id string) (quota *Quota, err error) {
+	quota = &Quota{}
+	if err = c.do(http.MethodDelete, c.changeURL(path, t, id), nil, quota); err != nil {
+		quota = nil
+	}
+	return
+}
@@ -0,0 +1,24 @@
+/*
+Package api implements a REST/JSON API for managing filesystem quotas remotely, together with a client for it.
+
+All quota endpoints take the filesystem path as the path query parameter. The type segment is one of
+user, group or project; user and group IDs may also be given by name.
+PUT and DELETE requests with the dry_run=true query parameter return the expected result without
+changing the quota.
+
+	GET    /v1/health                     Reports the server is up, no authentication required
+	GET    /v1/quotas/{type}?path=...      Quota report of all IDs of the given type
+	GET    /v1/quotas/{type}/{id}?path=... Quota of a single ID
+	PUT    /v1/quotas/{type}/{id}?path=... Sets the limits of an ID, the body is a SetRequest
+	DELETE /v1/quotas/{type}/{id}?path=... Removes all limits of an ID
+
+Successful responses carry a Quota, Report or Health document. Failed requests are answered with an
+Error document and a matching status code: 400 for malformed requests, 401 if authentication failed,
+403 if the operation is not granted, 404 for unknown users or groups and 422 if a change violates
+the quota policy.
+
+Requests authenticate with a bearer token in the Authorization header or a client certificate
+verified by the server. Each Grant restricts the mounts and operations available to a token or
+certificate.
+*/
+package api
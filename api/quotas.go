@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"os/user"
+	"strconv"
+
+	"github.com/anexia-it/fsquota"
+)
+
+// errUnknownQuotaType is returned for quota types not known to fsquota
+var errUnknownQuotaType = errors.New("unknown quota type")
+
+// quotaBackend carries out the quota operations served by the API
+type quotaBackend struct {
+	getFn     func(path string, t fsquota.QuotaType, id string) (*fsquota.Info, error)
+	reportFn  func(path string, t fsquota.QuotaType) (*fsquota.Report, error)
+	setFn     func(path string, t fsquota.QuotaType, id string, limits *fsquota.Limits, opts []fsquota.SetOption) (*fsquota.Info, error)
+	clearFn   func(path string, t fsquota.QuotaType, id string, opts []fsquota.SetOption) (*fsquota.Info, error)
+	resolveFn func(t fsquota.QuotaType, idOrName string) (string, error)
+	nameFn    func(t fsquota.QuotaType, id string) string
+	// mountPointFn resolves a path to the mount point of its filesystem
+	mountPointFn func(path string) (string, error)
+}
+
+// systemBackend operates on the quotas of the local system
+var systemBackend = &quotaBackend{
+	getFn:     getInfo,
+	reportFn:  fsquota.GetReport,
+	setFn:     setQuota,
+	clearFn:   clearQuota,
+	resolveFn: resolveID,
+	nameFn:    fsquota.LookupQuotaName,
+
+	mountPointFn: fsquota.MountPoint,
+}
+
+func projectID(id string) (projectID uint32, err error) {
+	var parsed uint64
+	if parsed, err = strconv.ParseUint(id, 10, 32); err == nil {
+		projectID = uint32(parsed)
+	}
+	return
+}
+
+func getInfo(path string, t fsquota.QuotaType, id string) (info *fsquota.Info, err error) {
+	switch t {
+	case fsquota.QuotaTypeUser:
+		return fsquota.GetUserInfo(path, &user.User{Uid: id})
+	case fsquota.QuotaTypeGroup:
+		return fsquota.GetGroupInfo(path, &user.Group{Gid: id})
+	case fsquota.QuotaTypeProject:
+		var project uint32
+		if project, err = projectID(id); err != nil {
+			return
+		}
+		return fsquota.GetProjectInfo(path, project)
+	}
+	err = errUnknownQuotaType
+	return
+}
+
+func setQuota(path string, t fsquota.QuotaType, id string, limits *fsquota.Limits, opts []fsquota.SetOption) (info *fsquota.Info, err error) {
+	switch t {
+	case fsquota.QuotaTypeUser:
+		return fsquota.SetUserQuota(path, &user.User{Uid: id}, *limits, opts...)
+	case fsquota.QuotaTypeGroup:
+		return fsquota.SetGroupQuota(path, &user.Group{Gid: id}, *limits, opts...)
+	case fsquota.QuotaTypeProject:
+		var project uint32
+		if project, err = projectID(id); err != nil {
+			return
+		}
+		return fsquota.SetProjectQuota(path, project, limits, opts...)
+	}
+	err = errUnknownQuotaType
+	return
+}
+
+func clearQuota(path string, t fsquota.QuotaType, id string, opts []fsquota.SetOption) (info *fsquota.Info, err error) {
+	switch t {
+	case fsquota.QuotaTypeUser:
+		return fsquota.ClearUserQuota(path, &user.User{Uid: id}, opts...)
+	case fsquota.QuotaTypeGroup:
+		return fsquota.ClearGroupQuota(path, &user.Group{Gid: id}, opts...)
+	case fsquota.QuotaTypeProject:
+		var project uint32
+		if project, err = projectID(id); err != nil {
+			return
+		}
+		return fsquota.ClearProjectQuota(path, project, opts...)
+	}
+	err = errUnknownQuotaType
+	return
+}
+
+// resolveID converts a user or group name to its numeric ID, numeric IDs are returned unchanged
+func resolveID(t fsquota.QuotaType, idOrName string) (id string, err error) {
+	if _, parseErr := strconv.ParseUint(idOrName, 10, 32); parseErr == nil {
+		id = idOrName
+		return
+	}
+
+	switch t {
+	case fsquota.QuotaTypeUser:
+		var u *user.User
+		if u, err = user.Lookup(idOrName); err == nil {
+			id = u.Uid
+		}
+		return
+	case fsquota.QuotaTypeGroup:
+		var g *user.Group
+		if g, err = user.LookupGroup(idOrName); err == nil {
+			id = g.Gid
+		}
+		return
+	}
+
+	err = errors.New("project IDs must be numeric")
+	return
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anexia-it/fsquota"
+)
+
+const quotasPrefix = "/v1/quotas/"
+
+// Server serves the quota API
+type Server struct {
+	grants  []*Grant
+	backend *quotaBackend
+	mux     *http.ServeMux
+}
+
+// NewServer creates a server managing the local quotas, authorizing requests by grants
+func NewServer(grants []*Grant) *Server {
+	return newServer(grants, systemBackend)
+}
+
+func newServer(grants []*Grant, backend *quotaBackend) *Server {
+	s := &Server{
+		grants:  grants,
+		backend: backend,
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/v1/health", s.handleHealth)
+	s.mux.HandleFunc(quotasPrefix, s.handleQuotas)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	apiErr, ok := err.(*Error)
+	if !ok {
+		apiErr = &Error{
+			Message: err.Error(),
+		}
+	}
+	if policyErr, ok := err.(*fsquota.PolicyError); ok {
+		apiErr.Violations = policyErr.Violations
+	}
+	writeJSON(w, status, apiErr)
+}
+
+// errorStatus maps errors returned by fsquota to status codes
+func errorStatus(err error) int {
+	switch e := err.(type) {
+	case *Error:
+		return e.StatusCode
+	case *fsquota.PolicyError:
+		return http.StatusUnprocessableEntity
+	case user.UnknownUserError, user.UnknownGroupError:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &Health{
+		Status:  "ok",
+		Version: fsquota.VersionString(),
+	})
+}
+
+// operationFor returns the operation requested by method on a report (hasID false) or a single ID
+func operationFor(method string, hasID bool) (op Operation, ok bool) {
+	switch {
+	case !hasID && method == http.MethodGet:
+		return OperationReport, true
+	case hasID && method == http.MethodGet:
+		return OperationGet, true
+	case hasID && method == http.MethodPut:
+		return OperationSet, true
+	case hasID && method == http.MethodDelete:
+		return OperationClear, true
+	}
+	return
+}
+
+func (s *Server) handleQuotas(w http.ResponseWriter, r *http.Request) {
+	grant := authenticate(s.grants, r)
+	if grant == nil {
+		writeError(w, http.StatusUnauthorized, errors.New("authentication required"))
+		return
+	}
+
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, quotasPrefix), "/")
+	if len(segments) > 2 || segments[0] == "" || (len(segments) == 2 && segments[1] == "") {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	var t fsquota.QuotaType
+	if err := t.UnmarshalText([]byte(segments[0])); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	op, ok := operationFor(r.Method, len(segments) == 2)
+	if !ok {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		writeError(w, http.StatusBadRequest, errors.New("path parameter required"))
+		return
+	}
+
+	// Paths are compared by the filesystem they are on, as symlinks and ".." could otherwise escape a granted mount.
+	// Paths which cannot be resolved are rejected like paths outside of the granted mounts.
+	mountPoint, err := s.backend.mountPointFn(path)
+	if err != nil || !grant.allowsMount(mountPoint, s.backend.mountPointFn) || !grant.allowsOperation(op) {
+		writeError(w, http.StatusForbidden, errors.New("operation not permitted"))
+		return
+	}
+
+	// The resolved mount point is operated on, so the path cannot be redirected after the check
+	path = mountPoint
+
+	if op == OperationReport {
+		s.report(w, path, t)
+		return
+	}
+
+	id, err := s.backend.resolveFn(t, segments[1])
+	if err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	var info *fsquota.Info
+	switch op {
+	case OperationGet:
+		info, err = s.backend.getFn(path, t, id)
+	case OperationSet:
+		info, err = s.set(r, grant, path, t, id)
+	case OperationClear:
+		var opts []fsquota.SetOption
+		if opts, err = dryRunOptions(r); err == nil {
+			info, err = s.backend.clearFn(path, t, id, opts)
+		}
+	}
+
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newQuota(id, s.backend.nameFn(t, id), info))
+}
+
+// dryRunOptions returns the options of requests asking for changes to be computed without applying them
+func dryRunOptions(r *http.Request) (opts []fsquota.SetOption, err error) {
+	value := r.URL.Query().Get("dry_run")
+	if value == "" {
+		return
+	}
+
+	dryRun, parseErr := strconv.ParseBool(value)
+	if parseErr != nil {
+		err = &Error{StatusCode: http.StatusBadRequest, Message: "dry_run must be true or false"}
+		return
+	}
+
+	if dryRun {
+		opts = append(opts, fsquota.DryRun(nil))
+	}
+	return
+}
+
+func (s *Server) set(r *http.Request, grant *Grant, path string, t fsquota.QuotaType, id string) (info *fsquota.Info, err error) {
+	request := &SetRequest{}
+	if decodeErr := json.NewDecoder(r.Body).Decode(request); decodeErr != nil {
+		err = &Error{StatusCode: http.StatusBadRequest, Message: decodeErr.Error()}
+		return
+	}
+
+	limits, ok := request.limits()
+	if !ok {
+		err = &Error{StatusCode: http.StatusBadRequest, Message: "nothing to set"}
+		return
+	}
+
+	var opts []fsquota.SetOption
+	if opts, err = dryRunOptions(r); err != nil {
+		return
+	}
+
+	if request.Force {
+		if !grant.allowsOperation(OperationForce) {
+			err = &Error{StatusCode: http.StatusForbidden, Message: "operation not permitted"}
+			return
+		}
+		opts = append(opts, fsquota.OverridePolicy())
+	}
+
+	return s.backend.setFn(path, t, id, limits, opts)
+}
+
+func (s *Server) report(w http.ResponseWriter, path string, t fsquota.QuotaType) {
+	report, err := s.backend.reportFn(path, t)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	ids := make([]string, 0, len(report.Infos))
+	for id := range report.Infos {
+		ids = append(ids, id)
+	}
+	// IDs are sorted numerically, so reports are stable
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseUint(ids[i], 10, 64)
+		b, _ := strconv.ParseUint(ids[j], 10, 64)
+		if a != b {
+			return a < b
+		}
+		return ids[i] < ids[j]
+	})
+
+	result := &Report{
+		Path:   path,
+		Type:   t,
+		Quotas: make([]*Quota, 0, len(ids)),
+	}
+	for _, id := range ids {
+		result.Quotas = append(result.Quotas, newQuota(id, s.backend.nameFn(t, id), report.Infos[id]))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/user"
+	"testing"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBackend keeps user quotas of a single path in memory
+func fakeBackend(infos map[string]*fsquota.Info) *quotaBackend {
+	return &quotaBackend{
+		getFn: func(path string, t fsquota.QuotaType, id string) (*fsquota.Info, error) {
+			if info, ok := infos[id]; ok {
+				return info, nil
+			}
+			return &fsquota.Info{}, nil
+		},
+		reportFn: func(path string, t fsquota.QuotaType) (*fsquota.Report, error) {
+			return &fsquota.Report{Infos: infos}, nil
+		},
+		setFn: func(path string, t fsquota.QuotaType, id string, limits *fsquota.Limits, opts []fsquota.SetOption) (*fsquota.Info, error) {
+			if limits.Bytes.GetHard() > 1000 && len(opts) == 0 {
+				return nil, &fsquota.PolicyError{Type: t, ID: id, Violations: []string{"too large"}}
+			}
+			info := &fsquota.Info{}
+			info.Bytes.SetSoft(limits.Bytes.GetSoft())
+			info.Bytes.SetHard(limits.Bytes.GetHard())
+			infos[id] = info
+			return info, nil
+		},
+		clearFn: func(path string, t fsquota.QuotaType, id string, opts []fsquota.SetOption) (*fsquota.Info, error) {
+			delete(infos, id)
+			return &fsquota.Info{}, nil
+		},
+		resolveFn: func(t fsquota.QuotaType, idOrName string) (string, error) {
+			if idOrName == "alice" {
+				return "1000", nil
+			}
+			if idOrName == "bob" {
+				return "", user.UnknownUserError("bob")
+			}
+			return idOrName, nil
+		},
+		nameFn: func(t fsquota.QuotaType, id string) string {
+			if id == "1000" {
+				return "alice"
+			}
+			return ""
+		},
+		mountPointFn: testMountPoint,
+	}
+}
+
+// testMountPoint resolves paths on the fake mounts /srv, /srv2 and /home, /srv/home-link links to /home
+func testMountPoint(path string) (string, error) {
+	switch path {
+	case "/srv", "/srv/", "/srv/data":
+		return "/srv", nil
+	case "/srv2":
+		return "/srv2", nil
+	case "/home", "/home/", "/srv/home-link":
+		return "/home", nil
+	}
+	return "", errors.New("no such file or directory")
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
+func testServer() (client func(token string) *Client, closeFn func()) {
+	info := &fsquota.Info{BytesUsed: 10}
+	info.Bytes.SetSoft(100)
+	info.Bytes.SetHard(200)
+
+	grants := []*Grant{
+		{Name: "admin", Token: "admin-token", Mounts: []string{Wildcard}, Operations: []Operation{Wildcard}},
+		{Name: "reader", Token: "reader-token", Mounts: []string{"/srv/"}, Operations: []Operation{OperationGet, OperationReport}},
+	}
+
+	server := httptest.NewServer(newServer(grants, fakeBackend(map[string]*fsquota.Info{
+		"1000": info,
+		"2":    {},
+	})))
+
+	client = func(token string) *Client {
+		return NewClient(server.URL, token, nil)
+	}
+	return client, server.Close
+}
+
+func assertStatus(t *testing.T, status int, err error) {
+	if assert.IsType(t, &Error{}, err) {
+		assert.EqualValues(t, status, err.(*Error).StatusCode)
+	}
+}
+
+func TestServer_Health(t *testing.T) {
+	client, closeFn := testServer()
+	defer closeFn()
+
+	health, err := client("").Health()
+	require.NoError(t, err)
+	assert.EqualValues(t, "ok", health.Status)
+	assert.EqualValues(t, fsquota.VersionString(), health.Version)
+}
+
+func TestServer_Authentication(t *testing.T) {
+	client, closeFn := testServer()
+	defer closeFn()
+
+	_, err := client("").GetQuota("/srv", fsquota.QuotaTypeUser, "1000")
+	assertStatus(t, http.StatusUnauthorized, err)
+
+	_, err = client("invalid").GetQuota("/srv", fsquota.QuotaTypeUser, "1000")
+	assertStatus(t, http.StatusUnauthorized, err)
+
+	// The reader may only read below /srv
+	_, err = client("reader-token").GetQuota("/srv/data", fsquota.QuotaTypeUser, "1000")
+	assert.NoError(t, err)
+	_, err = client("reader-token").GetQuota("/srv2", fsquota.QuotaTypeUser, "1000")
+	assertStatus(t, http.StatusForbidden, err)
+	// Symlinks are resolved before the mount is checked
+	_, err = client("reader-token").GetQuota("/srv/home-link", fsquota.QuotaTypeUser, "1000")
+	assertStatus(t, http.StatusForbidden, err)
+	_, err = client("reader-token").GetQuota("/srv/unresolvable", fsquota.QuotaTypeUser, "1000")
+	assertStatus(t, http.StatusForbidden, err)
+	_, err = client("reader-token").ClearQuota("/srv", fsquota.QuotaTypeUser, "1000")
+	assertStatus(t, http.StatusForbidden, err)
+}
+
+func TestServer_Quotas(t *testing.T) {
+	client, closeFn := testServer()
+	defer closeFn()
+	admin := client("admin-token")
+
+	quota, err := admin.GetQuota("/srv", fsquota.QuotaTypeUser, "alice")
+	require.NoError(t, err)
+	assert.EqualValues(t, &Quota{ID: "1000", Name: "alice", BytesSoft: 100, BytesHard: 200, BytesUsed: 10}, quota)
+
+	_, err = admin.GetQuota("/srv", fsquota.QuotaTypeUser, "bob")
+	assertStatus(t, http.StatusNotFound, err)
+
+	report, err := admin.GetReport("/srv", fsquota.QuotaTypeUser)
+	require.NoError(t, err)
+	if assert.Len(t, report.Quotas, 2) {
+		assert.EqualValues(t, "2", report.Quotas[0].ID)
+		assert.EqualValues(t, "1000", report.Quotas[1].ID)
+	}
+
+	quota, err = admin.SetQuota("/srv", fsquota.QuotaTypeUser, "1001", &SetRequest{BytesSoft: uint64Ptr(500), BytesHard: uint64Ptr(600)})
+	require.NoError(t, err)
+	assert.EqualValues(t, 600, quota.BytesHard)
+
+	_, err = admin.SetQuota("/srv", fsquota.QuotaTypeUser, "1001", &SetRequest{})
+	assertStatus(t, http.StatusBadRequest, err)
+
+	_, err = admin.SetQuota("/srv", fsquota.QuotaTypeUser, "1001", &SetRequest{BytesHard: uint64Ptr(2000)})
+	assertStatus(t, http.StatusUnprocessableEntity, err)
+	assert.EqualValues(t, []string{"too large"}, err.(*Error).Violations)
+
+	quota, err = admin.SetQuota("/srv", fsquota.QuotaTypeUser, "1001", &SetRequest{BytesHard: uint64Ptr(2000), Force: true})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2000, quota.BytesHard)
+
+	_, err = admin.ClearQuota("/srv", fsquota.QuotaTypeUser, "1001")
+	require.NoError(t, err)
+}
+
+func TestDryRunOptions(t *testing.T) {
+	opts, err := dryRunOptions(httptest.NewRequest(http.MethodPut, "/v1/quotas/user/1000?path=/srv", nil))
+	assert.NoError(t, err)
+	assert.Empty(t, opts)
+
+	opts, err = dryRunOptions(httptest.NewRequest(http.MethodPut, "/v1/quotas/user/1000?path=/srv&dry_run=true", nil))
+	assert.NoError(t, err)
+	assert.Len(t, opts, 1)
+
+	_, err = dryRunOptions(httptest.NewRequest(http.MethodDelete, "/v1/quotas/user/1000?path=/srv&dry_run=maybe", nil))
+	assertStatus(t, http.StatusBadRequest, err)
+}
+
+func TestGrant_allowsMount(t *testing.T) {
+	g := &Grant{Mounts: []string{"/srv", "/nonexistent"}}
+	assert.True(t, g.allowsMount("/srv", testMountPoint))
+	assert.False(t, g.allowsMount("/srv2", testMountPoint))
+	assert.False(t, g.allowsMount("/home", testMountPoint))
+	assert.False(t, (&Grant{}).allowsMount("/srv", testMountPoint))
+	assert.True(t, (&Grant{Mounts: []string{Wildcard}}).allowsMount("/home", testMountPoint))
+}
+
+func TestQuota_Info(t *testing.T) {
+	info := &fsquota.Info{BytesUsed: 1, FilesUsed: 2}
+	info.Bytes.SetSoft(3)
+	info.Bytes.SetHard(4)
+	info.Files.SetSoft(5)
+	info.Files.SetHard(6)
+
+	converted := newQuota("1", "", info).Info()
+	assert.EqualValues(t, 1, converted.BytesUsed)
+	assert.EqualValues(t, 2, converted.FilesUsed)
+	assert.EqualValues(t, 3, converted.Bytes.GetSoft())
+	assert.EqualValues(t, 4, converted.Bytes.GetHard())
+	assert.EqualValues(t, 5, converted.Files.GetSoft())
+	assert.EqualValues(t, 6, converted.Files.GetHard())
+	assert.True(t, converted.BytesGraceExpires.IsZero())
+}
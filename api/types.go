@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+)
+
+// Quota contains the limits and usage of a single ID
+type Quota struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+
+	BytesSoft uint64 `json:"bytes_soft"`
+	BytesHard uint64 `json:"bytes_hard"`
+	BytesUsed uint64 `json:"bytes_used"`
+	FilesSoft uint64 `json:"files_soft"`
+	FilesHard uint64 `json:"files_hard"`
+	FilesUsed uint64 `json:"files_used"`
+
+	BytesGraceExpires *time.Time `json:"bytes_grace_expires,omitempty"`
+	FilesGraceExpires *time.Time `json:"files_grace_expires,omitempty"`
+
+	InheritsDefaultLimits bool `json:"inherits_default_limits,omitempty"`
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func newQuota(id, name string, info *fsquota.Info) *Quota {
+	return &Quota{
+		ID:                    id,
+		Name:                  name,
+		BytesSoft:             info.Bytes.GetSoft(),
+		BytesHard:             info.Bytes.GetHard(),
+		BytesUsed:             info.BytesUsed,
+		FilesSoft:             info.Files.GetSoft(),
+		FilesHard:             info.Files.GetHard(),
+		FilesUsed:             info.FilesUsed,
+		BytesGraceExpires:     timeOrNil(info.BytesGraceExpires),
+		FilesGraceExpires:     timeOrNil(info.FilesGraceExpires),
+		InheritsDefaultLimits: info.InheritsDefaultLimits,
+	}
+}
+
+// Info converts the quota to the representation used by fsquota
+func (q *Quota) Info() *fsquota.Info {
+	info := &fsquota.Info{
+		BytesUsed:             q.BytesUsed,
+		FilesUsed:             q.FilesUsed,
+		InheritsDefaultLimits: q.InheritsDefaultLimits,
+	}
+	info.Bytes.SetSoft(q.BytesSoft)
+	info.Bytes.SetHard(q.BytesHard)
+	info.Files.SetSoft(q.FilesSoft)
+	info.Files.SetHard(q.FilesHard)
+
+	if q.BytesGraceExpires != nil {
+		info.BytesGraceExpires = *q.BytesGraceExpires
+	}
+	if q.FilesGraceExpires != nil {
+		info.FilesGraceExpires = *q.FilesGraceExpires
+	}
+	return info
+}
+
+// Report contains the quotas of all IDs of a type, sorted by ID
+type Report struct {
+	Path   string            `json:"path"`
+	Type   fsquota.QuotaType `json:"type"`
+	Quotas []*Quota          `json:"quotas"`
+}
+
+// SetRequest is the body of a request setting limits. Limits left out keep their current value.
+type SetRequest struct {
+	BytesSoft *uint64 `json:"bytes_soft,omitempty"`
+	BytesHard *uint64 `json:"bytes_hard,omitempty"`
+	FilesSoft *uint64 `json:"files_soft,omitempty"`
+	FilesHard *uint64 `json:"files_hard,omitempty"`
+
+	// Force applies the change even if it violates the quota policy. It requires the force operation.
+	Force bool `json:"force,omitempty"`
+}
+
+// limits converts the request to limits, ok is false if no limit is set
+func (r *SetRequest) limits() (limits *fsquota.Limits, ok bool) {
+	limits = &fsquota.Limits{}
+	for _, l := range []struct {
+		value *uint64
+		setFn func(uint64)
+	}{
+		{r.BytesSoft, limits.Bytes.SetSoft},
+		{r.BytesHard, limits.Bytes.SetHard},
+		{r.FilesSoft, limits.Files.SetSoft},
+		{r.FilesHard, limits.Files.SetHard},
+	} {
+		if l.value != nil {
+			l.setFn(*l.value)
+			ok = true
+		}
+	}
+	return
+}
+
+// Health is the response of the health endpoint
+type Health struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+}
+
+// Error describes a failed request
+type Error struct {
+	// StatusCode is the HTTP status code the error was returned with
+	StatusCode int `json:"-"`
+
+	Message string `json:"error"`
+	// Violations lists the policy rules violated by a rejected change
+	Violations []string `json:"violations,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,299 @@
+package fsquota
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/speijnik/go-errortree"
+)
+
+// DefaultAuditLog is the file audit records are written to by default
+const DefaultAuditLog = "/var/lib/fsqm/audit.jsonl"
+
+// Audit operations
+const (
+	// AuditOperationSet is recorded when limits are set
+	AuditOperationSet = "set"
+	// AuditOperationClear is recorded when all limits of an ID are removed
+	AuditOperationClear = "clear"
+)
+
+// AuditLimits are the limits of an ID recorded in the audit log
+type AuditLimits struct {
+	BytesSoft uint64 `json:"bytes_soft"`
+	BytesHard uint64 `json:"bytes_hard"`
+	FilesSoft uint64 `json:"files_soft"`
+	FilesHard uint64 `json:"files_hard"`
+}
+
+func newAuditLimits(limits *Limits) *AuditLimits {
+	if limits == nil {
+		return nil
+	}
+
+	bytesHard, bytesSoft, _ := limits.Bytes.getValues()
+	filesHard, filesSoft, _ := limits.Files.getValues()
+
+	return &AuditLimits{
+		BytesSoft: bytesSoft,
+		BytesHard: bytesHard,
+		FilesSoft: filesSoft,
+		FilesHard: filesHard,
+	}
+}
+
+// matches reports whether limits equal the recorded limits
+func (l *AuditLimits) matches(limits *Limits) bool {
+	return *l == *newAuditLimits(limits)
+}
+
+// Limits converts the recorded limits, setting all four values
+func (l *AuditLimits) Limits() *Limits {
+	limits := &Limits{}
+	limits.Bytes.SetSoft(l.BytesSoft)
+	limits.Bytes.SetHard(l.BytesHard)
+	limits.Files.SetSoft(l.FilesSoft)
+	limits.Files.SetHard(l.FilesHard)
+	return limits
+}
+
+// AuditRecord describes a single quota change
+type AuditRecord struct {
+	// ChangeID uniquely identifies the change
+	ChangeID  string    `json:"change_id"`
+	Timestamp time.Time `json:"timestamp"`
+	// CallerUID is the UID of the user making the change. If the change was made by root via sudo,
+	// it is the UID of the user invoking sudo.
+	CallerUID int       `json:"caller_uid"`
+	Host      string    `json:"host"`
+	Operation string    `json:"operation"`
+	Path      string    `json:"path"`
+	Type      QuotaType `json:"type"`
+	ID        string    `json:"id"`
+
+	OldLimits *AuditLimits `json:"old_limits"`
+	NewLimits *AuditLimits `json:"new_limits"`
+}
+
+// newChangeID creates a random change ID
+func newChangeID() (id string, err error) {
+	data := make([]byte, 8)
+	if _, err = rand.Read(data); err == nil {
+		id = hex.EncodeToString(data)
+	}
+	return
+}
+
+// callerUID returns the UID of the user making changes, which is the user invoking sudo if running via sudo
+func callerUID() int {
+	uid := os.Getuid()
+	if uid == 0 {
+		if sudoUID, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil {
+			return sudoUID
+		}
+	}
+	return uid
+}
+
+// newAuditRecord creates the record of a change of the filesystem mounted at mountPoint, t and id from before to after.
+// before is nil if the limits before the change are unknown.
+func newAuditRecord(operation, mountPoint string, t QuotaType, id string, before, after *Limits, now time.Time) (record *AuditRecord, err error) {
+	record = &AuditRecord{
+		Timestamp: now.UTC(),
+		CallerUID: callerUID(),
+		Operation: operation,
+		Path:      mountPoint,
+		Type:      t,
+		ID:        id,
+		OldLimits: newAuditLimits(before),
+		NewLimits: newAuditLimits(after),
+	}
+
+	if record.ChangeID, err = newChangeID(); err != nil {
+		record = nil
+		return
+	}
+
+	// Records are kept even if the host name is unknown
+	record.Host, _ = os.Hostname()
+	return
+}
+
+// AuditSink receives audit records
+type AuditSink interface {
+	Record(record *AuditRecord) error
+}
+
+// ErrChangeSuperseded is returned when undoing a change whose limits have been changed again since
+var ErrChangeSuperseded = errors.New("limits have been changed since the change")
+
+// UndoSuperseded undoes changes even if their limits have been changed again since
+func UndoSuperseded() SetOption {
+	return func(options *setOptions) {
+		options.undoSuperseded = true
+	}
+}
+
+// AuditError is returned if a change was applied, but its audit record could not be written
+type AuditError struct {
+	Record *AuditRecord
+	Err    error
+}
+
+func (e *AuditError) Error() string {
+	return fmt.Sprintf("change %s applied, but not recorded in the audit log: %s", e.Record.ChangeID, e.Err)
+}
+
+var (
+	activeAuditSinkMu sync.RWMutex
+	activeAuditSink   AuditSink
+)
+
+// SetAuditSink installs the sink recording all subsequent quota changes. A nil sink disables auditing.
+func SetAuditSink(sink AuditSink) {
+	activeAuditSinkMu.Lock()
+	defer activeAuditSinkMu.Unlock()
+	activeAuditSink = sink
+}
+
+// GetAuditSink returns the audit sink currently installed
+func GetAuditSink() AuditSink {
+	activeAuditSinkMu.RLock()
+	defer activeAuditSinkMu.RUnlock()
+	return activeAuditSink
+}
+
+// MultiAuditSink passes records to all of its sinks
+type MultiAuditSink []AuditSink
+
+// Record implements AuditSink
+func (m MultiAuditSink) Record(record *AuditRecord) (err error) {
+	for i, sink := range m {
+		if recordErr := sink.Record(record); recordErr != nil {
+			err = errortree.Add(err, strconv.Itoa(i), recordErr)
+		}
+	}
+	return
+}
+
+// JSONLinesAuditSink appends records to a file, one JSON document per line
+type JSONLinesAuditSink struct {
+	mu       sync.Mutex
+	fileName string
+}
+
+// NewJSONLinesAuditSink creates a sink appending to fileName
+func NewJSONLinesAuditSink(fileName string) *JSONLinesAuditSink {
+	return &JSONLinesAuditSink{
+		fileName: fileName,
+	}
+}
+
+// Record implements AuditSink
+func (s *JSONLinesAuditSink) Record(record *AuditRecord) (err error) {
+	var data []byte
+	if data, err = json.Marshal(record); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = os.MkdirAll(filepath.Dir(s.fileName), 0755); err != nil {
+		return
+	}
+
+	var f *os.File
+	if f, err = os.OpenFile(s.fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640); err != nil {
+		return
+	}
+
+	// A single write per record keeps concurrent appends from interleaving
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return
+	}
+	return f.Close()
+}
+
+// ReadAuditRecords reads records written by JSONLinesAuditSink from r
+func ReadAuditRecords(r io.Reader) (records []*AuditRecord, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		record := &AuditRecord{}
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			err = fmt.Errorf("line %d: %s", line, err)
+			records = nil
+			return
+		}
+		records = append(records, record)
+	}
+
+	if err = scanner.Err(); err != nil {
+		records = nil
+	}
+	return
+}
+
+// ReadAuditLog reads the records of an audit log file written by JSONLinesAuditSink
+func ReadAuditLog(fileName string) (records []*AuditRecord, err error) {
+	var f *os.File
+	if f, err = os.Open(fileName); err != nil {
+		return
+	}
+	defer f.Close()
+
+	return ReadAuditRecords(f)
+}
+
+// AuditFilter selects audit records. Zero fields match all records.
+type AuditFilter struct {
+	Path  string
+	Type  *QuotaType
+	ID    string
+	Since time.Time
+	Until time.Time
+}
+
+// Matches reports whether record is selected by the filter
+func (f *AuditFilter) Matches(record *AuditRecord) bool {
+	switch {
+	case f.Path != "" && filepath.Clean(f.Path) != filepath.Clean(record.Path):
+		return false
+	case f.Type != nil && *f.Type != record.Type:
+		return false
+	case f.ID != "" && f.ID != record.ID:
+		return false
+	case !f.Since.IsZero() && record.Timestamp.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && record.Timestamp.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// FindAuditRecord returns the record of the change with the given ID, nil if there is none
+func FindAuditRecord(records []*AuditRecord, changeID string) *AuditRecord {
+	for _, record := range records {
+		if record.ChangeID == changeID {
+			return record
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package fsquota
+
+import (
+	"encoding/json"
+	"errors"
+	"log/syslog"
+	"os"
+	"time"
+)
+
+// SyslogAuditSink writes records as JSON to syslog
+type SyslogAuditSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogAuditSink creates a sink logging to the local syslog daemon with the given tag
+func NewSyslogAuditSink(tag string) (sink *SyslogAuditSink, err error) {
+	var writer *syslog.Writer
+	if writer, err = syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, tag); err != nil {
+		return
+	}
+
+	sink = &SyslogAuditSink{
+		writer: writer,
+	}
+	return
+}
+
+// Record implements AuditSink
+func (s *SyslogAuditSink) Record(record *AuditRecord) (err error) {
+	var data []byte
+	if data, err = json.Marshal(record); err != nil {
+		return
+	}
+	return s.writer.Notice(string(data))
+}
+
+// beginAudit prepares recording a change of path, t and idString. The returned function records the
+// change once it has been applied, it is nil if auditing is disabled.
+func beginAudit(operation string, t quotaCtlType, path string, idString string) (finish func(info *Info) error) {
+	sink := GetAuditSink()
+	if sink == nil {
+		return nil
+	}
+
+	// IDs without a quota entry start out without limits, limits which cannot be read are recorded as unknown
+	var before *Limits
+	if current, err := getQuota(t, path, idString); err == nil {
+		before = &current.Limits
+	} else if err == ErrRquotaNoQuota || os.IsNotExist(err) {
+		before = &Limits{}
+	}
+
+	// Changes are recorded against the mount point, so records of the same filesystem match regardless of the
+	// path used to make them
+	mountPoint := path
+	if resolved, err := mountPointForPath(path); err == nil {
+		mountPoint = resolved
+	}
+
+	return func(info *Info) (err error) {
+		var record *AuditRecord
+		if record, err = newAuditRecord(operation, mountPoint, QuotaType(t), idString, before, &info.Limits, time.Now()); err != nil {
+			return
+		}
+
+		if recordErr := sink.Record(record); recordErr != nil {
+			err = &AuditError{Record: record, Err: recordErr}
+		}
+		return
+	}
+}
+
+// undoChange restores the limits recorded before a change
+func undoChange(record *AuditRecord, opts []SetOption) (info *Info, err error) {
+	return undoChangeWith(record, opts, getQuota, policySetQuota(opts))
+}
+
+func undoChangeWith(record *AuditRecord, opts []SetOption, getFn getQuotaFn, setFn setQuotaFn) (info *Info, err error) {
+	if record.OldLimits == nil {
+		err = errors.New("change does not record previous limits")
+		return
+	}
+
+	// Limits changed again since are only replaced if requested
+	if !newSetOptions(opts).undoSuperseded {
+		var current *Info
+		if current, err = getFn(quotaCtlType(record.Type), record.Path, record.ID); err != nil {
+			return
+		}
+
+		if record.NewLimits == nil || !record.NewLimits.matches(&current.Limits) {
+			err = ErrChangeSuperseded
+			return
+		}
+	}
+
+	return setFn(quotaCtlType(record.Type), record.Path, record.ID, record.OldLimits.Limits())
+}
@@ -0,0 +1,47 @@
+package fsquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoChangeWith(t *testing.T) {
+	quotas := &fakeQuotas{
+		limits: map[string][4]uint64{
+			"1000": {3, 4, 0, 0},
+		},
+	}
+
+	record := &AuditRecord{
+		Path:      "/mnt",
+		Type:      QuotaTypeUser,
+		ID:        "1000",
+		OldLimits: &AuditLimits{BytesSoft: 1, BytesHard: 2},
+		NewLimits: &AuditLimits{BytesSoft: 5, BytesHard: 6},
+	}
+
+	// Limits changed since the change are only replaced if requested
+	_, err := undoChangeWith(record, nil, quotas.get, quotas.set)
+	assert.EqualValues(t, ErrChangeSuperseded, err)
+	assert.EqualValues(t, [4]uint64{3, 4, 0, 0}, quotas.limits["1000"])
+
+	_, err = undoChangeWith(record, []SetOption{OverridePolicy()}, quotas.get, quotas.set)
+	assert.EqualValues(t, ErrChangeSuperseded, err)
+	assert.EqualValues(t, [4]uint64{3, 4, 0, 0}, quotas.limits["1000"])
+
+	_, err = undoChangeWith(record, []SetOption{UndoSuperseded()}, quotas.get, quotas.set)
+	assert.NoError(t, err)
+	assert.EqualValues(t, [4]uint64{1, 2, 0, 0}, quotas.limits["1000"])
+
+	record.OldLimits = &AuditLimits{}
+	record.NewLimits = &AuditLimits{BytesSoft: 1, BytesHard: 2}
+	_, err = undoChangeWith(record, nil, quotas.get, quotas.set)
+	assert.NoError(t, err)
+	assert.EqualValues(t, [4]uint64{0, 0, 0, 0}, quotas.limits["1000"])
+
+	// Changes without previous limits cannot be undone
+	record.OldLimits = nil
+	_, err = undoChangeWith(record, []SetOption{UndoSuperseded()}, quotas.get, quotas.set)
+	assert.Error(t, err)
+}
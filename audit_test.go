@@ -0,0 +1,108 @@
+package fsquota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuditRecord(t *testing.T) {
+	before := &Limits{}
+	before.Bytes.SetHard(100)
+	after := &Limits{}
+	after.Bytes.SetSoft(150)
+	after.Bytes.SetHard(200)
+	after.Files.SetHard(10)
+
+	now := time.Unix(1000000, 0)
+	record, err := newAuditRecord(AuditOperationSet, "/srv", QuotaTypeGroup, "100", before, after, now)
+	require.NoError(t, err)
+
+	assert.Len(t, record.ChangeID, 16)
+	assert.EqualValues(t, now.UTC(), record.Timestamp)
+	assert.EqualValues(t, AuditOperationSet, record.Operation)
+	assert.EqualValues(t, &AuditLimits{BytesHard: 100}, record.OldLimits)
+	assert.EqualValues(t, &AuditLimits{BytesSoft: 150, BytesHard: 200, FilesHard: 10}, record.NewLimits)
+
+	restored := record.OldLimits.Limits()
+	assert.False(t, restored.Bytes.partial())
+	assert.False(t, restored.Files.partial())
+	assert.EqualValues(t, 100, restored.Bytes.GetHard())
+
+	other, err := newAuditRecord(AuditOperationSet, "/srv", QuotaTypeGroup, "100", before, after, now)
+	require.NoError(t, err)
+	assert.NotEqual(t, record.ChangeID, other.ChangeID)
+
+	// Unknown previous limits are not recorded
+	unknown, err := newAuditRecord(AuditOperationSet, "/srv", QuotaTypeGroup, "100", nil, after, now)
+	require.NoError(t, err)
+	assert.Nil(t, unknown.OldLimits)
+}
+
+func TestCallerUID(t *testing.T) {
+	if os.Getuid() != 0 {
+		assert.EqualValues(t, os.Getuid(), callerUID())
+		return
+	}
+
+	sudoUID, hadSudoUID := os.LookupEnv("SUDO_UID")
+	defer func() {
+		if hadSudoUID {
+			os.Setenv("SUDO_UID", sudoUID)
+		} else {
+			os.Unsetenv("SUDO_UID")
+		}
+	}()
+
+	os.Setenv("SUDO_UID", "1000")
+	assert.EqualValues(t, 1000, callerUID())
+	os.Unsetenv("SUDO_UID")
+	assert.EqualValues(t, 0, callerUID())
+}
+
+func TestJSONLinesAuditSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsquota-audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "log", "audit.jsonl")
+	sink := NewJSONLinesAuditSink(fileName)
+
+	now := time.Unix(1000000, 0)
+	first, err := newAuditRecord(AuditOperationSet, "/srv", QuotaTypeUser, "1000", &Limits{}, &Limits{}, now)
+	require.NoError(t, err)
+	second, err := newAuditRecord(AuditOperationClear, "/home", QuotaTypeProject, "5", &Limits{}, &Limits{}, now.Add(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Record(first))
+	require.NoError(t, MultiAuditSink{sink}.Record(second))
+
+	records, err := ReadAuditLog(fileName)
+	require.NoError(t, err)
+	assert.EqualValues(t, []*AuditRecord{first, second}, records)
+	assert.EqualValues(t, second, FindAuditRecord(records, second.ChangeID))
+	assert.Nil(t, FindAuditRecord(records, "unknown"))
+
+	_, err = ReadAuditRecords(strings.NewReader("{}\nnot json\n"))
+	assert.EqualError(t, err, "line 2: invalid character 'o' in literal null (expecting 'u')")
+}
+
+func TestAuditFilter_Matches(t *testing.T) {
+	record := &AuditRecord{Path: "/srv", Type: QuotaTypeUser, ID: "1000", Timestamp: time.Unix(1000000, 0)}
+	user, group := QuotaTypeUser, QuotaTypeGroup
+
+	assert.True(t, (&AuditFilter{}).Matches(record))
+	assert.True(t, (&AuditFilter{Path: "/srv/", Type: &user, ID: "1000"}).Matches(record))
+	assert.False(t, (&AuditFilter{Path: "/home"}).Matches(record))
+	assert.False(t, (&AuditFilter{Type: &group}).Matches(record))
+	assert.False(t, (&AuditFilter{ID: "1001"}).Matches(record))
+	assert.True(t, (&AuditFilter{Since: time.Unix(1000000, 0), Until: time.Unix(1000000, 0)}).Matches(record))
+	assert.False(t, (&AuditFilter{Since: time.Unix(1000001, 0)}).Matches(record))
+	assert.False(t, (&AuditFilter{Until: time.Unix(999999, 0)}).Matches(record))
+}
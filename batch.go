@@ -0,0 +1,13 @@
+package fsquota
+
+// QuotaChange describes new limits for a single user, group or project on a filesystem
+type QuotaChange struct {
+	// Path on the filesystem to change the quota on
+	Path string
+	// Type of the quota to change
+	Type QuotaType
+	// Numeric ID of the user, group or project
+	ID string
+	// Limits to apply, limits not set are left unchanged
+	Limits Limits
+}
@@ -0,0 +1,120 @@
+package fsquota
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/speijnik/go-errortree"
+)
+
+var (
+	// ErrBatchRolledBack is returned when a batch failed to apply and all changes have been reverted
+	ErrBatchRolledBack = errors.New("batch failed, all changes have been rolled back")
+)
+
+// validateQuotaChange checks a change for errors which can be detected without touching the filesystem
+func validateQuotaChange(change *QuotaChange) (err error) {
+	if change.Path == "" {
+		err = errortree.Add(err, "path", errors.New("path must not be empty"))
+	}
+
+	if change.Type > QuotaTypeProject {
+		err = errortree.Add(err, "type", errors.New("unknown quota type"))
+	}
+
+	if _, parseErr := parseID(change.ID); parseErr != nil {
+		err = errortree.Add(err, "id", parseErr)
+	}
+
+	_, _, haveBytesLimits := change.Limits.Bytes.getValues()
+	_, _, haveFilesLimits := change.Limits.Files.getValues()
+	if !haveBytesLimits && !haveFilesLimits {
+		err = errortree.Add(err, "limits", errors.New("no limits provided"))
+	}
+
+	return
+}
+
+// snapshotLimits returns limits restoring exactly the limits of info
+func snapshotLimits(info *Info) *Limits {
+	bytesHard, bytesSoft, _ := info.Bytes.getValues()
+	filesHard, filesSoft, _ := info.Files.getValues()
+
+	limits := &Limits{}
+	limits.Bytes.SetHard(bytesHard)
+	limits.Bytes.SetSoft(bytesSoft)
+	limits.Files.SetHard(filesHard)
+	limits.Files.SetSoft(filesSoft)
+	return limits
+}
+
+// checkQuotaChanges validates all changes and checks them using checkFn, errors are keyed by the index of the change
+func checkQuotaChanges(changes []*QuotaChange, checkFn checkQuotaFn) (err error) {
+	for i, change := range changes {
+		if validationErr := validateQuotaChange(change); validationErr != nil {
+			err = errortree.Add(err, strconv.Itoa(i), validationErr)
+		} else if checkErr := checkFn(quotaCtlType(change.Type), change.Path, change.ID, &change.Limits); checkErr != nil {
+			err = errortree.Add(err, strconv.Itoa(i), checkErr)
+		}
+	}
+	return
+}
+
+// applyQuotaChanges validates and snapshots all changes before applying them in order.
+// If applying a change fails, all changes applied so far are reverted in reverse order.
+// Changes applied without an audit record do not fail the batch, their *AuditError is returned keyed by index once all
+// changes have been applied.
+// Errors are keyed by the index of the change within changes.
+func applyQuotaChanges(changes []*QuotaChange, getFn getQuotaFn, setFn setQuotaFn, checkFn checkQuotaFn) (err error) {
+	if err = checkQuotaChanges(changes, checkFn); err != nil {
+		return
+	}
+
+	snapshots := make([]*Limits, len(changes))
+	for i, change := range changes {
+		info, getErr := getFn(quotaCtlType(change.Type), change.Path, change.ID)
+		if getErr != nil {
+			err = errortree.Add(err, strconv.Itoa(i), getErr)
+			continue
+		}
+		snapshots[i] = snapshotLimits(info)
+	}
+
+	if err != nil {
+		return
+	}
+
+	// Changes applied without an audit record are reported once all changes have been applied
+	var auditErr error
+	for i, change := range changes {
+		if _, setErr := setFn(quotaCtlType(change.Type), change.Path, change.ID, &change.Limits); setErr != nil {
+			if _, isAuditErr := setErr.(*AuditError); isAuditErr {
+				auditErr = errortree.Add(auditErr, strconv.Itoa(i), setErr)
+				continue
+			}
+
+			err = errortree.Add(err, strconv.Itoa(i), setErr)
+
+			// Restore in reverse order, so IDs changed multiple times end up with their original limits
+			var rollbackErr error
+			for j := i - 1; j >= 0; j-- {
+				// Restores applied without an audit record still count as restored
+				if _, restoreErr := setFn(quotaCtlType(changes[j].Type), changes[j].Path, changes[j].ID, snapshots[j]); restoreErr != nil {
+					if _, isAuditErr := restoreErr.(*AuditError); !isAuditErr {
+						rollbackErr = errortree.Add(rollbackErr, strconv.Itoa(j), restoreErr)
+					}
+				}
+			}
+
+			if rollbackErr != nil {
+				err = errortree.Add(err, "rollback", rollbackErr)
+			} else {
+				err = errortree.Add(err, "rollback", ErrBatchRolledBack)
+			}
+			return
+		}
+	}
+
+	err = auditErr
+	return
+}
@@ -0,0 +1,149 @@
+package fsquota
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/speijnik/go-errortree"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeQuotas is an in-memory quota store used to test batch operations
+type fakeQuotas struct {
+	limits  map[string][4]uint64
+	failID  string
+	auditID string
+	sets    int
+}
+
+func (f *fakeQuotas) get(t quotaCtlType, path string, idString string) (*Info, error) {
+	values := f.limits[idString]
+	info := &Info{}
+	info.Bytes.SetSoft(values[0])
+	info.Bytes.SetHard(values[1])
+	info.Files.SetSoft(values[2])
+	info.Files.SetHard(values[3])
+	return info, nil
+}
+
+func (f *fakeQuotas) set(t quotaCtlType, path string, idString string, limits *Limits) (*Info, error) {
+	f.sets++
+	if idString == f.failID {
+		return nil, errors.New("set failed")
+	}
+
+	values := f.limits[idString]
+	bytesHard, bytesSoft, haveBytes := limits.Bytes.getValues()
+	if haveBytes {
+		values[0], values[1] = bytesSoft, bytesHard
+	}
+	filesHard, filesSoft, haveFiles := limits.Files.getValues()
+	if haveFiles {
+		values[2], values[3] = filesSoft, filesHard
+	}
+	f.limits[idString] = values
+
+	info, _ := f.get(t, path, idString)
+	if idString == f.auditID {
+		return info, &AuditError{Record: &AuditRecord{}, Err: errors.New("audit failed")}
+	}
+	return info, nil
+}
+
+func newBytesChange(id string, soft, hard uint64) *QuotaChange {
+	change := &QuotaChange{
+		Path: "/mnt",
+		Type: QuotaTypeUser,
+		ID:   id,
+	}
+	change.Limits.Bytes.SetSoft(soft)
+	change.Limits.Bytes.SetHard(hard)
+	return change
+}
+
+func TestApplyQuotaChanges(t *testing.T) {
+	t.Run("Validation", func(t *testing.T) {
+		quotas := &fakeQuotas{limits: map[string][4]uint64{}}
+
+		changes := []*QuotaChange{
+			newBytesChange("1000", 1, 2),
+			{Path: "", Type: QuotaType(7), ID: "alice"},
+		}
+
+		err := applyQuotaChanges(changes, quotas.get, quotas.set, noopCheckQuota)
+		if assert.Error(t, err) {
+			assert.Nil(t, errortree.Get(err, "0"))
+			assert.Error(t, errortree.Get(err, "1", "path"))
+			assert.Error(t, errortree.Get(err, "1", "type"))
+			assert.Error(t, errortree.Get(err, "1", "id"))
+			assert.Error(t, errortree.Get(err, "1", "limits"))
+		}
+		assert.EqualValues(t, 0, quotas.sets)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		quotas := &fakeQuotas{limits: map[string][4]uint64{
+			"1000": {0, 0, 10, 20},
+		}}
+
+		err := applyQuotaChanges([]*QuotaChange{
+			newBytesChange("1000", 1, 2),
+			newBytesChange("1001", 3, 4),
+		}, quotas.get, quotas.set, noopCheckQuota)
+		assert.NoError(t, err)
+		assert.EqualValues(t, [4]uint64{1, 2, 10, 20}, quotas.limits["1000"])
+		assert.EqualValues(t, [4]uint64{3, 4, 0, 0}, quotas.limits["1001"])
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		quotas := &fakeQuotas{
+			limits: map[string][4]uint64{
+				"1000": {5, 6, 7, 8},
+			},
+			failID: "1002",
+		}
+
+		err := applyQuotaChanges([]*QuotaChange{
+			newBytesChange("1000", 1, 2),
+			newBytesChange("1000", 3, 4),
+			newBytesChange("1001", 3, 4),
+			newBytesChange("1002", 3, 4),
+		}, quotas.get, quotas.set, noopCheckQuota)
+		if assert.Error(t, err) {
+			assert.Error(t, errortree.Get(err, "3"))
+			assert.EqualValues(t, ErrBatchRolledBack, errortree.Get(err, "rollback"))
+		}
+		assert.EqualValues(t, [4]uint64{5, 6, 7, 8}, quotas.limits["1000"])
+		assert.EqualValues(t, [4]uint64{0, 0, 0, 0}, quotas.limits["1001"])
+	})
+
+	t.Run("AuditFailure", func(t *testing.T) {
+		quotas := &fakeQuotas{
+			limits:  map[string][4]uint64{},
+			auditID: "1000",
+		}
+
+		// Changes applied without an audit record are not rolled back
+		err := applyQuotaChanges([]*QuotaChange{
+			newBytesChange("1000", 1, 2),
+			newBytesChange("1001", 3, 4),
+		}, quotas.get, quotas.set, noopCheckQuota)
+		if assert.Error(t, err) {
+			assert.IsType(t, &AuditError{}, errortree.Get(err, "0"))
+			assert.Nil(t, errortree.Get(err, "rollback"))
+		}
+		assert.EqualValues(t, [4]uint64{1, 2, 0, 0}, quotas.limits["1000"])
+		assert.EqualValues(t, [4]uint64{3, 4, 0, 0}, quotas.limits["1001"])
+
+		// Changes applied without an audit record are rolled back if a later change fails
+		quotas.failID = "1001"
+		err = applyQuotaChanges([]*QuotaChange{
+			newBytesChange("1000", 5, 6),
+			newBytesChange("1001", 7, 8),
+		}, quotas.get, quotas.set, noopCheckQuota)
+		if assert.Error(t, err) {
+			assert.EqualValues(t, ErrBatchRolledBack, errortree.Get(err, "rollback"))
+		}
+		assert.EqualValues(t, [4]uint64{1, 2, 0, 0}, quotas.limits["1000"])
+	})
+}
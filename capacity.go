@@ -0,0 +1,137 @@
+package fsquota
+
+import (
+	"time"
+)
+
+// QuotaSummary aggregates the quotas of a single quota type on a filesystem
+type QuotaSummary struct {
+	// Number of IDs with a quota entry
+	IDs int
+
+	// Sums of all configured limits
+	BytesSoftLimitSum uint64
+	BytesHardLimitSum uint64
+	FilesSoftLimitSum uint64
+	FilesHardLimitSum uint64
+
+	// Usage of all IDs
+	BytesUsed uint64
+	FilesUsed uint64
+
+	// Usage of the IDs with a hard limit, which is what BytesHardLimitSum and FilesHardLimitSum limit
+	BytesUsedLimited uint64
+	FilesUsedLimited uint64
+
+	// Number of IDs without a hard limit
+	BytesUnlimited int
+	FilesUnlimited int
+
+	// Number of IDs exceeding any soft limit
+	OverSoftLimit int
+	// Number of IDs exceeding a soft limit whose grace period has not yet expired
+	InGrace int
+	// Number of IDs exceeding a soft limit whose grace period has expired
+	GraceExpired int
+
+	// Usage if all IDs with a hard limit filled up to it, with the usage of all other IDs unchanged
+	WorstCaseBytes uint64
+	WorstCaseFiles uint64
+}
+
+// CapacityAnalysis compares the quotas configured on a filesystem with its capacity
+type CapacityAnalysis struct {
+	// Size and inode count of the filesystem
+	CapacityBytes uint64
+	CapacityFiles uint64
+
+	// Space and inodes in use on the filesystem
+	UsedBytes uint64
+	UsedFiles uint64
+
+	// Summaries of all quota types enabled on the filesystem
+	Summaries map[QuotaType]*QuotaSummary
+}
+
+// BytesOvercommit returns the ratio of the sum of all byte hard limits of quotaType to the filesystem size
+func (a *CapacityAnalysis) BytesOvercommit(quotaType QuotaType) float64 {
+	summary := a.Summaries[quotaType]
+	if summary == nil || a.CapacityBytes == 0 {
+		return 0
+	}
+	return float64(summary.BytesHardLimitSum) / float64(a.CapacityBytes)
+}
+
+// FilesOvercommit returns the ratio of the sum of all file hard limits of quotaType to the filesystem's inode count
+func (a *CapacityAnalysis) FilesOvercommit(quotaType QuotaType) float64 {
+	summary := a.Summaries[quotaType]
+	if summary == nil || a.CapacityFiles == 0 {
+		return 0
+	}
+	return float64(summary.FilesHardLimitSum) / float64(a.CapacityFiles)
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// graceState reports whether a resource exceeds its soft limit and, if so, whether its grace period has expired
+func graceState(used, soft uint64, expires time.Time, now time.Time) (over, expired bool) {
+	if soft == 0 || used <= soft {
+		return
+	}
+	over = true
+	expired = !expires.IsZero() && !expires.After(now)
+	return
+}
+
+// summarizeReport aggregates report, evaluating grace periods at now
+func summarizeReport(report *Report, now time.Time) (summary *QuotaSummary) {
+	summary = &QuotaSummary{}
+
+	for _, info := range report.Infos {
+		summary.IDs++
+
+		bytesHard, bytesSoft, _ := info.Bytes.getValues()
+		filesHard, filesSoft, _ := info.Files.getValues()
+
+		summary.BytesSoftLimitSum += bytesSoft
+		summary.BytesHardLimitSum += bytesHard
+		summary.FilesSoftLimitSum += filesSoft
+		summary.FilesHardLimitSum += filesHard
+		summary.BytesUsed += info.BytesUsed
+		summary.FilesUsed += info.FilesUsed
+
+		if bytesHard == 0 {
+			summary.BytesUnlimited++
+			summary.WorstCaseBytes += info.BytesUsed
+		} else {
+			summary.BytesUsedLimited += info.BytesUsed
+			summary.WorstCaseBytes += maxUint64(bytesHard, info.BytesUsed)
+		}
+
+		if filesHard == 0 {
+			summary.FilesUnlimited++
+			summary.WorstCaseFiles += info.FilesUsed
+		} else {
+			summary.FilesUsedLimited += info.FilesUsed
+			summary.WorstCaseFiles += maxUint64(filesHard, info.FilesUsed)
+		}
+
+		bytesOver, bytesExpired := graceState(info.BytesUsed, bytesSoft, info.BytesGraceExpires, now)
+		filesOver, filesExpired := graceState(info.FilesUsed, filesSoft, info.FilesGraceExpires, now)
+		switch {
+		case bytesExpired || filesExpired:
+			summary.OverSoftLimit++
+			summary.GraceExpired++
+		case bytesOver || filesOver:
+			summary.OverSoftLimit++
+			summary.InGrace++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,59 @@
+package fsquota
+
+import (
+	"time"
+
+	"github.com/speijnik/go-errortree"
+	"golang.org/x/sys/unix"
+)
+
+func analyzeCapacity(path string) (analysis *CapacityAnalysis, err error) {
+	var statfs unix.Statfs_t
+	if err = unix.Statfs(path, &statfs); err != nil {
+		return
+	}
+
+	analysis = &CapacityAnalysis{
+		CapacityBytes: statfs.Blocks * uint64(statfs.Frsize),
+		CapacityFiles: statfs.Files,
+		UsedBytes:     (statfs.Blocks - statfs.Bfree) * uint64(statfs.Frsize),
+		UsedFiles:     statfs.Files - statfs.Ffree,
+		Summaries:     make(map[QuotaType]*QuotaSummary),
+	}
+
+	var reports map[QuotaType]*Report
+	if reports, err = getEnabledReports(path); err != nil {
+		analysis = nil
+		return
+	}
+
+	now := time.Now()
+	for t, report := range reports {
+		analysis.Summaries[t] = summarizeReport(report, now)
+	}
+
+	return
+}
+
+// getEnabledReports retrieves the reports of all quota types enabled on the filesystem at path
+func getEnabledReports(path string) (reports map[QuotaType]*Report, err error) {
+	reports = make(map[QuotaType]*Report)
+
+	for _, t := range QuotaTypes() {
+		// Quota types not enabled on the filesystem are skipped
+		report, reportErr := getEnabledReport(path, quotaCtlType(t))
+		if reportErr != nil {
+			err = errortree.Add(err, t.String(), reportErr)
+			continue
+		}
+
+		if report != nil {
+			reports[t] = report
+		}
+	}
+
+	if err != nil {
+		reports = nil
+	}
+	return
+}
@@ -0,0 +1,68 @@
+package fsquota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeReport(t *testing.T) {
+	now := time.Unix(1000000, 0)
+
+	newInfo := func(bytesSoft, bytesHard, bytesUsed, filesSoft, filesHard, filesUsed uint64) *Info {
+		info := &Info{BytesUsed: bytesUsed, FilesUsed: filesUsed}
+		info.Bytes.SetSoft(bytesSoft)
+		info.Bytes.SetHard(bytesHard)
+		info.Files.SetSoft(filesSoft)
+		info.Files.SetHard(filesHard)
+		return info
+	}
+
+	inGrace := newInfo(100, 200, 150, 0, 0, 5)
+	inGrace.BytesGraceExpires = now.Add(time.Hour)
+	expired := newInfo(0, 0, 10, 10, 20, 15)
+	expired.FilesGraceExpires = now.Add(-time.Hour)
+
+	report := &Report{Infos: map[string]*Info{
+		"1000": newInfo(100, 200, 50, 10, 20, 5),
+		"1001": inGrace,
+		"1002": expired,
+		// Exceeding the hard limit, worst case uses the actual usage
+		"1003": newInfo(0, 100, 300, 0, 0, 0),
+	}}
+
+	summary := summarizeReport(report, now)
+	assert.EqualValues(t, &QuotaSummary{
+		IDs:               4,
+		BytesSoftLimitSum: 200,
+		BytesHardLimitSum: 500,
+		FilesSoftLimitSum: 20,
+		FilesHardLimitSum: 40,
+		BytesUsed:         510,
+		FilesUsed:         25,
+		BytesUsedLimited:  500,
+		FilesUsedLimited:  20,
+		BytesUnlimited:    1,
+		FilesUnlimited:    2,
+		OverSoftLimit:     2,
+		InGrace:           1,
+		GraceExpired:      1,
+		WorstCaseBytes:    200 + 200 + 10 + 300,
+		WorstCaseFiles:    20 + 5 + 20 + 0,
+	}, summary)
+}
+
+func TestCapacityAnalysis_Overcommit(t *testing.T) {
+	analysis := &CapacityAnalysis{
+		CapacityBytes: 1000,
+		CapacityFiles: 0,
+		Summaries: map[QuotaType]*QuotaSummary{
+			QuotaTypeUser: {BytesHardLimitSum: 2500, FilesHardLimitSum: 10},
+		},
+	}
+
+	assert.EqualValues(t, 2.5, analysis.BytesOvercommit(QuotaTypeUser))
+	assert.EqualValues(t, 0, analysis.FilesOvercommit(QuotaTypeUser))
+	assert.EqualValues(t, 0, analysis.BytesOvercommit(QuotaTypeGroup))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+)
+
+// applyRecord is a single quota change as read from a batch file
+type applyRecord struct {
+	Path  string `json:"path"`
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Bytes string `json:"bytes"`
+	Files string `json:"files"`
+}
+
+// resolveQuotaID converts a user or group name or numeric ID into a numeric ID
+func resolveQuotaID(t fsquota.QuotaType, idOrName string) (id string, err error) {
+	switch t {
+	case fsquota.QuotaTypeUser:
+		return resolveUid(idOrName)
+	case fsquota.QuotaTypeGroup:
+		return resolveGid(idOrName)
+	}
+
+	if !isNumeric(idOrName) || idOrName == "" {
+		err = errors.New("project IDs must be numeric")
+		return
+	}
+	id = idOrName
+	return
+}
+
+func (r *applyRecord) toChange() (change *fsquota.QuotaChange, err error) {
+	change = &fsquota.QuotaChange{
+		Path: r.Path,
+	}
+
+	var parseErr error
+	if change.Type, parseErr = parseQuotaType(r.Type); parseErr != nil {
+		err = errortree.Add(err, "type", parseErr)
+	} else {
+		idOrName := r.ID
+		if idOrName == "" {
+			idOrName = r.Name
+		}
+
+		if change.ID, parseErr = resolveQuotaID(change.Type, idOrName); parseErr != nil {
+			err = errortree.Add(err, "id", parseErr)
+		}
+	}
+
+	// Limits given as - are left unchanged
+	bytesString, filesString := r.Bytes, r.Files
+	if bytesString == "-" {
+		bytesString = ""
+	}
+	if filesString == "-" {
+		filesString = ""
+	}
+
+	var limitChange *fsquota.LimitChange
+	if limitChange, parseErr = parseLimitChange(bytesString, filesString); parseErr != nil {
+		err = errortree.Add(err, "limits", parseErr)
+	}
+
+	// Relative limits are resolved before the batch is applied
+	if err == nil {
+		if resolveErr := resolveLimitChange(change.Path, change.Type, change.ID, limitChange, &change.Limits); resolveErr != nil {
+			err = errortree.Add(err, "limits", resolveErr)
+		}
+	}
+
+	if err != nil {
+		change = nil
+	}
+	return
+}
+
+// readApplyLines reads records in "path type id-or-name bytes [files]" format, one per line
+func readApplyLines(r io.Reader) (records []*applyRecord, err error) {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 4 || len(fields) > 5 {
+			err = errortree.Add(err, fmt.Sprintf("line %d", lineNumber), errors.New("expected format is: path type id bytes [files]"))
+			continue
+		}
+
+		record := &applyRecord{
+			Path:  fields[0],
+			Type:  fields[1],
+			ID:    fields[2],
+			Bytes: fields[3],
+		}
+		if len(fields) == 5 {
+			record.Files = fields[4]
+		}
+		records = append(records, record)
+	}
+
+	if scanErr := scanner.Err(); scanErr != nil {
+		err = scanErr
+	}
+	return
+}
+
+// readApplyJSON reads records from either a JSON array or a stream of JSON objects
+func readApplyJSON(data []byte) (records []*applyRecord, err error) {
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &records)
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		record := &applyRecord{}
+		if decodeErr := decoder.Decode(record); decodeErr == io.EOF {
+			return
+		} else if decodeErr != nil {
+			err = decodeErr
+			return
+		}
+		records = append(records, record)
+	}
+}
+
+func readApplyFile(fileName string) (records []*applyRecord, err error) {
+	var data []byte
+	if fileName == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(fileName)
+	}
+	if err != nil {
+		return
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) || bytes.HasPrefix(data, []byte("{")) {
+		return readApplyJSON(data)
+	}
+	return readApplyLines(bytes.NewReader(data))
+}
+
+var cmdApply = &cobra.Command{
+	Use:   "apply -f file",
+	Short: "Applies a batch of quota changes, rolling back all changes if any of them fails",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 0 {
+			err = errors.New("no arguments expected")
+			return
+		}
+
+		fileName, _ := cmd.Flags().GetString("file")
+		if fileName == "" {
+			err = errors.New("file is required")
+			return
+		}
+
+		var records []*applyRecord
+		if records, err = readApplyFile(fileName); err != nil {
+			return
+		}
+
+		changes := make([]*fsquota.QuotaChange, len(records))
+		for i, record := range records {
+			var recordErr error
+			if changes[i], recordErr = record.toChange(); recordErr != nil {
+				err = errortree.Add(err, fmt.Sprintf("record %d", i+1), recordErr)
+			}
+		}
+
+		if err != nil {
+			return
+		}
+
+		if err = fsquota.ApplyQuotaChanges(changes, setOptions(cmd)...); err != nil {
+			return
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %d changes\n", appliedVerb(cmd, "apply", "applied"), len(changes))
+		return
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdApply)
+	cmdApply.Flags().StringP("file", "f", "", "File containing the changes, either JSON records or lines in \"path type id bytes [files]\" format with limits as accepted by set; - reads from standard input")
+	cmdRoot.AddCommand(cmdApply)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+// loadAuditSink installs the audit sinks configured via the audit flags
+func loadAuditSink(cmd *cobra.Command) (err error) {
+	var sinks fsquota.MultiAuditSink
+
+	if fileName, _ := cmd.Flags().GetString("audit-log"); fileName != "" {
+		sinks = append(sinks, fsquota.NewJSONLinesAuditSink(fileName))
+	}
+
+	if useSyslog, _ := cmd.Flags().GetBool("audit-syslog"); useSyslog {
+		var sink *fsquota.SyslogAuditSink
+		if sink, err = fsquota.NewSyslogAuditSink("fsqm"); err != nil {
+			return
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if len(sinks) == 0 {
+		fsquota.SetAuditSink(nil)
+		return
+	}
+
+	fsquota.SetAuditSink(sinks)
+	return
+}
+
+func init() {
+	cmdRoot.PersistentFlags().String("audit-log", fsquota.DefaultAuditLog, "File quota changes are recorded in, empty to disable")
+	cmdRoot.PersistentFlags().Bool("audit-syslog", false, "Record quota changes in syslog")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+func percentOf(value, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) / float64(total) * 100
+}
+
+var cmdCapacity = &cobra.Command{
+	Use:   "capacity path",
+	Short: "Compares the quotas configured on a filesystem with its capacity",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		var analysis *fsquota.CapacityAnalysis
+		if analysis, err = fsquota.AnalyzeCapacity(args[0]); err != nil {
+			return
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), "filesystem:")
+		fmt.Fprintf(cmd.OutOrStdout(), "  - bytes: %s of %s used (%.1f%%)\n", humanize.IBytes(analysis.UsedBytes), humanize.IBytes(analysis.CapacityBytes), percentOf(analysis.UsedBytes, analysis.CapacityBytes))
+		fmt.Fprintf(cmd.OutOrStdout(), "  - files: %s of %s used (%.1f%%)\n", humanizeInodes(analysis.UsedFiles), humanizeInodes(analysis.CapacityFiles), percentOf(analysis.UsedFiles, analysis.CapacityFiles))
+
+		if len(analysis.Summaries) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "no quotas enabled")
+			return
+		}
+
+		for _, t := range inspectQuotaTypes {
+			summary, ok := analysis.Summaries[t]
+			if !ok {
+				continue
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s quotas:\n", t)
+			fmt.Fprintf(cmd.OutOrStdout(), "  - IDs: %d\n", summary.IDs)
+			fmt.Fprintf(cmd.OutOrStdout(), "  - over soft limit: %d (%d in grace, %d grace expired)\n", summary.OverSoftLimit, summary.InGrace, summary.GraceExpired)
+			fmt.Fprintln(cmd.OutOrStdout(), "  - bytes:")
+			fmt.Fprintf(cmd.OutOrStdout(), "    - soft limits: %s (%.1f%% of capacity)\n", humanize.IBytes(summary.BytesSoftLimitSum), percentOf(summary.BytesSoftLimitSum, analysis.CapacityBytes))
+			fmt.Fprintf(cmd.OutOrStdout(), "    - hard limits: %s (%.1f%% of capacity)\n", humanize.IBytes(summary.BytesHardLimitSum), analysis.BytesOvercommit(t)*100)
+			fmt.Fprintf(cmd.OutOrStdout(), "    - used: %s, %s of it limited (%.1f%% of hard limits)\n", humanize.IBytes(summary.BytesUsed), humanize.IBytes(summary.BytesUsedLimited), percentOf(summary.BytesUsedLimited, summary.BytesHardLimitSum))
+			fmt.Fprintf(cmd.OutOrStdout(), "    - worst case: %s (%.1f%% of capacity, %d IDs unlimited)\n", humanize.IBytes(summary.WorstCaseBytes), percentOf(summary.WorstCaseBytes, analysis.CapacityBytes), summary.BytesUnlimited)
+			fmt.Fprintln(cmd.OutOrStdout(), "  - files:")
+			fmt.Fprintf(cmd.OutOrStdout(), "    - soft limits: %s (%.1f%% of capacity)\n", humanizeInodes(summary.FilesSoftLimitSum), percentOf(summary.FilesSoftLimitSum, analysis.CapacityFiles))
+			fmt.Fprintf(cmd.OutOrStdout(), "    - hard limits: %s (%.1f%% of capacity)\n", humanizeInodes(summary.FilesHardLimitSum), analysis.FilesOvercommit(t)*100)
+			fmt.Fprintf(cmd.OutOrStdout(), "    - used: %s, %s of it limited (%.1f%% of hard limits)\n", humanizeInodes(summary.FilesUsed), humanizeInodes(summary.FilesUsedLimited), percentOf(summary.FilesUsedLimited, summary.FilesHardLimitSum))
+			fmt.Fprintf(cmd.OutOrStdout(), "    - worst case: %s (%.1f%% of capacity, %d IDs unlimited)\n", humanizeInodes(summary.WorstCaseFiles), percentOf(summary.WorstCaseFiles, analysis.CapacityFiles), summary.FilesUnlimited)
+		}
+
+		return
+	},
+}
+
+func init() {
+	cmdRoot.AddCommand(cmdCapacity)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+)
+
+// collectPaths returns the paths given as arguments or all quota-enabled mount points
+func collectPaths(cmd *cobra.Command, args []string) (paths []string, err error) {
+	allMounts, _ := cmd.Flags().GetBool("all-mounts")
+	if !allMounts {
+		if len(args) == 0 {
+			err = errors.New("at least one argument required")
+		}
+		paths = args
+		return
+	}
+
+	if len(args) != 0 {
+		err = errors.New("no arguments expected when collecting from all mounts")
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range inspectQuotaTypes {
+		mountPoints, mountErr := fsquota.QuotaMountPoints(t)
+		if mountErr != nil {
+			err = mountErr
+			return
+		}
+
+		for _, mountPoint := range mountPoints {
+			if !seen[mountPoint] {
+				seen[mountPoint] = true
+				paths = append(paths, mountPoint)
+			}
+		}
+	}
+	return
+}
+
+func collectOnce(store *fsquota.HistoryStore, paths []string) (err error) {
+	for _, path := range paths {
+		snapshot, snapshotErr := fsquota.TakeSnapshot(path)
+		if snapshotErr != nil {
+			err = errortree.Add(err, path, snapshotErr)
+			continue
+		}
+
+		if appendErr := store.Append(snapshot); appendErr != nil {
+			err = errortree.Add(err, path, appendErr)
+		}
+	}
+	return
+}
+
+var cmdCollect = &cobra.Command{
+	Use:   "collect [path...]",
+	Short: "Records the current quota reports in the usage history",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		var paths []string
+		if paths, err = collectPaths(cmd, args); err != nil {
+			return
+		}
+
+		historyDir, _ := cmd.Flags().GetString("history-dir")
+		store := fsquota.NewHistoryStore(historyDir)
+		store.Retention, _ = cmd.Flags().GetDuration("retention")
+
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval <= 0 {
+			return collectOnce(store, paths)
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// Failures are reported but do not stop periodic collection
+			if collectErr := collectOnce(store, paths); collectErr != nil {
+				cmd.Println(collectErr)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-signals:
+				return
+			}
+		}
+	},
+}
+
+func init() {
+	cmdCollect.Flags().Bool("all-mounts", false, "Collect from all filesystems with quotas enabled")
+	cmdCollect.Flags().String("history-dir", fsquota.DefaultHistoryDir, "Directory the usage history is stored in")
+	cmdCollect.Flags().Duration("interval", 0, "Collect repeatedly at the given interval instead of once")
+	cmdCollect.Flags().Duration("retention", fsquota.DefaultHistoryRetention, "Period to keep usage history for, 0 keeps it forever")
+	cmdRoot.AddCommand(cmdCollect)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+)
+
+// copyQuota implements the user and group copy commands.
+// resolveFn converts a name or ID argument into an ID, lookupFn converts an ID back into a name for display.
+func copyQuota(cmd *cobra.Command, args []string, t fsquota.QuotaType, resolveFn func(string) (string, error), lookupFn func(string) string) (err error) {
+	allMounts, _ := cmd.Flags().GetBool("all-mounts")
+
+	var paths []string
+	if allMounts {
+		if len(args) < 2 {
+			err = errors.New("at least two arguments required")
+			return
+		}
+
+		if paths, err = fsquota.QuotaMountPoints(t); err != nil {
+			return
+		}
+
+		if len(paths) == 0 {
+			err = errors.New("no filesystems with " + t.String() + " quotas enabled found")
+			return
+		}
+	} else {
+		if len(args) < 3 {
+			err = errors.New("at least three arguments required")
+			return
+		}
+
+		paths = args[:1]
+		args = args[1:]
+	}
+
+	ids := make([]string, len(args))
+	for i, arg := range args {
+		var resolveErr error
+		if ids[i], resolveErr = resolveFn(arg); resolveErr != nil {
+			err = errortree.Add(err, arg, resolveErr)
+		}
+	}
+
+	if err != nil {
+		return
+	}
+
+	for _, path := range paths {
+		infos, copyErr := fsquota.CopyQuotaWithOptions(path, t, ids[0], ids[1:], setOptions(cmd)...)
+		if copyErr != nil {
+			err = errortree.Add(err, path, copyErr)
+		}
+
+		for _, id := range ids[1:] {
+			if info, ok := infos[id]; ok {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s on %s:\n", t, lookupFn(id), path)
+				printInfo(cmd, info, "  ")
+			}
+		}
+	}
+
+	return
+}
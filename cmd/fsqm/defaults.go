@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var cmdDefaults = &cobra.Command{
+	Use:   "defaults",
+	Short: "Default quota limits management",
+}
+
+func init() {
+	cmdRoot.AddCommand(cmdDefaults)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdDefaultsGet = &cobra.Command{
+	Use:   "get path",
+	Short: "Retrieves the default limits for a given path",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		var quotaType fsquota.QuotaType
+		if quotaType, err = parseQuotaTypeFlag(cmd); err != nil {
+			return
+		}
+
+		var limits *fsquota.Limits
+		if limits, err = fsquota.GetDefaultLimits(args[0], quotaType); err != nil {
+			return
+		}
+
+		printLimits(cmd, limits, "")
+		return
+	},
+}
+
+func init() {
+	cmdDefaultsGet.Flags().StringP("type", "t", "user", "Quota type, one of user, group or project")
+	cmdDefaults.AddCommand(cmdDefaultsGet)
+}
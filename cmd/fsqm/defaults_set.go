@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdDefaultsSet = &cobra.Command{
+	Use:   "set path",
+	Short: "Sets the default limits for a given path",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		var change *fsquota.LimitChange
+		if change, err = parseLimitChangeFlags(cmd); err != nil {
+			return
+		}
+
+		var quotaType fsquota.QuotaType
+		if quotaType, err = parseQuotaTypeFlag(cmd); err != nil {
+			return
+		}
+
+		// Default limits are stored as the limits of ID 0
+		var limits *fsquota.Limits
+		if limits, err = fsquota.ResolveLimits(args[0], quotaType, "0", change); err != nil {
+			return
+		}
+
+		var result *fsquota.Limits
+		if result, err = fsquota.SetDefaultLimits(args[0], quotaType, limits, setOptions(cmd)...); err != nil {
+			return
+		}
+
+		printLimits(cmd, result, "")
+		return
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdDefaultsSet)
+	cmdDefaultsSet.Flags().StringP("type", "t", "user", "Quota type, one of user, group or project")
+	addLimitChangeFlags(cmdDefaultsSet)
+	cmdDefaults.AddCommand(cmdDefaultsSet)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+// isDryRun reports whether changes are only to be computed and printed
+func isDryRun(cmd *cobra.Command) bool {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	return dryRun
+}
+
+// appliedVerb returns past if changes were applied, or "would" followed by verb in dry-run mode
+func appliedVerb(cmd *cobra.Command, verb, past string) string {
+	if isDryRun(cmd) {
+		return "would " + verb
+	}
+	return past
+}
+
+// printChangePreview prints the limits changed by a change computed in dry-run mode, in diff format
+func printChangePreview(cmd *cobra.Command, preview *fsquota.ChangePreview) {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "%s %s on %s:\n", preview.Type, quotaTypeLookupFn(preview.Type, false)(preview.ID), preview.Path)
+
+	changed := false
+	for _, l := range []struct {
+		name          string
+		before, after uint64
+		formatFn      func(uint64) string
+	}{
+		{"bytes soft", preview.Before.Bytes.GetSoft(), preview.After.Bytes.GetSoft(), humanize.IBytes},
+		{"bytes hard", preview.Before.Bytes.GetHard(), preview.After.Bytes.GetHard(), humanize.IBytes},
+		{"files soft", preview.Before.Files.GetSoft(), preview.After.Files.GetSoft(), humanizeInodes},
+		{"files hard", preview.Before.Files.GetHard(), preview.After.Files.GetHard(), humanizeInodes},
+	} {
+		if l.before == l.after {
+			continue
+		}
+		changed = true
+		fmt.Fprintf(out, "  - %s: %s\n", l.name, l.formatFn(l.before))
+		fmt.Fprintf(out, "  + %s: %s\n", l.name, l.formatFn(l.after))
+	}
+
+	if !changed {
+		fmt.Fprintln(out, "  unchanged")
+	}
+}
+
+func init() {
+	cmdRoot.PersistentFlags().Bool("dry-run", false, "Print the changes to quotas without applying them")
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+)
+
+var (
+	editByteUnits = []string{"", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	editFileUnits = []string{"", "k", "M", "G", "T", "P", "E"}
+)
+
+// formatExactValue formats value using the largest unit it is a multiple of, so it parses back to the same value
+func formatExactValue(value uint64, base uint64, units []string) string {
+	unit := 0
+	for value != 0 && value%base == 0 && unit < len(units)-1 {
+		value /= base
+		unit++
+	}
+	return strconv.FormatUint(value, 10) + units[unit]
+}
+
+func formatEditLimit(limit *fsquota.Limit, base uint64, units []string) string {
+	return formatExactValue(limit.GetSoft(), base, units) + "," + formatExactValue(limit.GetHard(), base, units)
+}
+
+// editErrorPrefix marks the lines reporting errors of the previous attempt, they are replaced on every attempt
+const editErrorPrefix = "#!"
+
+// writeEditFile renders the limits of all paths in "path bytes files" format, prefixed by header
+func writeEditFile(w io.Writer, header string, paths []string, infos map[string]*fsquota.Info) (err error) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "# "+header)
+	fmt.Fprintln(tw, "# Limits are given as soft,hard, ie. 10GiB,12GiB for bytes or 1M,2M for files, 0 means no limit.")
+	fmt.Fprintln(tw, "# Only changed lines are applied, removed lines are left unchanged.")
+	fmt.Fprintln(tw, "#")
+	fmt.Fprintln(tw, "# path\tbytes\tfiles\t")
+	for _, path := range paths {
+		info := infos[path]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t# used %s, %s files\n", path,
+			formatEditLimit(&info.Bytes, 1024, editByteUnits), formatEditLimit(&info.Files, 1000, editFileUnits),
+			humanize.IBytes(info.BytesUsed), humanizeInodes(info.FilesUsed))
+	}
+	return tw.Flush()
+}
+
+// withEditError replaces the error lines of an edited file with editErr
+func withEditError(data []byte, editErr error) []byte {
+	buf := &bytes.Buffer{}
+	for _, line := range strings.Split(editErr.Error(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			buf.WriteString(editErrorPrefix + " " + line + "\n")
+		}
+	}
+
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte(editErrorPrefix)) {
+			buf.Write(line)
+		}
+	}
+	return buf.Bytes()
+}
+
+// readEditFile parses an edited file and returns changes for all lines which differ from infos
+func readEditFile(r io.Reader, t fsquota.QuotaType, id string, infos map[string]*fsquota.Info) (changes []*fsquota.QuotaChange, err error) {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		lineKey := fmt.Sprintf("line %d", lineNumber)
+		if len(fields) < 3 {
+			err = errortree.Add(err, lineKey, errors.New("expected format is: path bytes files"))
+			continue
+		}
+
+		path := strings.Join(fields[:len(fields)-2], " ")
+		info, ok := infos[path]
+		if !ok {
+			err = errortree.Add(err, lineKey, errors.New("unknown path "+path))
+			continue
+		}
+		if seen[path] {
+			err = errortree.Add(err, lineKey, errors.New("duplicate path "+path))
+			continue
+		}
+		seen[path] = true
+
+		var lineErr, parseErr error
+		var bytesSoft, bytesHard, filesSoft, filesHard uint64
+		if bytesSoft, bytesHard, parseErr = parseLimitsString(fields[len(fields)-2]); parseErr != nil {
+			lineErr = errortree.Add(lineErr, "bytes", parseErr)
+		}
+		if filesSoft, filesHard, parseErr = parseLimitsString(fields[len(fields)-1]); parseErr != nil {
+			lineErr = errortree.Add(lineErr, "files", parseErr)
+		}
+		if bytesHard != 0 && bytesSoft > bytesHard {
+			lineErr = errortree.Add(lineErr, "bytes", errors.New("soft limit exceeds hard limit"))
+		}
+		if filesHard != 0 && filesSoft > filesHard {
+			lineErr = errortree.Add(lineErr, "files", errors.New("soft limit exceeds hard limit"))
+		}
+		if lineErr != nil {
+			err = errortree.Add(err, lineKey, lineErr)
+			continue
+		}
+
+		if bytesSoft == info.Bytes.GetSoft() && bytesHard == info.Bytes.GetHard() &&
+			filesSoft == info.Files.GetSoft() && filesHard == info.Files.GetHard() {
+			continue
+		}
+
+		change := &fsquota.QuotaChange{
+			Path: path,
+			Type: t,
+			ID:   id,
+		}
+		change.Limits.Bytes.SetSoft(bytesSoft)
+		change.Limits.Bytes.SetHard(bytesHard)
+		change.Limits.Files.SetSoft(filesSoft)
+		change.Limits.Files.SetHard(filesHard)
+		changes = append(changes, change)
+	}
+
+	if scanErr := scanner.Err(); scanErr != nil {
+		err = scanErr
+	}
+	if err != nil {
+		changes = nil
+	}
+	return
+}
+
+// checkEditChanges checks changes against the policy before they are applied, errors are keyed by path
+func checkEditChanges(changes []*fsquota.QuotaChange, opts []fsquota.SetOption) (err error) {
+	for _, change := range changes {
+		if checkErr := fsquota.CheckQuotaChanges([]*fsquota.QuotaChange{change}, opts...); checkErr != nil {
+			// Errors of the single change are keyed by its index
+			err = errortree.Add(err, change.Path, errortree.GetAny(checkErr, "0"))
+		}
+	}
+	return
+}
+
+// runEditor opens fileName in $VISUAL or $EDITOR, falling back to vi
+func runEditor(fileName string) (err error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor may be given with arguments, ie. "emacs -nw"
+	args := append(strings.Fields(editor), fileName)
+	editorCmd := exec.Command(args[0], args[1:]...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	return editorCmd.Run()
+}
+
+// editQuota implements the user and group edit commands. The limits of id on paths, or on all filesystems with
+// quotas of type t enabled if none are given, are edited in an editor until they parse and pass the policy and are then
+// applied. Saving the file unchanged aborts the edit.
+func editQuota(cmd *cobra.Command, t fsquota.QuotaType, id, name string, paths []string, getFn func(path string) (*fsquota.Info, error)) (err error) {
+	if len(paths) == 0 {
+		if paths, err = fsquota.QuotaMountPoints(t); err != nil {
+			return
+		}
+
+		if len(paths) == 0 {
+			err = errors.New("no filesystems with " + t.String() + " quotas enabled found")
+			return
+		}
+	}
+
+	infos := make(map[string]*fsquota.Info, len(paths))
+	for _, path := range paths {
+		var getErr error
+		if infos[path], getErr = getFn(path); getErr != nil {
+			err = errortree.Add(err, path, getErr)
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	var file *os.File
+	if file, err = ioutil.TempFile("", "fsqm-edit-"); err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+	if err = file.Close(); err != nil {
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err = writeEditFile(buf, fmt.Sprintf("Editing %s quotas of %s (%s)", t, name, id), paths, infos); err != nil {
+		return
+	}
+
+	// Edits are kept when the editor is reopened after an error
+	data := buf.Bytes()
+	var changes []*fsquota.QuotaChange
+	for {
+		if err = ioutil.WriteFile(file.Name(), data, 0600); err != nil {
+			return
+		}
+
+		if err = runEditor(file.Name()); err != nil {
+			return
+		}
+
+		var edited []byte
+		if edited, err = ioutil.ReadFile(file.Name()); err != nil {
+			return
+		}
+
+		// Saving the file unchanged aborts the edit, also after an error
+		if bytes.Equal(edited, data) {
+			fmt.Fprintln(cmd.OutOrStdout(), "edit aborted")
+			return
+		}
+		data = edited
+
+		var editErr error
+		if changes, editErr = readEditFile(bytes.NewReader(data), t, id, infos); editErr == nil {
+			editErr = checkEditChanges(changes, setOptions(cmd))
+		}
+		if editErr == nil {
+			break
+		}
+		data = withEditError(data, editErr)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "no changes")
+		return
+	}
+
+	if err = fsquota.ApplyQuotaChanges(changes, setOptions(cmd)...); err != nil {
+		return
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%s %d changes\n", appliedVerb(cmd, "apply", "applied"), len(changes))
+	return
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatExactValue(t *testing.T) {
+	assert.EqualValues(t, "0", formatExactValue(0, 1024, editByteUnits))
+	assert.EqualValues(t, "1000", formatExactValue(1000, 1024, editByteUnits))
+	assert.EqualValues(t, "10GiB", formatExactValue(10*1024*1024*1024, 1024, editByteUnits))
+	assert.EqualValues(t, "1536KiB", formatExactValue(1536*1024, 1024, editByteUnits))
+	assert.EqualValues(t, "2M", formatExactValue(2000000, 1000, editFileUnits))
+	assert.EqualValues(t, "1024", formatExactValue(1024, 1000, editFileUnits))
+	assert.EqualValues(t, "8EiB", formatExactValue(8<<60, 1024, editByteUnits))
+
+	for _, value := range []uint64{0, 1, 1023, 1024, 1536 * 1024, 10 * 1024 * 1024 * 1024} {
+		soft, _, err := parseLimitsString(formatExactValue(value, 1024, editByteUnits) + ",0")
+		require.NoError(t, err)
+		assert.EqualValues(t, value, soft)
+	}
+}
+
+func testEditInfos() map[string]*fsquota.Info {
+	info := &fsquota.Info{}
+	info.Bytes.SetSoft(1024 * 1024)
+	info.Bytes.SetHard(2 * 1024 * 1024)
+	info.Files.SetSoft(1000)
+	info.Files.SetHard(2000)
+
+	return map[string]*fsquota.Info{
+		"/home":         info,
+		"/srv/with gap": {},
+	}
+}
+
+func TestReadEditFile(t *testing.T) {
+	t.Run("Unchanged", func(t *testing.T) {
+		infos := testEditInfos()
+		buf := &strings.Builder{}
+		require.NoError(t, writeEditFile(buf, "header", []string{"/home", "/srv/with gap"}, infos))
+
+		changes, err := readEditFile(strings.NewReader(buf.String()), fsquota.QuotaTypeUser, "1000", infos)
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		changes, err := readEditFile(strings.NewReader(`# comment
+/home 1MiB,2MiB 1k,2k
+/srv/with gap 10GiB,12GiB 1M,2M # used 0 B
+`), fsquota.QuotaTypeUser, "1000", testEditInfos())
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+
+		assert.EqualValues(t, "/srv/with gap", changes[0].Path)
+		assert.EqualValues(t, fsquota.QuotaTypeUser, changes[0].Type)
+		assert.EqualValues(t, "1000", changes[0].ID)
+		assert.EqualValues(t, 10*1024*1024*1024, changes[0].Limits.Bytes.GetSoft())
+		assert.EqualValues(t, 12*1024*1024*1024, changes[0].Limits.Bytes.GetHard())
+		assert.EqualValues(t, 1000000, changes[0].Limits.Files.GetSoft())
+		assert.EqualValues(t, 2000000, changes[0].Limits.Files.GetHard())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		changes, err := readEditFile(strings.NewReader(`/home 1MiB
+/unknown 1MiB,2MiB 1k,2k
+/home 2MiB,1MiB 1k,2k
+/srv/with gap 0,0 2k,1k
+/srv/with gap 0,0 0,0
+`), fsquota.QuotaTypeUser, "1000", testEditInfos())
+		assert.Error(t, err)
+		assert.Nil(t, changes)
+
+		for _, key := range []string{"line 1", "line 2", "line 3", "line 4", "line 5"} {
+			assert.Error(t, errortree.Get(err, key), key)
+		}
+		assert.Error(t, errortree.Get(err, "line 3", "bytes"))
+		assert.Nil(t, errortree.Get(err, "line 3", "files"))
+		assert.Error(t, errortree.Get(err, "line 4", "files"))
+		assert.Contains(t, errortree.Get(err, "line 5").Error(), "duplicate path")
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdExport = &cobra.Command{
+	Use:   "export path",
+	Short: "Exports the user, group and project limits of a filesystem keyed by name, for importing them on another host",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		var export *fsquota.Export
+		if export, err = fsquota.ExportQuotas(args[0]); err != nil {
+			return
+		}
+
+		fileName, _ := cmd.Flags().GetString("output-file")
+		if fileName == "" || fileName == "-" {
+			return fsquota.WriteExport(cmd.OutOrStdout(), export)
+		}
+
+		var f *os.File
+		if f, err = os.Create(fileName); err != nil {
+			return
+		}
+
+		if err = fsquota.WriteExport(f, export); err != nil {
+			f.Close()
+			return
+		}
+		return f.Close()
+	},
+}
+
+func init() {
+	cmdExport.Flags().StringP("output-file", "o", "", "File to write the export to, defaults to standard output")
+	cmdRoot.AddCommand(cmdExport)
+}
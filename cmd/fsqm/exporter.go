@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/anexia-it/fsquota/exporter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+)
+
+// parseIDRangesFlag parses a flag holding a list of ID ranges
+func parseIDRangesFlag(cmd *cobra.Command, flagName string) (ranges []fsquota.IDRange, err error) {
+	var rangeStrings []string
+	if rangeStrings, err = cmd.Flags().GetStringSlice(flagName); err != nil {
+		return
+	}
+
+	for i, s := range rangeStrings {
+		r, parseErr := parseIDRange(s)
+		if parseErr != nil {
+			err = errortree.Add(err, strconv.Itoa(i), parseErr)
+			continue
+		}
+		ranges = append(ranges, *r)
+	}
+	return
+}
+
+// writeTextfile writes the textfile once or, if interval is positive, repeatedly until interrupted
+func writeTextfile(cmd *cobra.Command, filename string, registry *prometheus.Registry, interval time.Duration) (err error) {
+	if interval <= 0 {
+		return exporter.WriteTextfile(filename, registry)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Failures are reported but do not stop periodic writes
+		if writeErr := exporter.WriteTextfile(filename, registry); writeErr != nil {
+			cmd.Println(writeErr)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-signals:
+			return
+		}
+	}
+}
+
+var cmdExporter = &cobra.Command{
+	Use:   "exporter [path...]",
+	Short: "Exports quota usage and limits as Prometheus metrics",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		var paths []string
+		if paths, err = collectPaths(cmd, args); err != nil {
+			return
+		}
+
+		filter := &exporter.IDFilter{}
+		var parseErr error
+		if filter.Include, parseErr = parseIDRangesFlag(cmd, "include"); parseErr != nil {
+			err = errortree.Add(err, "include", parseErr)
+		}
+		if filter.Exclude, parseErr = parseIDRangesFlag(cmd, "exclude"); parseErr != nil {
+			err = errortree.Add(err, "exclude", parseErr)
+		}
+		if err != nil {
+			return
+		}
+
+		resolveNames, _ := cmd.Flags().GetBool("resolve-names")
+		registry := prometheus.NewRegistry()
+		if err = registry.Register(exporter.NewCollector(exporter.Options{
+			Paths:        paths,
+			ResolveNames: resolveNames,
+			Filter:       filter,
+		})); err != nil {
+			return
+		}
+
+		if textfile, _ := cmd.Flags().GetString("textfile"); textfile != "" {
+			interval, _ := cmd.Flags().GetDuration("interval")
+			return writeTextfile(cmd, textfile, registry, interval)
+		}
+
+		listen, _ := cmd.Flags().GetString("listen")
+		metricsPath, _ := cmd.Flags().GetString("metrics-path")
+
+		mux := http.NewServeMux()
+		mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		return http.ListenAndServe(listen, mux)
+	},
+}
+
+func init() {
+	cmdExporter.Flags().Bool("all-mounts", false, "Export all filesystems with quotas enabled")
+	cmdExporter.Flags().String("listen", ":9719", "Address to serve metrics on")
+	cmdExporter.Flags().String("metrics-path", "/metrics", "HTTP path to serve metrics under")
+	cmdExporter.Flags().Bool("resolve-names", false, "Add a name label with the user or group name of each ID")
+	cmdExporter.Flags().StringSlice("include", nil, "Only export IDs within the given ranges, ie. 1000-59999")
+	cmdExporter.Flags().StringSlice("exclude", nil, "Do not export IDs within the given ranges, ie. 0-999")
+	cmdExporter.Flags().String("textfile", "", "Write metrics to the given node_exporter textfile instead of serving them")
+	cmdExporter.Flags().Duration("interval", 0, "Rewrite the textfile at the given interval instead of once")
+	cmdRoot.AddCommand(cmdExporter)
+}
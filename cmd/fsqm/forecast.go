@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+func formatReached(reached time.Time, now time.Time) string {
+	if reached.IsZero() {
+		return "never"
+	}
+	if !reached.After(now) {
+		return "exceeded"
+	}
+	return reached.Format("2006-01-02")
+}
+
+var cmdForecast = &cobra.Command{
+	Use:   "forecast path",
+	Short: "Lists the IDs expected to reach a limit soon, based on the usage history",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		historyDir, _ := cmd.Flags().GetString("history-dir")
+		window, _ := cmd.Flags().GetDuration("window")
+		days, _ := cmd.Flags().GetInt("days")
+
+		// The current limits and usage are taken from the filesystem, the history only holds past usage
+		var current *fsquota.Snapshot
+		if current, err = fsquota.TakeSnapshot(args[0]); err != nil {
+			return
+		}
+
+		now := current.Timestamp
+		var history []*fsquota.UsageRecord
+		if history, err = fsquota.NewHistoryStore(historyDir).Read(args[0], now.Add(-window)); err != nil {
+			return
+		}
+
+		if len(history) == 0 {
+			err = errors.New("at least one collected sample is required, see fsqm collect")
+			return
+		}
+
+		horizon := now.Add(time.Duration(days) * 24 * time.Hour)
+		var expected []*fsquota.Forecast
+		for _, forecast := range fsquota.ForecastUsage(history, current) {
+			if first := forecast.FirstLimitReached(); !first.IsZero() && first.Before(horizon) {
+				expected = append(expected, forecast)
+			}
+		}
+
+		sort.SliceStable(expected, func(i, j int) bool {
+			return expected[i].FirstLimitReached().Before(expected[j].FirstLimitReached())
+		})
+
+		if len(expected) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "no limits expected to be reached within %d days\n", days)
+			return
+		}
+
+		for _, forecast := range expected {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s:\n", forecast.Type, quotaTypeLookupFn(forecast.Type, false)(forecast.ID))
+			fmt.Fprintf(cmd.OutOrStdout(), "  - bytes: %s used, %s/day, soft: %s, hard: %s\n",
+				humanize.IBytes(forecast.Bytes.Used), formatDelta(int64(forecast.Bytes.GrowthPerDay), humanize.IBytes),
+				formatReached(forecast.Bytes.SoftLimitReached, now), formatReached(forecast.Bytes.HardLimitReached, now))
+			fmt.Fprintf(cmd.OutOrStdout(), "  - files: %s used, %s/day, soft: %s, hard: %s\n",
+				humanizeInodes(forecast.Files.Used), formatDelta(int64(forecast.Files.GrowthPerDay), humanizeInodes),
+				formatReached(forecast.Files.SoftLimitReached, now), formatReached(forecast.Files.HardLimitReached, now))
+		}
+
+		return
+	},
+}
+
+func init() {
+	cmdForecast.Flags().IntP("days", "d", 7, "Number of days to look ahead")
+	cmdForecast.Flags().Duration("window", 30*24*time.Hour, "Period of history to base the growth estimate on")
+	cmdForecast.Flags().String("history-dir", fsquota.DefaultHistoryDir, "Directory the usage history is stored in")
+	cmdRoot.AddCommand(cmdForecast)
+}
@@ -16,4 +16,20 @@ func main() {
 var cmdRoot = &cobra.Command{
 	Use:   "fsqm",
 	Short: "filesystem quota manager",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
+		// Other commands keep working if the policy or audit log can not be read
+		if !changesQuotas(cmd) {
+			return
+		}
+
+		if err = loadPolicy(cmd); err != nil {
+			return
+		}
+		return loadAuditSink(cmd)
+	},
+}
+
+func init() {
+	cmdRoot.PersistentFlags().Bool("force", false, "Apply changes violating the quota policy")
+	cmdRoot.PersistentFlags().String("policy", defaultPolicyFile, "Quota policy file, changes are not validated if it does not exist")
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"os/user"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdGroupClear = &cobra.Command{
+	Use:   "clear path group",
+	Short: "Removes all quota limits of a given group",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 2 {
+			err = errors.New("exactly two arguments required")
+			return
+		}
+
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
+		}
+
+		if isRemote(cmd) {
+			return remoteClear(cmd, printer, args[0], fsquota.QuotaTypeGroup, args[1])
+		}
+
+		var g *user.Group
+		if g, err = lookupGroup(args[1]); err != nil {
+			return
+		}
+
+		var info *fsquota.Info
+		if info, err = fsquota.ClearGroupQuota(args[0], g, setOptions(cmd)...); err != nil {
+			return
+		}
+
+		return printer.printQuota(args[0], fsquota.QuotaTypeGroup, g.Gid, resolvedName(lookupGroupnameByGid, g.Gid), info)
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdGroupClear)
+	addOutputFlags(cmdGroupClear)
+	addRemoteFlags(cmdGroupClear)
+	cmdGroup.AddCommand(cmdGroupClear)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os/user"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+func resolveGid(groupIdOrGroupName string) (gid string, err error) {
+	var g *user.Group
+	if g, err = lookupGroup(groupIdOrGroupName); err == nil {
+		gid = g.Gid
+	}
+	return
+}
+
+var cmdGroupCopy = &cobra.Command{
+	Use:   "copy [path] prototype group...",
+	Short: "Applies the quota limits of a prototype group to other groups",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		return copyQuota(cmd, args, fsquota.QuotaTypeGroup, resolveGid, lookupGroupnameByGid)
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdGroupCopy)
+	cmdGroupCopy.Flags().Bool("all-mounts", false, "Copy limits on all filesystems with group quotas enabled, path must be omitted")
+	cmdGroup.AddCommand(cmdGroupCopy)
+}
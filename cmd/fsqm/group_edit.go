@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"os/user"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdGroupEdit = &cobra.Command{
+	Use:   "edit group [path...]",
+	Short: "Edits a group's quota limits in $EDITOR, on all filesystems with group quotas enabled if no path is given",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) < 1 {
+			err = errors.New("at least one argument required")
+			return
+		}
+
+		var g *user.Group
+		if g, err = lookupGroup(args[0]); err != nil {
+			return
+		}
+
+		return editQuota(cmd, fsquota.QuotaTypeGroup, g.Gid, lookupGroupnameByGid(g.Gid), args[1:], func(path string) (*fsquota.Info, error) {
+			return fsquota.GetGroupInfo(path, g)
+		})
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdGroupEdit)
+	cmdGroup.AddCommand(cmdGroupEdit)
+}
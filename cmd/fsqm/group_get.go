@@ -17,6 +17,15 @@ var cmdGroupGet = &cobra.Command{
 			return
 		}
 
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
+		}
+
+		if isRemote(cmd) {
+			return remoteGet(cmd, printer, args[0], fsquota.QuotaTypeGroup, args[1])
+		}
+
 		var g *user.Group
 		if g, err = lookupGroup(args[1]); err != nil {
 			return
@@ -27,12 +36,12 @@ var cmdGroupGet = &cobra.Command{
 			return
 		}
 
-		printQuotaInfo(cmd, info)
-
-		return
+		return printer.printQuota(args[0], fsquota.QuotaTypeGroup, g.Gid, resolvedName(lookupGroupnameByGid, g.Gid), info)
 	},
 }
 
 func init() {
+	addOutputFlags(cmdGroupGet)
+	addRemoteFlags(cmdGroupGet)
 	cmdGroup.AddCommand(cmdGroupGet)
 }
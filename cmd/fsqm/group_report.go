@@ -24,23 +24,36 @@ var cmdGroupReport = &cobra.Command{
 			return
 		}
 
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
+		}
+
+		if isRemote(cmd) {
+			return remoteReport(cmd, printer, args[0], fsquota.QuotaTypeGroup)
+		}
+
 		var report *fsquota.Report
 		if report, err = fsquota.GetGroupReport(args[0]); err != nil {
 			return
 		}
 
-		lookupFn := lookupGroupnameByGid
-
-		if wantNumeric, _ := cmd.Flags().GetBool("numeric"); wantNumeric {
-			lookupFn = noopLookup
+		var entries []*fsquota.ReportEntry
+		if entries, err = queryReport(cmd, report, func(id string) string {
+			return resolvedName(lookupGroupnameByGid, id)
+		}); err != nil {
+			return
 		}
 
-		printReport(cmd, report, "group", lookupFn)
-		return
+		numeric, _ := cmd.Flags().GetBool("numeric")
+		return printer.printEntries(args[0], fsquota.QuotaTypeGroup, entries, numeric)
 	},
 }
 
 func init() {
 	cmdGroupReport.Flags().BoolP("numeric", "n", false, "Print numeric group IDs")
+	addReportQueryFlags(cmdGroupReport)
+	addOutputFlags(cmdGroupReport)
+	addRemoteFlags(cmdGroupReport)
 	cmdGroup.AddCommand(cmdGroupReport)
 }
@@ -5,7 +5,6 @@ import (
 	"os/user"
 
 	"github.com/anexia-it/fsquota"
-	"github.com/speijnik/go-errortree"
 	"github.com/spf13/cobra"
 )
 
@@ -18,20 +17,18 @@ var cmdGroupSet = &cobra.Command{
 			return
 		}
 
-		var bytesSoft, bytesHard, filesSoft, filesHard uint64
-		var bytesPresent, filesPresent bool
-		var parseErr error
-
-		if bytesSoft, bytesHard, bytesPresent, parseErr = parseLimitsFlag(cmd, "bytes"); parseErr != nil {
-			err = errortree.Add(err, "bytes", parseErr)
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
 		}
 
-		if filesSoft, filesHard, filesPresent, parseErr = parseLimitsFlag(cmd, "files"); parseErr != nil {
-			err = errortree.Add(err, "files", parseErr)
+		var change *fsquota.LimitChange
+		if change, err = parseLimitChangeFlags(cmd); err != nil {
+			return
 		}
 
-		if err != nil {
-			return
+		if isRemote(cmd) {
+			return remoteSet(cmd, printer, args[0], fsquota.QuotaTypeGroup, args[1], change)
 		}
 
 		var g *user.Group
@@ -39,35 +36,24 @@ var cmdGroupSet = &cobra.Command{
 			return
 		}
 
-		var info *fsquota.Info
-		limits := fsquota.Limits{}
-
-		if bytesPresent {
-			limits.Bytes.SetSoft(bytesSoft)
-			limits.Bytes.SetHard(bytesHard)
-		}
-
-		if filesPresent {
-			limits.Files.SetSoft(filesSoft)
-			limits.Files.SetHard(filesHard)
-		}
-
-		if !filesPresent && !bytesPresent {
-			err = errors.New("nothing to set")
+		var limits *fsquota.Limits
+		if limits, err = fsquota.ResolveLimits(args[0], fsquota.QuotaTypeGroup, g.Gid, change); err != nil {
 			return
 		}
 
-		if info, err = fsquota.SetGroupQuota(args[0], g, limits); err != nil {
+		var info *fsquota.Info
+		if info, err = fsquota.SetGroupQuota(args[0], g, *limits, setOptions(cmd)...); err != nil {
 			return
 		}
 
-		printQuotaInfo(cmd, info)
-		return
+		return printer.printQuota(args[0], fsquota.QuotaTypeGroup, g.Gid, resolvedName(lookupGroupnameByGid, g.Gid), info)
 	},
 }
 
 func init() {
-	cmdGroupSet.Flags().StringP("bytes", "b", "", "Byte limit in soft,hard format. ie. 1MiB,2GiB")
-	cmdGroupSet.Flags().StringP("files", "f", "", "File limit in soft,hard format, ie. 1M,2G")
+	markChangesQuotas(cmdGroupSet)
+	addLimitChangeFlags(cmdGroupSet)
+	addOutputFlags(cmdGroupSet)
+	addRemoteFlags(cmdGroupSet)
 	cmdGroup.AddCommand(cmdGroupSet)
 }
@@ -2,7 +2,11 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/anexia-it/fsquota"
@@ -12,22 +16,42 @@ import (
 )
 
 func humanizeInodes(inodes uint64) string {
-	return strings.TrimSuffix(humanize.Bytes(inodes), "B")
+	return strings.TrimSpace(strings.TrimSuffix(humanize.Bytes(inodes), "B"))
 }
 
 func printInfo(cmd *cobra.Command, info *fsquota.Info, prefix string) {
-	cmd.Println(prefix + "bytes:")
-	cmd.Printf(prefix+"  - soft: %s\n", humanize.IBytes(info.Bytes.GetSoft()))
-	cmd.Printf(prefix+"  - hard: %s\n", humanize.IBytes(info.Bytes.GetHard()))
-	cmd.Printf(prefix+"  - used: %s\n", humanize.IBytes(info.BytesUsed))
-	cmd.Println(prefix + "files:")
-	cmd.Printf(prefix+"  - soft: %s\n", humanizeInodes(info.Files.GetSoft()))
-	cmd.Printf(prefix+"  - hard: %s\n", humanizeInodes(info.Files.GetHard()))
-	cmd.Printf(prefix+"  - used: %s\n", humanizeInodes(info.FilesUsed))
+	out := cmd.OutOrStdout()
+	bytesFn, filesFn := numberFormats(humanNumbers(cmd, true))
+
+	if info.InheritsDefaultLimits {
+		fmt.Fprintln(out, prefix+"limits: match the default, probably inherited")
+	}
+	fmt.Fprintln(out, prefix+"bytes:")
+	fmt.Fprintf(out, prefix+"  - soft: %s\n", bytesFn(info.Bytes.GetSoft()))
+	fmt.Fprintf(out, prefix+"  - hard: %s\n", bytesFn(info.Bytes.GetHard()))
+	fmt.Fprintf(out, prefix+"  - used: %s\n", bytesFn(info.BytesUsed))
+	if !info.BytesGraceExpires.IsZero() {
+		fmt.Fprintf(out, prefix+"  - grace expires: %s\n", info.BytesGraceExpires.Format(time.RFC3339))
+	}
+	fmt.Fprintln(out, prefix+"files:")
+	fmt.Fprintf(out, prefix+"  - soft: %s\n", filesFn(info.Files.GetSoft()))
+	fmt.Fprintf(out, prefix+"  - hard: %s\n", filesFn(info.Files.GetHard()))
+	fmt.Fprintf(out, prefix+"  - used: %s\n", filesFn(info.FilesUsed))
+	if !info.FilesGraceExpires.IsZero() {
+		fmt.Fprintf(out, prefix+"  - grace expires: %s\n", info.FilesGraceExpires.Format(time.RFC3339))
+	}
 }
 
-func printQuotaInfo(cmd *cobra.Command, info *fsquota.Info) {
-	printInfo(cmd, info, "")
+func printLimits(cmd *cobra.Command, limits *fsquota.Limits, prefix string) {
+	out := cmd.OutOrStdout()
+	bytesFn, filesFn := numberFormats(humanNumbers(cmd, true))
+
+	fmt.Fprintln(out, prefix+"bytes:")
+	fmt.Fprintf(out, prefix+"  - soft: %s\n", bytesFn(limits.Bytes.GetSoft()))
+	fmt.Fprintf(out, prefix+"  - hard: %s\n", bytesFn(limits.Bytes.GetHard()))
+	fmt.Fprintln(out, prefix+"files:")
+	fmt.Fprintf(out, prefix+"  - soft: %s\n", filesFn(limits.Files.GetSoft()))
+	fmt.Fprintf(out, prefix+"  - hard: %s\n", filesFn(limits.Files.GetHard()))
 }
 
 func noopLookup(s string) string {
@@ -35,10 +59,32 @@ func noopLookup(s string) string {
 }
 
 func printReport(cmd *cobra.Command, report *fsquota.Report, reportType string, lookupFn func(string) string) {
-	for identifier, info := range report.Infos {
-		cmd.Printf("%s %s:\n", reportType, lookupFn(identifier))
-		printInfo(cmd, info, "  ")
+	out := cmd.OutOrStdout()
+	for _, identifier := range report.IDs() {
+		fmt.Fprintf(out, "%s %s:\n", reportType, lookupFn(identifier))
+		printInfo(cmd, report.Infos[identifier], "  ")
+	}
+}
+
+func parseQuotaTypeFlag(cmd *cobra.Command) (quotaType fsquota.QuotaType, err error) {
+	var typeString string
+	if typeString, err = cmd.Flags().GetString("type"); err != nil {
+		return
+	}
+
+	return parseQuotaType(typeString)
+}
+
+func parseQuotaType(typeString string) (quotaType fsquota.QuotaType, err error) {
+	for _, t := range []fsquota.QuotaType{fsquota.QuotaTypeUser, fsquota.QuotaTypeGroup, fsquota.QuotaTypeProject} {
+		if t.String() == typeString {
+			quotaType = t
+			return
+		}
 	}
+
+	err = errors.New("quota type must be one of user, group or project")
+	return
 }
 
 func isNumeric(s string) bool {
@@ -51,18 +97,166 @@ func isNumeric(s string) bool {
 	return true
 }
 
-func parseLimitsFlag(cmd *cobra.Command, flagName string) (soft, hard uint64, present bool, err error) {
-	var flagString string
-	if flagString, err = cmd.Flags().GetString(flagName); err != nil {
+// parseLimitValue parses a single limit, given as absolute value (1GiB), percentage of the filesystem capacity (5%)
+// or as delta to the current limit (+10GiB, -1GiB)
+func parseLimitValue(s string) (value *fsquota.LimitValue, err error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasSuffix(s, "%") {
+		var percent float64
+		if percent, err = strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64); err != nil {
+			return
+		}
+		value = &fsquota.LimitValue{Kind: fsquota.LimitPercent, Percent: percent}
+		return
+	}
+
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		var delta uint64
+		if delta, err = humanize.ParseBytes(s[1:]); err != nil {
+			return
+		}
+		if delta > math.MaxInt64 {
+			err = errors.New("delta out of range")
+			return
+		}
+
+		value = &fsquota.LimitValue{Kind: fsquota.LimitDelta, Delta: int64(delta)}
+		if s[0] == '-' {
+			value.Delta = -value.Delta
+		}
+		return
+	}
+
+	var absolute uint64
+	if absolute, err = humanize.ParseBytes(s); err != nil {
+		return
+	}
+	value = &fsquota.LimitValue{Kind: fsquota.LimitAbsolute, Value: absolute}
+	return
+}
+
+// parseLimitValues parses limits in soft,hard format. Either value may be omitted to leave it unchanged.
+func parseLimitValues(s string) (soft, hard *fsquota.LimitValue, err error) {
+	if s == "" {
+		return
+	}
+
+	valueParts := strings.Split(s, ",")
+	if len(valueParts) != 2 {
+		err = errors.New("expected format is soft,hard")
+		return
+	}
+
+	var parseErr error
+	if valueParts[0] != "" {
+		if soft, parseErr = parseLimitValue(valueParts[0]); parseErr != nil {
+			err = errortree.Add(err, "soft", parseErr)
+		}
+	}
+
+	if valueParts[1] != "" {
+		if hard, parseErr = parseLimitValue(valueParts[1]); parseErr != nil {
+			err = errortree.Add(err, "hard", parseErr)
+		}
+	}
+
+	return
+}
+
+// parseLimitChange parses byte and file limits in the soft,hard format of the set commands, including percentages
+// and deltas. Empty strings leave the limits unchanged.
+func parseLimitChange(bytesString, filesString string) (change *fsquota.LimitChange, err error) {
+	change = &fsquota.LimitChange{}
+
+	var parseErr error
+	if change.BytesSoft, change.BytesHard, parseErr = parseLimitValues(bytesString); parseErr != nil {
+		err = errortree.Add(err, "bytes", parseErr)
+	}
+
+	if change.FilesSoft, change.FilesHard, parseErr = parseLimitValues(filesString); parseErr != nil {
+		err = errortree.Add(err, "files", parseErr)
+	}
+
+	if err != nil {
+		change = nil
+	}
+	return
+}
+
+// limitChangeEmpty reports whether change leaves all limits unchanged
+func limitChangeEmpty(change *fsquota.LimitChange) bool {
+	return change.BytesSoft == nil && change.BytesHard == nil && change.FilesSoft == nil && change.FilesHard == nil
+}
+
+// resolveLimitChange resolves change for an ID and sets the resulting limits on limits.
+// Limits not part of change are left unset.
+func resolveLimitChange(path string, t fsquota.QuotaType, id string, change *fsquota.LimitChange, limits *fsquota.Limits) (err error) {
+	var resolved *fsquota.Limits
+	if resolved, err = fsquota.ResolveLimits(path, t, id, change); err != nil {
+		return
+	}
+
+	for _, target := range []struct {
+		value *fsquota.LimitValue
+		getFn func() uint64
+		setFn func(uint64)
+	}{
+		{change.BytesSoft, resolved.Bytes.GetSoft, limits.Bytes.SetSoft},
+		{change.BytesHard, resolved.Bytes.GetHard, limits.Bytes.SetHard},
+		{change.FilesSoft, resolved.Files.GetSoft, limits.Files.SetSoft},
+		{change.FilesHard, resolved.Files.GetHard, limits.Files.SetHard},
+	} {
+		if target.value != nil {
+			target.setFn(target.getFn())
+		}
+	}
+	return
+}
+
+// resolveDeclaredLimits parses the byte and file limits declared for the filesystem at path, in the same format as
+// parseLimitChange, and sets them on limits. Percentages are resolved against its capacity, deltas are rejected as
+// they would compound each time the declaration is applied.
+func resolveDeclaredLimits(path string, bytesString, filesString string, limits *fsquota.Limits) (err error) {
+	var change *fsquota.LimitChange
+	if change, err = parseLimitChange(bytesString, filesString); err != nil {
 		return
 	}
 
-	if flagString == "" {
+	for _, value := range []*fsquota.LimitValue{change.BytesSoft, change.BytesHard, change.FilesSoft, change.FilesHard} {
+		if value != nil && value.Kind == fsquota.LimitDelta {
+			err = errors.New("deltas are not supported in declared limits")
+			return
+		}
+	}
+
+	// Without deltas the current limits of the ID are not consulted
+	return resolveLimitChange(path, fsquota.QuotaTypeUser, "0", change, limits)
+}
+
+// parseLimitChangeFlags parses the bytes and files flags
+func parseLimitChangeFlags(cmd *cobra.Command) (change *fsquota.LimitChange, err error) {
+	bytesString, _ := cmd.Flags().GetString("bytes")
+	filesString, _ := cmd.Flags().GetString("files")
+	if change, err = parseLimitChange(bytesString, filesString); err != nil {
 		return
 	}
-	present = true
 
-	valueParts := strings.Split(flagString, ",")
+	if limitChangeEmpty(change) {
+		change = nil
+		err = errors.New("nothing to set")
+	}
+	return
+}
+
+// addLimitChangeFlags registers the bytes and files flags parsed by parseLimitChangeFlags
+func addLimitChangeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("bytes", "b", "", "Byte limit in soft,hard format, ie. 1MiB,2GiB. Values may be percentages of the filesystem size (5%) or deltas (+1GiB), either may be omitted (,2GiB)")
+	cmd.Flags().StringP("files", "f", "", "File limit in soft,hard format, ie. 1M,2G. Values may be percentages of the filesystem's inodes (5%) or deltas (+1k), either may be omitted (1k,)")
+}
+
+func parseLimitsString(s string) (soft, hard uint64, err error) {
+	valueParts := strings.Split(s, ",")
 	if len(valueParts) != 2 {
 		err = errors.New("expected format is soft,hard")
 		return
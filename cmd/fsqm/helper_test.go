@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimitChange(t *testing.T) {
+	change, err := parseLimitChange("1MiB,+1GiB", ",5%")
+	require.NoError(t, err)
+	assert.EqualValues(t, &fsquota.LimitValue{Kind: fsquota.LimitAbsolute, Value: 1024 * 1024}, change.BytesSoft)
+	assert.EqualValues(t, &fsquota.LimitValue{Kind: fsquota.LimitDelta, Delta: 1024 * 1024 * 1024}, change.BytesHard)
+	assert.Nil(t, change.FilesSoft)
+	assert.EqualValues(t, &fsquota.LimitValue{Kind: fsquota.LimitPercent, Percent: 5}, change.FilesHard)
+
+	change, err = parseLimitChange("", "")
+	require.NoError(t, err)
+	assert.True(t, limitChangeEmpty(change))
+
+	change, err = parseLimitChange("1MiB", "x,1")
+	assert.Nil(t, change)
+	assert.Error(t, errortree.Get(err, "bytes"))
+	assert.Error(t, errortree.Get(err, "files", "soft"))
+}
+
+func TestApplyRecord_ToChange(t *testing.T) {
+	change, err := (&applyRecord{Path: "/srv", Type: "project", ID: "5", Bytes: "1MiB,2MiB", Files: "-"}).toChange()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1024*1024, change.Limits.Bytes.GetSoft())
+	assert.EqualValues(t, 2*1024*1024, change.Limits.Bytes.GetHard())
+
+	// Omitted values are left unset
+	change, err = (&applyRecord{Path: "/srv", Type: "project", ID: "5", Bytes: ",2MiB"}).toChange()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2*1024*1024, change.Limits.Bytes.GetHard())
+	assert.EqualValues(t, 0, change.Limits.Bytes.GetSoft())
+
+	change, err = (&applyRecord{Path: "/srv", Type: "project", ID: "5", Bytes: "1MiB"}).toChange()
+	assert.Nil(t, change)
+	assert.Error(t, errortree.Get(err, "limits", "bytes"))
+}
+
+func TestResolveDeclaredLimits(t *testing.T) {
+	limits := &fsquota.Limits{}
+	require.NoError(t, resolveDeclaredLimits("/srv", "1MiB,2MiB", ",10", limits))
+	assert.EqualValues(t, 1024*1024, limits.Bytes.GetSoft())
+	assert.EqualValues(t, 2*1024*1024, limits.Bytes.GetHard())
+	assert.EqualValues(t, 10, limits.Files.GetHard())
+
+	assert.Error(t, resolveDeclaredLimits("/srv", "+1MiB,2MiB", "", &fsquota.Limits{}))
+	assert.Error(t, resolveDeclaredLimits("/srv", "1MiB", "", &fsquota.Limits{}))
+}
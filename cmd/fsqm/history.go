@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+// parseTimeFlag parses a flag holding either a RFC 3339 timestamp or a duration before now
+func parseTimeFlag(cmd *cobra.Command, flagName string, now time.Time) (t time.Time, err error) {
+	var s string
+	if s, err = cmd.Flags().GetString(flagName); err != nil || s == "" {
+		return
+	}
+
+	if d, parseErr := time.ParseDuration(s); parseErr == nil {
+		t = now.Add(-d)
+		return
+	}
+
+	if t, err = time.Parse(time.RFC3339, s); err != nil {
+		err = errors.New(flagName + " must be a duration or RFC 3339 timestamp")
+	}
+	return
+}
+
+func formatAuditLimits(limits *fsquota.AuditLimits) string {
+	if limits == nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("bytes %s,%s files %s,%s",
+		humanize.IBytes(limits.BytesSoft), humanize.IBytes(limits.BytesHard),
+		humanizeInodes(limits.FilesSoft), humanizeInodes(limits.FilesHard))
+}
+
+func printAuditRecord(cmd *cobra.Command, record *fsquota.AuditRecord, numeric bool) {
+	fmt.Fprintf(cmd.OutOrStdout(), "%s %s uid %s on %s: %s %s %s on %s: %s -> %s\n",
+		record.ChangeID, record.Timestamp.Local().Format(time.RFC3339),
+		quotaTypeLookupFn(fsquota.QuotaTypeUser, numeric)(fmt.Sprint(record.CallerUID)), record.Host,
+		record.Operation, record.Type, quotaTypeLookupFn(record.Type, numeric)(record.ID), record.Path,
+		formatAuditLimits(record.OldLimits), formatAuditLimits(record.NewLimits))
+}
+
+// readAuditLog reads the audit log configured via the audit-log flag
+func readAuditLog(cmd *cobra.Command) (records []*fsquota.AuditRecord, err error) {
+	fileName, _ := cmd.Flags().GetString("audit-log")
+	if fileName == "" {
+		err = errors.New("audit-log required")
+		return
+	}
+	return fsquota.ReadAuditLog(fileName)
+}
+
+var cmdHistory = &cobra.Command{
+	Use:   "history [path]",
+	Short: "Lists the quota changes recorded in the audit log",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) > 1 {
+			err = errors.New("at most one argument expected")
+			return
+		}
+
+		filter := &fsquota.AuditFilter{}
+		if len(args) == 1 {
+			// Changes are recorded against the mount point, paths no longer mounted are matched as given
+			if filter.Path, err = fsquota.MountPoint(args[0]); err != nil {
+				filter.Path = args[0]
+				err = nil
+			}
+		}
+
+		now := time.Now()
+		if filter.Since, err = parseTimeFlag(cmd, "since", now); err != nil {
+			return
+		}
+		if filter.Until, err = parseTimeFlag(cmd, "until", now); err != nil {
+			return
+		}
+
+		if typeString, _ := cmd.Flags().GetString("type"); typeString != "" {
+			var t fsquota.QuotaType
+			if t, err = parseQuotaType(typeString); err != nil {
+				return
+			}
+			filter.Type = &t
+
+			if id, _ := cmd.Flags().GetString("id"); id != "" {
+				if filter.ID, err = resolveQuotaID(t, id); err != nil {
+					return
+				}
+			}
+		} else if id, _ := cmd.Flags().GetString("id"); id != "" {
+			err = errors.New("type required to filter by ID")
+			return
+		}
+
+		var records []*fsquota.AuditRecord
+		if records, err = readAuditLog(cmd); err != nil {
+			return
+		}
+
+		var matching []*fsquota.AuditRecord
+		for _, record := range records {
+			if filter.Matches(record) {
+				matching = append(matching, record)
+			}
+		}
+
+		// The most recent changes are listed if limited
+		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(matching) > limit {
+			matching = matching[len(matching)-limit:]
+		}
+
+		numeric, _ := cmd.Flags().GetBool("numeric")
+		for _, record := range matching {
+			printAuditRecord(cmd, record, numeric)
+		}
+		return
+	},
+}
+
+func init() {
+	cmdHistory.Flags().StringP("type", "t", "", "Only list changes of the given quota type, one of user, group or project")
+	cmdHistory.Flags().String("id", "", "Only list changes of the given user, group or project, requires type")
+	cmdHistory.Flags().String("since", "", "Only list changes after the given RFC 3339 time or duration ago, ie. 24h")
+	cmdHistory.Flags().String("until", "", "Only list changes before the given RFC 3339 time or duration ago")
+	cmdHistory.Flags().IntP("limit", "n", 0, "Only list the given number of most recent changes")
+	cmdHistory.Flags().BoolP("numeric", "N", false, "Print numeric user and group IDs")
+	cmdRoot.AddCommand(cmdHistory)
+}
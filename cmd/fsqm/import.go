@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+func readExportFile(fileName string) (export *fsquota.Export, err error) {
+	if fileName == "-" {
+		return fsquota.ReadExport(os.Stdin)
+	}
+
+	var f *os.File
+	if f, err = os.Open(fileName); err != nil {
+		return
+	}
+	defer f.Close()
+
+	return fsquota.ReadExport(f)
+}
+
+func readImportMappingFile(fileName string) (mapping fsquota.ImportMapping, err error) {
+	var f *os.File
+	if f, err = os.Open(fileName); err != nil {
+		return
+	}
+	defer f.Close()
+
+	return fsquota.ReadImportMapping(f)
+}
+
+var cmdImport = &cobra.Command{
+	Use:   "import path file",
+	Short: "Applies the default limits and limits of an export to a filesystem, resolving users, groups and projects by name",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 2 {
+			err = errors.New("exactly two arguments required")
+			return
+		}
+
+		var export *fsquota.Export
+		if export, err = readExportFile(args[1]); err != nil {
+			return
+		}
+
+		mapping := fsquota.ImportMapping{}
+		if mappingFile, _ := cmd.Flags().GetString("mapping"); mappingFile != "" {
+			if mapping, err = readImportMappingFile(mappingFile); err != nil {
+				return
+			}
+		}
+
+		keepIDs, _ := cmd.Flags().GetBool("keep-ids")
+		result := fsquota.ResolveImport(export, args[0], mapping, keepIDs, setOptions(cmd)...)
+		if len(result.Unresolved) > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "unresolved entries from %s:%s:\n", export.Host, export.Path)
+			for _, unresolved := range result.Unresolved {
+				entry := unresolved.Entry
+				if entry.Name != "" {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %s %s (%s): %s\n", entry.Type, entry.Name, entry.ID, unresolved.Err)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %s %s: %s\n", entry.Type, entry.ID, unresolved.Err)
+				}
+			}
+
+			if strict, _ := cmd.Flags().GetBool("strict"); strict {
+				err = fmt.Errorf("%d entries could not be resolved", len(result.Unresolved))
+				return
+			}
+		}
+
+		if len(result.Changes) == 0 && len(result.Defaults) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "nothing to import")
+			return
+		}
+
+		if len(result.Changes) > 0 {
+			if err = fsquota.ApplyQuotaChanges(result.Changes, setOptions(cmd)...); err != nil {
+				return
+			}
+		}
+
+		var skipped map[fsquota.QuotaType]error
+		if skipped, err = fsquota.ImportDefaults(result, args[0], setOptions(cmd)...); err != nil {
+			return
+		}
+
+		for _, t := range fsquota.QuotaTypes() {
+			if limits, ok := result.Defaults[t]; ok {
+				if skipErr, isSkipped := skipped[t]; isSkipped {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s defaults: not imported, %s\n", t, skipErr)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s defaults: %s\n", t, formatLimits(limits))
+				}
+			}
+		}
+		for _, change := range result.Changes {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s: %s\n", change.Type, quotaTypeLookupFn(change.Type, false)(change.ID), formatLimits(&change.Limits))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %d changes\n", appliedVerb(cmd, "apply", "applied"), len(result.Changes)+len(result.Defaults)-len(skipped))
+		return
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdImport)
+	cmdImport.Flags().StringP("mapping", "m", "", "File mapping names or IDs of the exporting host to names or IDs on this host, one \"type old new\" entry per line, ie. \"user alice alice.smith\"")
+	cmdImport.Flags().Bool("keep-ids", false, "Apply entries exported without a name to the same ID instead of reporting them as unresolved")
+	cmdImport.Flags().Bool("strict", false, "Fail without applying any changes if an entry cannot be resolved, instead of skipping it")
+	cmdRoot.AddCommand(cmdImport)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var inspectQuotaTypes = []fsquota.QuotaType{fsquota.QuotaTypeUser, fsquota.QuotaTypeGroup, fsquota.QuotaTypeProject}
+
+func quotaTypeLookupFn(t fsquota.QuotaType, numeric bool) func(string) string {
+	if numeric {
+		return noopLookup
+	}
+
+	switch t {
+	case fsquota.QuotaTypeUser:
+		return lookupUsernameByUid
+	case fsquota.QuotaTypeGroup:
+		return lookupGroupnameByGid
+	}
+	return noopLookup
+}
+
+var cmdInspect = &cobra.Command{
+	Use:   "inspect image",
+	Short: "Inspects quota information of an unmounted ext4 or XFS filesystem image or block device",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		var inspection *fsquota.ImageInspection
+		if inspection, err = fsquota.InspectImage(args[0]); err != nil {
+			return
+		}
+
+		wantNumeric, _ := cmd.Flags().GetBool("numeric")
+
+		fmt.Fprintf(cmd.OutOrStdout(), "filesystem: %s\n", inspection.Filesystem)
+		fmt.Fprintf(cmd.OutOrStdout(), "quota feature: %t\n", inspection.QuotaFeature)
+		fmt.Fprintf(cmd.OutOrStdout(), "project quota feature: %t\n", inspection.ProjectQuotaFeature)
+
+		for _, t := range inspectQuotaTypes {
+			inode, haveInode := inspection.QuotaInodes[t]
+			_, haveAccounting := inspection.Accounting[t]
+			if !haveInode && !haveAccounting {
+				continue
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s quota:\n", t)
+			if haveInode {
+				fmt.Fprintf(cmd.OutOrStdout(), "  - inode: %d\n", inode)
+			}
+
+			if haveAccounting {
+				fmt.Fprintf(cmd.OutOrStdout(), "  - accounting: %t\n", inspection.Accounting[t])
+				fmt.Fprintf(cmd.OutOrStdout(), "  - enforcement: %t\n", inspection.Enforcement[t])
+			}
+
+			if quotaFile, haveQuotaFile := inspection.QuotaFiles[t]; haveQuotaFile {
+				fmt.Fprintf(cmd.OutOrStdout(), "  - format version: %d\n", quotaFile.Version)
+				fmt.Fprintf(cmd.OutOrStdout(), "  - bytes grace period: %s\n", time.Duration(quotaFile.BytesGracePeriod)*time.Second)
+				fmt.Fprintf(cmd.OutOrStdout(), "  - files grace period: %s\n", time.Duration(quotaFile.FilesGracePeriod)*time.Second)
+				printReport(cmd, quotaFile.Report, t.String(), quotaTypeLookupFn(t, wantNumeric))
+			}
+		}
+
+		return
+	},
+}
+
+func init() {
+	cmdInspect.Flags().BoolP("numeric", "n", false, "Print numeric user and group IDs")
+	cmdRoot.AddCommand(cmdInspect)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/anexia-it/fsquota/notify"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultNotifyConfigFile = "/etc/fsqm/notify.yaml"
+
+// notifyDocument is the notification configuration file format
+type notifyDocument struct {
+	Repeat time.Duration       `yaml:"repeat"`
+	SMTP   *smtpEntry          `yaml:"smtp"`
+	Mounts []*notifyMountEntry `yaml:"mounts"`
+}
+
+type smtpEntry struct {
+	Addr     string `yaml:"addr"`
+	From     string `yaml:"from"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type notifiersEntry struct {
+	Email   *emailEntry   `yaml:"email"`
+	Webhook *webhookEntry `yaml:"webhook"`
+	Exec    []string      `yaml:"exec"`
+}
+
+type emailEntry struct {
+	To      string `yaml:"to"`
+	Subject string `yaml:"subject"`
+	Body    string `yaml:"body"`
+}
+
+type webhookEntry struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+type notifyMountEntry struct {
+	Path         string        `yaml:"path"`
+	Types        []string      `yaml:"types"`
+	GraceWarning time.Duration `yaml:"grace-warning"`
+
+	notifiersEntry `yaml:",inline"`
+	Digest         *notifiersEntry `yaml:"digest"`
+}
+
+func (e *notifiersEntry) toNotifiers(smtpConfig *smtpEntry) (notifiers []notify.Notifier, err error) {
+	if e.Email != nil {
+		if smtpConfig == nil {
+			err = errortree.Add(err, "email", errors.New("smtp configuration required"))
+		} else {
+			var auth smtp.Auth
+			if smtpConfig.Username != "" {
+				host, _, _ := net.SplitHostPort(smtpConfig.Addr)
+				auth = smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, host)
+			}
+
+			email, emailErr := notify.NewEmailNotifier(smtpConfig.Addr, auth, smtpConfig.From, e.Email.To, e.Email.Subject, e.Email.Body)
+			if emailErr != nil {
+				err = errortree.Add(err, "email", emailErr)
+			} else {
+				notifiers = append(notifiers, email)
+			}
+		}
+	}
+
+	if e.Webhook != nil {
+		notifiers = append(notifiers, notify.NewWebhookNotifier(e.Webhook.URL, e.Webhook.Headers))
+	}
+
+	if len(e.Exec) != 0 {
+		notifiers = append(notifiers, &notify.ExecNotifier{
+			Command: e.Exec[0],
+			Args:    e.Exec[1:],
+		})
+	}
+	return
+}
+
+func (e *notifyMountEntry) toMountConfig(smtpConfig *smtpEntry) (mount *notify.MountConfig, err error) {
+	mount = &notify.MountConfig{
+		Path:         e.Path,
+		GraceWarning: e.GraceWarning,
+	}
+
+	for _, typeName := range e.Types {
+		t, parseErr := parseQuotaType(typeName)
+		if parseErr != nil {
+			err = errortree.Add(err, "types", parseErr)
+			break
+		}
+		mount.Types = append(mount.Types, t)
+	}
+
+	var notifyErr error
+	if mount.Notifiers, notifyErr = e.notifiersEntry.toNotifiers(smtpConfig); notifyErr != nil {
+		err = errortree.Add(err, "notifiers", notifyErr)
+	}
+
+	if e.Digest != nil {
+		if mount.DigestNotifiers, notifyErr = e.Digest.toNotifiers(smtpConfig); notifyErr != nil {
+			err = errortree.Add(err, "digest", notifyErr)
+		}
+	}
+	return
+}
+
+// loadNotifyConfig reads the notification configuration, returning the mount configurations and repeat interval
+func loadNotifyConfig(fileName string) (mounts []*notify.MountConfig, repeat time.Duration, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(fileName); err != nil {
+		return
+	}
+
+	document := &notifyDocument{}
+	if err = yaml.UnmarshalStrict(data, document); err != nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range document.Mounts {
+		if seen[entry.Path] {
+			err = errortree.Add(err, entry.Path, errors.New("configured more than once"))
+			continue
+		}
+		seen[entry.Path] = true
+
+		mount, mountErr := entry.toMountConfig(document.SMTP)
+		if mountErr != nil {
+			err = errortree.Add(err, entry.Path, mountErr)
+			continue
+		}
+		mounts = append(mounts, mount)
+	}
+
+	repeat = document.Repeat
+	return
+}
+
+func checkAndSave(checker *notify.Checker, state *notify.State, stateFile string) (err error) {
+	err = checker.Check()
+	if saveErr := state.Save(stateFile); saveErr != nil {
+		err = errortree.Add(err, "state", saveErr)
+	}
+	return
+}
+
+var cmdNotify = &cobra.Command{
+	Use:   "notify",
+	Short: "Notifies users and administrators about exceeded soft limits and expiring grace periods",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 0 {
+			err = errors.New("no arguments expected")
+			return
+		}
+
+		configFile, _ := cmd.Flags().GetString("config")
+		var mounts []*notify.MountConfig
+		var repeat time.Duration
+		if mounts, repeat, err = loadNotifyConfig(configFile); err != nil {
+			return
+		}
+
+		stateFile, _ := cmd.Flags().GetString("state")
+		var state *notify.State
+		if state, err = notify.LoadState(stateFile); err != nil {
+			return
+		}
+
+		checker := notify.NewChecker(mounts, state, repeat)
+
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval <= 0 {
+			return checkAndSave(checker, state, stateFile)
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// Failures are reported but do not stop periodic checks
+			if checkErr := checkAndSave(checker, state, stateFile); checkErr != nil {
+				cmd.Println(checkErr)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-signals:
+				return
+			}
+		}
+	},
+}
+
+func init() {
+	cmdNotify.Flags().String("config", defaultNotifyConfigFile, "Notification configuration file")
+	cmdNotify.Flags().String("state", notify.DefaultStateFile, "File the notifications sent are tracked in")
+	cmdNotify.Flags().Duration("interval", 0, "Check repeatedly at the given interval instead of once")
+	cmdRoot.AddCommand(cmdNotify)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats selected via the output flag
+const (
+	outputHuman          = "human"
+	outputJSON           = "json"
+	outputYAML           = "yaml"
+	outputCSV            = "csv"
+	outputTable          = "table"
+	outputTemplatePrefix = "template="
+)
+
+// quotaRecord is the schema of quotas printed in the json, yaml, csv and table formats, templates are executed
+// with it as well. Limits and usage are counted in bytes and files. Percentages relate usage to the soft and hard
+// limits and are omitted if the limit is not set. Grace states are none, active or expired.
+type quotaRecord struct {
+	Path string            `json:"path" yaml:"path"`
+	Type fsquota.QuotaType `json:"type" yaml:"type"`
+	ID   string            `json:"id" yaml:"id"`
+	Name string            `json:"name,omitempty" yaml:"name,omitempty"`
+
+	BytesSoft         uint64             `json:"bytes_soft" yaml:"bytes_soft"`
+	BytesHard         uint64             `json:"bytes_hard" yaml:"bytes_hard"`
+	BytesUsed         uint64             `json:"bytes_used" yaml:"bytes_used"`
+	BytesSoftPercent  *float64           `json:"bytes_soft_percent,omitempty" yaml:"bytes_soft_percent,omitempty"`
+	BytesHardPercent  *float64           `json:"bytes_hard_percent,omitempty" yaml:"bytes_hard_percent,omitempty"`
+	BytesGrace        fsquota.GraceState `json:"bytes_grace" yaml:"bytes_grace"`
+	BytesGraceExpires *time.Time         `json:"bytes_grace_expires,omitempty" yaml:"bytes_grace_expires,omitempty"`
+
+	FilesSoft         uint64             `json:"files_soft" yaml:"files_soft"`
+	FilesHard         uint64             `json:"files_hard" yaml:"files_hard"`
+	FilesUsed         uint64             `json:"files_used" yaml:"files_used"`
+	FilesSoftPercent  *float64           `json:"files_soft_percent,omitempty" yaml:"files_soft_percent,omitempty"`
+	FilesHardPercent  *float64           `json:"files_hard_percent,omitempty" yaml:"files_hard_percent,omitempty"`
+	FilesGrace        fsquota.GraceState `json:"files_grace" yaml:"files_grace"`
+	FilesGraceExpires *time.Time         `json:"files_grace_expires,omitempty" yaml:"files_grace_expires,omitempty"`
+
+	InheritsDefaultLimits bool `json:"inherits_default_limits" yaml:"inherits_default_limits"`
+}
+
+// usagePercent returns used as percentage of limit rounded to two decimals, nil if the limit is not set
+func usagePercent(used, limit uint64) *float64 {
+	if limit == 0 {
+		return nil
+	}
+	percent := math.Round(fsquota.UsagePercent(used, limit)*100) / 100
+	return &percent
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func newQuotaRecord(path string, t fsquota.QuotaType, id, name string, info *fsquota.Info, now time.Time) *quotaRecord {
+	return &quotaRecord{
+		Path: path,
+		Type: t,
+		ID:   id,
+		Name: name,
+
+		BytesSoft:         info.Bytes.GetSoft(),
+		BytesHard:         info.Bytes.GetHard(),
+		BytesUsed:         info.BytesUsed,
+		BytesSoftPercent:  usagePercent(info.BytesUsed, info.Bytes.GetSoft()),
+		BytesHardPercent:  usagePercent(info.BytesUsed, info.Bytes.GetHard()),
+		BytesGrace:        info.BytesGraceState(now),
+		BytesGraceExpires: optionalTime(info.BytesGraceExpires),
+
+		FilesSoft:         info.Files.GetSoft(),
+		FilesHard:         info.Files.GetHard(),
+		FilesUsed:         info.FilesUsed,
+		FilesSoftPercent:  usagePercent(info.FilesUsed, info.Files.GetSoft()),
+		FilesHardPercent:  usagePercent(info.FilesUsed, info.Files.GetHard()),
+		FilesGrace:        info.FilesGraceState(now),
+		FilesGraceExpires: optionalTime(info.FilesGraceExpires),
+
+		InheritsDefaultLimits: info.InheritsDefaultLimits,
+	}
+}
+
+// resolvedName returns the name of an ID, empty if lookupFn could not resolve it
+func resolvedName(lookupFn func(string) string, id string) string {
+	if name := lookupFn(id); name != id {
+		return name
+	}
+	return ""
+}
+
+// humanNumbers reports whether byte and file counts are humanized. Unless selected via the human or raw
+// flags, fallback applies.
+func humanNumbers(cmd *cobra.Command, fallback bool) bool {
+	if cmd.Flags().Changed("human") {
+		human, _ := cmd.Flags().GetBool("human")
+		return human
+	}
+
+	if cmd.Flags().Changed("raw") {
+		return false
+	}
+	return fallback
+}
+
+// numberFormats returns the functions formatting byte and file counts
+func numberFormats(human bool) (bytesFn, filesFn func(uint64) string) {
+	if !human {
+		formatFn := func(v uint64) string {
+			return strconv.FormatUint(v, 10)
+		}
+		return formatFn, formatFn
+	}
+	return humanize.IBytes, humanizeInodes
+}
+
+func formatPercent(percent *float64) string {
+	if percent == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*percent, 'f', -1, 64) + "%"
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// quotaPrinter prints quotas in the format selected via the output flags
+type quotaPrinter struct {
+	cmd      *cobra.Command
+	format   string
+	template *template.Template
+	bytesFn  func(uint64) string
+	filesFn  func(uint64) string
+	now      time.Time
+}
+
+// newQuotaPrinter validates the output flags, so commands changing quotas fail before applying anything
+func newQuotaPrinter(cmd *cobra.Command) (p *quotaPrinter, err error) {
+	if cmd.Flags().Changed("human") && cmd.Flags().Changed("raw") {
+		err = errors.New("raw and human are mutually exclusive")
+		return
+	}
+
+	p = &quotaPrinter{
+		cmd:    cmd,
+		format: outputHuman,
+		now:    time.Now(),
+	}
+	if format, _ := cmd.Flags().GetString("output"); format != "" {
+		p.format = format
+	}
+
+	// Only formats meant to be read by humans default to humanized numbers
+	humanDefault := false
+	switch {
+	case p.format == outputHuman || p.format == outputTable:
+		humanDefault = true
+	case p.format == outputJSON || p.format == outputYAML || p.format == outputCSV:
+	case strings.HasPrefix(p.format, outputTemplatePrefix):
+		p.template, err = template.New("output").Funcs(template.FuncMap{
+			"bytes":   func(v uint64) string { return p.bytesFn(v) },
+			"files":   func(v uint64) string { return p.filesFn(v) },
+			"percent": formatPercent,
+		}).Parse(strings.TrimPrefix(p.format, outputTemplatePrefix))
+		if err != nil {
+			p = nil
+			return
+		}
+		p.format = outputTemplatePrefix
+	default:
+		p = nil
+		err = errors.New("output must be one of human, json, yaml, csv, table or template=<go template>")
+		return
+	}
+
+	p.bytesFn, p.filesFn = numberFormats(humanNumbers(cmd, humanDefault))
+	return
+}
+
+// printQuota prints the quota of a single ID
+func (p *quotaPrinter) printQuota(path string, t fsquota.QuotaType, id, name string, info *fsquota.Info) error {
+	if p.format == outputHuman {
+		printInfo(p.cmd, info, "")
+		return nil
+	}
+	return p.printRecords([]*quotaRecord{newQuotaRecord(path, t, id, name, info, p.now)}, true)
+}
+
+// printEntries prints the entries of a report, names are omitted if numeric is set
+func (p *quotaPrinter) printEntries(path string, t fsquota.QuotaType, entries []*fsquota.ReportEntry, numeric bool) error {
+	records := make([]*quotaRecord, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name
+		if numeric {
+			name = ""
+		}
+
+		if p.format == outputHuman {
+			if name == "" {
+				name = entry.ID
+			}
+			fmt.Fprintf(p.cmd.OutOrStdout(), "%s %s:\n", t, name)
+			printInfo(p.cmd, entry.Info, "  ")
+			continue
+		}
+		records = append(records, newQuotaRecord(path, t, entry.ID, name, entry.Info, p.now))
+	}
+
+	if p.format == outputHuman {
+		return nil
+	}
+	return p.printRecords(records, false)
+}
+
+// printRecords prints records, single quotas are printed as a document instead of a list in json and yaml
+func (p *quotaPrinter) printRecords(records []*quotaRecord, single bool) (err error) {
+	var document interface{} = records
+	if single {
+		document = records[0]
+	}
+
+	switch p.format {
+	case outputJSON:
+		encoder := json.NewEncoder(p.cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document)
+	case outputYAML:
+		var data []byte
+		if data, err = yaml.Marshal(document); err != nil {
+			return
+		}
+		_, err = p.cmd.OutOrStdout().Write(data)
+		return
+	case outputCSV:
+		return p.printCSV(records)
+	case outputTable:
+		return p.printTable(records)
+	}
+
+	for _, record := range records {
+		if err = p.template.Execute(p.cmd.OutOrStdout(), record); err != nil {
+			return
+		}
+		fmt.Fprintln(p.cmd.OutOrStdout())
+	}
+	return
+}
+
+func (p *quotaPrinter) printCSV(records []*quotaRecord) (err error) {
+	w := csv.NewWriter(p.cmd.OutOrStdout())
+	w.Write([]string{
+		"path", "type", "id", "name",
+		"bytes_soft", "bytes_hard", "bytes_used", "bytes_soft_percent", "bytes_hard_percent", "bytes_grace", "bytes_grace_expires",
+		"files_soft", "files_hard", "files_used", "files_soft_percent", "files_hard_percent", "files_grace", "files_grace_expires",
+		"inherits_default_limits",
+	})
+
+	csvPercent := func(percent *float64) string {
+		if percent == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*percent, 'f', -1, 64)
+	}
+
+	for _, r := range records {
+		w.Write([]string{
+			r.Path, r.Type.String(), r.ID, r.Name,
+			p.bytesFn(r.BytesSoft), p.bytesFn(r.BytesHard), p.bytesFn(r.BytesUsed),
+			csvPercent(r.BytesSoftPercent), csvPercent(r.BytesHardPercent), string(r.BytesGrace), formatOptionalTime(r.BytesGraceExpires),
+			p.filesFn(r.FilesSoft), p.filesFn(r.FilesHard), p.filesFn(r.FilesUsed),
+			csvPercent(r.FilesSoftPercent), csvPercent(r.FilesHardPercent), string(r.FilesGrace), formatOptionalTime(r.FilesGraceExpires),
+			strconv.FormatBool(r.InheritsDefaultLimits),
+		})
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func (p *quotaPrinter) printTable(records []*quotaRecord) (err error) {
+	w := tabwriter.NewWriter(p.cmd.OutOrStdout(), 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tID\tNAME\tBYTES USED\tBYTES SOFT\tBYTES HARD\tBYTES %SOFT\tBYTES GRACE\tFILES USED\tFILES SOFT\tFILES HARD\tFILES %SOFT\tFILES GRACE")
+
+	for _, r := range records {
+		name := r.Name
+		if name == "" {
+			name = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Type, r.ID, name,
+			p.bytesFn(r.BytesUsed), p.bytesFn(r.BytesSoft), p.bytesFn(r.BytesHard), formatPercent(r.BytesSoftPercent), r.BytesGrace,
+			p.filesFn(r.FilesUsed), p.filesFn(r.FilesSoft), p.filesFn(r.FilesHard), formatPercent(r.FilesSoftPercent), r.FilesGrace)
+	}
+
+	return w.Flush()
+}
+
+// addOutputFlags registers the flags selecting the output format
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", outputHuman, "Output format, one of human, json, yaml, csv, table or template=<go template>")
+	cmd.Flags().Bool("human", false, "Print humanized byte and file counts, the default for the human and table formats")
+	cmd.Flags().Bool("raw", false, "Print raw byte and file counts, the default for the json, yaml, csv and template formats")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// plansDocument is the plans and rules document read by the plans commands
+type plansDocument struct {
+	Plans map[string]map[string]*plansLimits `yaml:"plans"`
+	Rules []*plansRule                       `yaml:"rules"`
+}
+
+type plansLimits struct {
+	Bytes string `yaml:"bytes"`
+	Files string `yaml:"files"`
+}
+
+type plansRule struct {
+	Plan  string `yaml:"plan"`
+	Group string `yaml:"group"`
+	UIDs  string `yaml:"uids"`
+	GIDs  string `yaml:"gids"`
+	Name  string `yaml:"name"`
+}
+
+// parseIDRange parses ranges in min-max format, or a single ID
+func parseIDRange(s string) (r *fsquota.IDRange, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+
+	var min, max uint64
+	if min, err = strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 32); err != nil {
+		return
+	}
+	if max, err = strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 32); err != nil {
+		return
+	}
+
+	r = &fsquota.IDRange{
+		Min: uint32(min),
+		Max: uint32(max),
+	}
+	return
+}
+
+// toLimits resolves the limits of a plan on the filesystem at path
+func (l *plansLimits) toLimits(path string) (limits *fsquota.Limits, err error) {
+	limits = &fsquota.Limits{}
+	if err = resolveDeclaredLimits(path, l.Bytes, l.Files, limits); err != nil {
+		limits = nil
+	}
+	return
+}
+
+func (r *plansRule) toAssignmentRule() (rule *fsquota.AssignmentRule, err error) {
+	rule = &fsquota.AssignmentRule{
+		Plan:        r.Plan,
+		Group:       r.Group,
+		NamePattern: r.Name,
+	}
+
+	var parseErr error
+	if r.UIDs != "" {
+		if rule.UIDRange, parseErr = parseIDRange(r.UIDs); parseErr != nil {
+			err = errortree.Add(err, "uids", parseErr)
+		}
+	}
+
+	if r.GIDs != "" {
+		if rule.GIDRange, parseErr = parseIDRange(r.GIDs); parseErr != nil {
+			err = errortree.Add(err, "gids", parseErr)
+		}
+	}
+
+	return
+}
+
+func readPlansFile(cmd *cobra.Command) (plans []*fsquota.QuotaPlan, rules []*fsquota.AssignmentRule, err error) {
+	fileName, _ := cmd.Flags().GetString("file")
+	if fileName == "" {
+		err = errors.New("file is required")
+		return
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(fileName); err != nil {
+		return
+	}
+
+	document := &plansDocument{}
+	if err = yaml.UnmarshalStrict(data, document); err != nil {
+		return
+	}
+
+	names := make([]string, 0, len(document.Plans))
+	for name := range document.Plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		plan := &fsquota.QuotaPlan{
+			Name:   name,
+			Limits: make(map[string]*fsquota.Limits),
+		}
+
+		for path, l := range document.Plans[name] {
+			var limitsErr error
+			if plan.Limits[path], limitsErr = l.toLimits(path); limitsErr != nil {
+				err = errortree.Add(err, "plans", errortree.Add(nil, name, errortree.Add(nil, path, limitsErr)))
+			}
+		}
+		plans = append(plans, plan)
+	}
+
+	for i, r := range document.Rules {
+		rule, ruleErr := r.toAssignmentRule()
+		if ruleErr != nil {
+			err = errortree.Add(err, "rules", errortree.Add(nil, strconv.Itoa(i), ruleErr))
+		}
+		rules = append(rules, rule)
+	}
+
+	return
+}
+
+func printPlanAssignments(cmd *cobra.Command, assignments []*fsquota.PlanAssignment) {
+	for _, assignment := range assignments {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s (%s): %s\n", assignment.Username, assignment.UID, assignment.Plan)
+	}
+}
+
+var cmdPlans = &cobra.Command{
+	Use:   "plans",
+	Short: "Quota plan management",
+}
+
+func init() {
+	cmdPlans.PersistentFlags().StringP("file", "f", "", "YAML document declaring plans and assignment rules")
+	cmdRoot.AddCommand(cmdPlans)
+}
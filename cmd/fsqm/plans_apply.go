@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdPlansApply = &cobra.Command{
+	Use:   "apply -f file",
+	Short: "Applies the limits of the assigned plan to every user",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 0 {
+			err = errors.New("no arguments expected")
+			return
+		}
+
+		var plans []*fsquota.QuotaPlan
+		var rules []*fsquota.AssignmentRule
+		if plans, rules, err = readPlansFile(cmd); err != nil {
+			return
+		}
+
+		// Users which could not be looked up are reported after the assignments of all other users
+		var assignments []*fsquota.PlanAssignment
+		if assignments, err = fsquota.ApplyQuotaPlans(plans, rules, setOptions(cmd)...); err != nil {
+			if _, unresolved := err.(*fsquota.UnresolvedUsersError); !unresolved {
+				return
+			}
+		}
+
+		printPlanAssignments(cmd, assignments)
+		return
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdPlansApply)
+	cmdPlans.AddCommand(cmdPlansApply)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultPolicyFile = "/etc/fsqm/policy.yaml"
+
+// policyDocument is the quota policy file format
+type policyDocument struct {
+	MaxFilesystemFraction *float64                `yaml:"max-filesystem-fraction"`
+	Protected             map[string][]string     `yaml:"protected"`
+	Bounds                map[string]*boundsEntry `yaml:"bounds"`
+}
+
+type boundsEntry struct {
+	MinBytes string `yaml:"min-bytes"`
+	MaxBytes string `yaml:"max-bytes"`
+	MinFiles string `yaml:"min-files"`
+	MaxFiles string `yaml:"max-files"`
+}
+
+func parseBound(s string) (value uint64, err error) {
+	if s == "" {
+		return
+	}
+	return humanize.ParseBytes(s)
+}
+
+func (e *boundsEntry) toLimitBounds() (bounds *fsquota.LimitBounds, err error) {
+	bounds = &fsquota.LimitBounds{}
+
+	var parseErr error
+	if bounds.MinBytes, parseErr = parseBound(e.MinBytes); parseErr != nil {
+		err = errortree.Add(err, "min-bytes", parseErr)
+	}
+	if bounds.MaxBytes, parseErr = parseBound(e.MaxBytes); parseErr != nil {
+		err = errortree.Add(err, "max-bytes", parseErr)
+	}
+	if bounds.MinFiles, parseErr = parseBound(e.MinFiles); parseErr != nil {
+		err = errortree.Add(err, "min-files", parseErr)
+	}
+	if bounds.MaxFiles, parseErr = parseBound(e.MaxFiles); parseErr != nil {
+		err = errortree.Add(err, "max-files", parseErr)
+	}
+
+	return
+}
+
+// toPolicy converts the document to a policy, settings not present are taken from the default policy
+func (d *policyDocument) toPolicy() (policy *fsquota.Policy, err error) {
+	policy = fsquota.DefaultPolicy()
+
+	if d.MaxFilesystemFraction != nil {
+		policy.MaxFilesystemFraction = *d.MaxFilesystemFraction
+	}
+
+	if d.Protected != nil {
+		policy.ProtectedIDs = make(map[fsquota.QuotaType][]fsquota.IDRange)
+		for typeName, ranges := range d.Protected {
+			t, parseErr := parseQuotaType(typeName)
+			if parseErr != nil {
+				err = errortree.Add(err, "protected", parseErr)
+				continue
+			}
+
+			for i, rangeString := range ranges {
+				r, rangeErr := parseIDRange(rangeString)
+				if rangeErr != nil {
+					err = errortree.Add(err, "protected", errortree.Add(nil, typeName, errortree.Add(nil, strconv.Itoa(i), rangeErr)))
+					continue
+				}
+				policy.ProtectedIDs[t] = append(policy.ProtectedIDs[t], *r)
+			}
+		}
+	}
+
+	if d.Bounds != nil {
+		policy.Bounds = make(map[fsquota.QuotaType]*fsquota.LimitBounds)
+		for typeName, entry := range d.Bounds {
+			t, parseErr := parseQuotaType(typeName)
+			if parseErr != nil {
+				err = errortree.Add(err, "bounds", parseErr)
+				continue
+			}
+
+			if policy.Bounds[t], parseErr = entry.toLimitBounds(); parseErr != nil {
+				err = errortree.Add(err, "bounds", errortree.Add(nil, typeName, parseErr))
+			}
+		}
+	}
+
+	return
+}
+
+// changesQuotasAnnotation marks commands changing quotas, the policy and audit sinks are only loaded for them
+const changesQuotasAnnotation = "fsqm/changes-quotas"
+
+// markChangesQuotas marks cmd as changing quotas
+func markChangesQuotas(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[changesQuotasAnnotation] = "true"
+}
+
+func changesQuotas(cmd *cobra.Command) bool {
+	_, ok := cmd.Annotations[changesQuotasAnnotation]
+	return ok
+}
+
+// loadPolicy installs the policy configured via the policy flag. Without a policy file no policy is installed.
+func loadPolicy(cmd *cobra.Command) (err error) {
+	fileName, _ := cmd.Flags().GetString("policy")
+
+	var data []byte
+	if data, err = ioutil.ReadFile(fileName); os.IsNotExist(err) {
+		fsquota.SetPolicy(nil)
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	document := &policyDocument{}
+	if err = yaml.UnmarshalStrict(data, document); err != nil {
+		return
+	}
+
+	var policy *fsquota.Policy
+	if policy, err = document.toPolicy(); err != nil {
+		return
+	}
+
+	fsquota.SetPolicy(policy)
+	return
+}
+
+// setOptions returns the options for calls changing quotas
+func setOptions(cmd *cobra.Command) (opts []fsquota.SetOption) {
+	if force, _ := cmd.Flags().GetBool("force"); force {
+		opts = append(opts, fsquota.OverridePolicy())
+	}
+
+	if isDryRun(cmd) {
+		opts = append(opts, fsquota.DryRun(func(preview *fsquota.ChangePreview) {
+			printChangePreview(cmd, preview)
+		}))
+	}
+	return
+}
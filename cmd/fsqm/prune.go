@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+)
+
+var cmdPrune = &cobra.Command{
+	Use:   "prune path",
+	Short: "Clears quotas of users and groups which no longer exist",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		pruneUsers, _ := cmd.Flags().GetBool("users")
+		pruneGroups, _ := cmd.Flags().GetBool("groups")
+		if !pruneUsers && !pruneGroups {
+			// Prune both if no type has been selected explicitly
+			pruneUsers, pruneGroups = true, true
+		}
+
+		if pruneUsers {
+			pruned, pruneErr := fsquota.PruneUserQuotas(args[0], setOptions(cmd)...)
+			for _, uid := range pruned {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s user %s\n", appliedVerb(cmd, "prune", "pruned"), uid)
+			}
+			if pruneErr != nil {
+				err = errortree.Add(err, "users", pruneErr)
+			}
+		}
+
+		if pruneGroups {
+			pruned, pruneErr := fsquota.PruneGroupQuotas(args[0], setOptions(cmd)...)
+			for _, gid := range pruned {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s group %s\n", appliedVerb(cmd, "prune", "pruned"), gid)
+			}
+			if pruneErr != nil {
+				err = errortree.Add(err, "groups", pruneErr)
+			}
+		}
+
+		return
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdPrune)
+	cmdPrune.Flags().BoolP("users", "u", false, "Prune user quotas")
+	cmdPrune.Flags().BoolP("groups", "g", false, "Prune group quotas")
+	cmdRoot.AddCommand(cmdPrune)
+}
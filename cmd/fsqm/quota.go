@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+// hasLimitsOrUsage reports whether info is worth showing
+func hasLimitsOrUsage(info *fsquota.Info) bool {
+	return info.Bytes.GetSoft() != 0 || info.Bytes.GetHard() != 0 || info.BytesUsed != 0 ||
+		info.Files.GetSoft() != 0 || info.Files.GetHard() != 0 || info.FilesUsed != 0
+}
+
+func printMountQuotaInfos(cmd *cobra.Command, header string, infos []*fsquota.MountQuotaInfo) {
+	printedHeader := false
+	for _, mountInfo := range infos {
+		if !hasLimitsOrUsage(mountInfo.Info) {
+			continue
+		}
+
+		if !printedHeader {
+			fmt.Fprintln(cmd.OutOrStdout(), header+":")
+			printedHeader = true
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "  %s:\n", mountInfo.MountPoint)
+		printInfo(cmd, mountInfo.Info, "    ")
+	}
+}
+
+var cmdQuota = &cobra.Command{
+	Use:   "quota [user]",
+	Short: "Shows the quotas of a user and its groups on all filesystems, defaulting to the current user",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) > 1 {
+			err = errors.New("at most one argument allowed")
+			return
+		}
+
+		var u *user.User
+		if len(args) == 1 {
+			u, err = lookupUser(args[0])
+		} else {
+			u, err = user.Current()
+		}
+		if err != nil {
+			return
+		}
+
+		var infos []*fsquota.MountQuotaInfo
+		if infos, err = fsquota.GetUserInfoAllMounts(u); err != nil {
+			return
+		}
+		printMountQuotaInfos(cmd, "user "+lookupUsernameByUid(u.Uid), infos)
+
+		// Users given by numeric ID may not exist, in which case their groups are unknown
+		gids, groupsErr := u.GroupIds()
+		if groupsErr != nil {
+			gids = nil
+		}
+		if u.Gid != "" {
+			gids = append([]string{u.Gid}, gids...)
+		}
+
+		seen := make(map[string]bool)
+		for _, gid := range gids {
+			if seen[gid] {
+				continue
+			}
+			seen[gid] = true
+
+			if infos, err = fsquota.GetGroupInfoAllMounts(&user.Group{Gid: gid}); err != nil {
+				return
+			}
+			printMountQuotaInfos(cmd, "group "+lookupGroupnameByGid(gid), infos)
+		}
+
+		return
+	},
+}
+
+func init() {
+	cmdRoot.AddCommand(cmdQuota)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// reconcileDocument is the desired state document read by the reconcile command
+type reconcileDocument struct {
+	Mounts []*reconcileMount `yaml:"mounts"`
+}
+
+type reconcileMount struct {
+	Path     string            `yaml:"path"`
+	Users    []*reconcileEntry `yaml:"users"`
+	Groups   []*reconcileEntry `yaml:"groups"`
+	Projects []*reconcileEntry `yaml:"projects"`
+}
+
+type reconcileEntry struct {
+	ID      string `yaml:"id"`
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+	Bytes   string `yaml:"bytes"`
+	Files   string `yaml:"files"`
+}
+
+func (e *reconcileEntry) toDesiredQuota(path string) (desired *fsquota.DesiredQuota, err error) {
+	desired = &fsquota.DesiredQuota{
+		ID:      e.ID,
+		Name:    e.Name,
+		Pattern: e.Pattern,
+	}
+
+	if err = resolveDeclaredLimits(path, e.Bytes, e.Files, &desired.Limits); err != nil {
+		desired = nil
+	}
+	return
+}
+
+func toDesiredQuotas(path string, entries []*reconcileEntry) (desired []*fsquota.DesiredQuota, err error) {
+	desired = make([]*fsquota.DesiredQuota, len(entries))
+	for i, entry := range entries {
+		var entryErr error
+		if desired[i], entryErr = entry.toDesiredQuota(path); entryErr != nil {
+			err = errortree.Add(err, strconv.Itoa(i), entryErr)
+		}
+	}
+	return
+}
+
+func (m *reconcileMount) toDesiredState() (state *fsquota.DesiredState, err error) {
+	state = &fsquota.DesiredState{
+		Path: m.Path,
+	}
+
+	var convErr error
+	if state.Users, convErr = toDesiredQuotas(m.Path, m.Users); convErr != nil {
+		err = errortree.Add(err, "users", convErr)
+	}
+
+	if state.Groups, convErr = toDesiredQuotas(m.Path, m.Groups); convErr != nil {
+		err = errortree.Add(err, "groups", convErr)
+	}
+
+	if state.Projects, convErr = toDesiredQuotas(m.Path, m.Projects); convErr != nil {
+		err = errortree.Add(err, "projects", convErr)
+	}
+
+	return
+}
+
+func formatLimits(limits *fsquota.Limits) string {
+	return fmt.Sprintf("bytes %s,%s files %s,%s",
+		humanize.IBytes(limits.Bytes.GetSoft()), humanize.IBytes(limits.Bytes.GetHard()),
+		humanizeInodes(limits.Files.GetSoft()), humanizeInodes(limits.Files.GetHard()))
+}
+
+func printReconcilePlan(cmd *cobra.Command, plan *fsquota.ReconcilePlan) {
+	fmt.Fprintf(cmd.OutOrStdout(), "%s:\n", plan.Path)
+	if len(plan.Steps) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "  nothing to do")
+		return
+	}
+
+	for _, step := range plan.Steps {
+		name := quotaTypeLookupFn(step.Type, false)(step.ID)
+		switch step.Action {
+		case fsquota.ReconcileAdd:
+			fmt.Fprintf(cmd.OutOrStdout(), "  + %s %s: %s\n", step.Type, name, formatLimits(&step.Limits))
+		case fsquota.ReconcileChange:
+			fmt.Fprintf(cmd.OutOrStdout(), "  ~ %s %s: %s -> %s\n", step.Type, name, formatLimits(&step.Current.Limits), formatLimits(&step.Limits))
+		case fsquota.ReconcileRemove:
+			fmt.Fprintf(cmd.OutOrStdout(), "  - %s %s: %s\n", step.Type, name, formatLimits(&step.Current.Limits))
+		}
+	}
+}
+
+var cmdReconcile = &cobra.Command{
+	Use:   "reconcile -f file",
+	Short: "Brings quotas to the state declared in a YAML document",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 0 {
+			err = errors.New("no arguments expected")
+			return
+		}
+
+		fileName, _ := cmd.Flags().GetString("file")
+		if fileName == "" {
+			err = errors.New("file is required")
+			return
+		}
+
+		var data []byte
+		if data, err = ioutil.ReadFile(fileName); err != nil {
+			return
+		}
+
+		document := &reconcileDocument{}
+		if err = yaml.UnmarshalStrict(data, document); err != nil {
+			return
+		}
+
+		states := make([]*fsquota.DesiredState, len(document.Mounts))
+		for i, mount := range document.Mounts {
+			var stateErr error
+			if states[i], stateErr = mount.toDesiredState(); stateErr != nil {
+				err = errortree.Add(err, mount.Path, stateErr)
+			}
+		}
+
+		if err != nil {
+			return
+		}
+
+		prune, _ := cmd.Flags().GetBool("prune")
+		planOnly, _ := cmd.Flags().GetBool("plan")
+
+		// Plan all mounts before applying anything
+		plans := make([]*fsquota.ReconcilePlan, len(states))
+		for i, state := range states {
+			var planErr error
+			if plans[i], planErr = fsquota.PlanReconcile(state, prune, setOptions(cmd)...); planErr != nil {
+				err = errortree.Add(err, state.Path, planErr)
+				continue
+			}
+			printReconcilePlan(cmd, plans[i])
+		}
+
+		if err != nil || planOnly {
+			return
+		}
+
+		for _, plan := range plans {
+			if len(plan.Steps) == 0 {
+				continue
+			}
+
+			if applyErr := fsquota.ApplyReconcilePlan(plan, setOptions(cmd)...); applyErr != nil {
+				err = errortree.Add(err, plan.Path, applyErr)
+				continue
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s %d changes\n", plan.Path, appliedVerb(cmd, "apply", "applied"), len(plan.Steps))
+		}
+
+		return
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdReconcile)
+	cmdReconcile.Flags().StringP("file", "f", "", "YAML document declaring the desired quotas")
+	cmdReconcile.Flags().Bool("prune", false, "Remove limits of IDs not declared in the document")
+	cmdReconcile.Flags().Bool("plan", false, "Only print the plan without applying it")
+	cmdRoot.AddCommand(cmdReconcile)
+}
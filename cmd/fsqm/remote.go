@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/anexia-it/fsquota/api"
+	"github.com/spf13/cobra"
+)
+
+// isRemote reports whether commands target a remote server instead of the local quotas
+func isRemote(cmd *cobra.Command) bool {
+	server, _ := cmd.Flags().GetString("server")
+	return server != ""
+}
+
+// remoteClient creates a client for the server configured via the server flags
+func remoteClient(cmd *cobra.Command) (client *api.Client, err error) {
+	server, _ := cmd.Flags().GetString("server")
+	token, _ := cmd.Flags().GetString("token")
+	if token == "" {
+		token = os.Getenv("FSQM_TOKEN")
+	}
+
+	tlsConfig := &tls.Config{}
+	if caFile, _ := cmd.Flags().GetString("ca-cert"); caFile != "" {
+		var data []byte
+		if data, err = ioutil.ReadFile(caFile); err != nil {
+			return
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			err = errors.New("no certificates found in " + caFile)
+			return
+		}
+	}
+
+	certFile, _ := cmd.Flags().GetString("cert")
+	keyFile, _ := cmd.Flags().GetString("key")
+	if certFile != "" || keyFile != "" {
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	client = api.NewClient(server, token, tlsConfig)
+	client.DryRun = isDryRun(cmd)
+	return
+}
+
+// remoteSetRequest converts a limit change for the server, which only accepts absolute limits
+func remoteSetRequest(cmd *cobra.Command, change *fsquota.LimitChange) (request *api.SetRequest, err error) {
+	request = &api.SetRequest{}
+	request.Force, _ = cmd.Flags().GetBool("force")
+
+	for _, v := range []struct {
+		value  *fsquota.LimitValue
+		target **uint64
+	}{
+		{change.BytesSoft, &request.BytesSoft},
+		{change.BytesHard, &request.BytesHard},
+		{change.FilesSoft, &request.FilesSoft},
+		{change.FilesHard, &request.FilesHard},
+	} {
+		if v.value == nil {
+			continue
+		}
+		if v.value.Kind != fsquota.LimitAbsolute {
+			request = nil
+			err = errors.New("only absolute limits are supported with a remote server")
+			return
+		}
+		value := v.value.Value
+		*v.target = &value
+	}
+	return
+}
+
+func remoteGet(cmd *cobra.Command, printer *quotaPrinter, path string, t fsquota.QuotaType, id string) (err error) {
+	var client *api.Client
+	if client, err = remoteClient(cmd); err != nil {
+		return
+	}
+
+	var quota *api.Quota
+	if quota, err = client.GetQuota(path, t, id); err != nil {
+		return
+	}
+
+	return printer.printQuota(path, t, quota.ID, quota.Name, quota.Info())
+}
+
+// remoteChange applies a change via changeFn. In dry-run mode, the difference to the current quota is printed as well.
+func remoteChange(cmd *cobra.Command, printer *quotaPrinter, client *api.Client, operation, path string, t fsquota.QuotaType, id string, changeFn func() (*api.Quota, error)) (err error) {
+	var before *api.Quota
+	if client.DryRun {
+		if before, err = client.GetQuota(path, t, id); err != nil {
+			return
+		}
+	}
+
+	var quota *api.Quota
+	if quota, err = changeFn(); err != nil {
+		return
+	}
+
+	if before != nil {
+		printChangePreview(cmd, &fsquota.ChangePreview{
+			Operation: operation,
+			Path:      path,
+			Type:      t,
+			ID:        quota.ID,
+			Before:    before.Info(),
+			After:     quota.Info(),
+		})
+	}
+
+	return printer.printQuota(path, t, quota.ID, quota.Name, quota.Info())
+}
+
+func remoteSet(cmd *cobra.Command, printer *quotaPrinter, path string, t fsquota.QuotaType, id string, change *fsquota.LimitChange) (err error) {
+	var request *api.SetRequest
+	if request, err = remoteSetRequest(cmd, change); err != nil {
+		return
+	}
+
+	var client *api.Client
+	if client, err = remoteClient(cmd); err != nil {
+		return
+	}
+
+	return remoteChange(cmd, printer, client, fsquota.AuditOperationSet, path, t, id, func() (*api.Quota, error) {
+		return client.SetQuota(path, t, id, request)
+	})
+}
+
+func remoteClear(cmd *cobra.Command, printer *quotaPrinter, path string, t fsquota.QuotaType, id string) (err error) {
+	var client *api.Client
+	if client, err = remoteClient(cmd); err != nil {
+		return
+	}
+
+	return remoteChange(cmd, printer, client, fsquota.AuditOperationClear, path, t, id, func() (*api.Quota, error) {
+		return client.ClearQuota(path, t, id)
+	})
+}
+
+func remoteReport(cmd *cobra.Command, printer *quotaPrinter, path string, t fsquota.QuotaType) (err error) {
+	var client *api.Client
+	if client, err = remoteClient(cmd); err != nil {
+		return
+	}
+
+	var remoteReport *api.Report
+	if remoteReport, err = client.GetReport(path, t); err != nil {
+		return
+	}
+
+	// Names are resolved by the server, as IDs may map to different names locally
+	report := &fsquota.Report{Infos: make(map[string]*fsquota.Info)}
+	names := make(map[string]string)
+	for _, quota := range remoteReport.Quotas {
+		report.Infos[quota.ID] = quota.Info()
+		names[quota.ID] = quota.Name
+	}
+
+	var entries []*fsquota.ReportEntry
+	if entries, err = queryReport(cmd, report, func(id string) string {
+		return names[id]
+	}); err != nil {
+		return
+	}
+
+	numeric, _ := cmd.Flags().GetBool("numeric")
+	return printer.printEntries(path, t, entries, numeric)
+}
+
+// addRemoteFlags adds the flags selecting and authenticating with a remote server to cmd.
+// Only commands supporting remote mode get them, so no other command can be mistaken to act on a remote server.
+func addRemoteFlags(cmd *cobra.Command) {
+	cmd.Flags().String("server", "", "Manage the quotas of a remote fsqm server at the given URL, ie. https://storage1:8443")
+	cmd.Flags().String("token", "", "Token to authenticate with at the remote server, defaults to $FSQM_TOKEN")
+	cmd.Flags().String("ca-cert", "", "CA certificate file to verify the remote server with")
+	cmd.Flags().String("cert", "", "Client certificate file to authenticate with at the remote server")
+	cmd.Flags().String("key", "", "Key file of the client certificate")
+}
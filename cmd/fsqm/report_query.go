@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+// reportColumnAliases are the short names of the byte columns
+var reportColumnAliases = map[string]fsquota.ReportColumn{
+	"used":    fsquota.ReportColumnBytesUsed,
+	"soft":    fsquota.ReportColumnBytesSoft,
+	"hard":    fsquota.ReportColumnBytesHard,
+	"percent": fsquota.ReportColumnBytesPercent,
+}
+
+// parseReportQueryFlags parses the flags registered by addReportQueryFlags. nameFn resolves the names
+// matched by the name flag and sorted by the name column.
+func parseReportQueryFlags(cmd *cobra.Command, nameFn func(string) string) (query *fsquota.ReportQuery, err error) {
+	query = &fsquota.ReportQuery{
+		NameFn: nameFn,
+	}
+	query.OverSoft, _ = cmd.Flags().GetBool("over-soft")
+	query.InGrace, _ = cmd.Flags().GetBool("in-grace")
+	query.GraceExpired, _ = cmd.Flags().GetBool("grace-expired")
+	query.AtHard, _ = cmd.Flags().GetBool("at-hard")
+	query.HasLimits, _ = cmd.Flags().GetBool("has-limits")
+	query.HasUsage, _ = cmd.Flags().GetBool("has-usage")
+	query.MinPercent, _ = cmd.Flags().GetFloat64("min-percent")
+	query.NamePattern, _ = cmd.Flags().GetString("name")
+	query.Reverse, _ = cmd.Flags().GetBool("reverse")
+	query.Top, _ = cmd.Flags().GetInt("top")
+
+	sortBy, _ := cmd.Flags().GetString("sort")
+	query.SortBy = fsquota.ReportColumn(sortBy)
+	if column, ok := reportColumnAliases[sortBy]; ok {
+		query.SortBy = column
+	}
+
+	if query.IDRanges, err = parseIDRangesFlag(cmd, "ids"); err != nil {
+		query = nil
+	}
+	return
+}
+
+// queryReport selects the entries of report according to the report flags
+func queryReport(cmd *cobra.Command, report *fsquota.Report, nameFn func(string) string) (entries []*fsquota.ReportEntry, err error) {
+	var query *fsquota.ReportQuery
+	if query, err = parseReportQueryFlags(cmd, nameFn); err != nil {
+		return
+	}
+	return report.Query(query, time.Now())
+}
+
+// addReportQueryFlags registers the flags filtering, sorting and limiting reports
+func addReportQueryFlags(cmd *cobra.Command) {
+	columns := make([]string, len(fsquota.ReportColumns))
+	for i, column := range fsquota.ReportColumns {
+		columns[i] = string(column)
+	}
+
+	cmd.Flags().Bool("over-soft", false, "Only list IDs exceeding a soft limit")
+	cmd.Flags().Bool("in-grace", false, "Only list IDs exceeding a soft limit within the grace period")
+	cmd.Flags().Bool("grace-expired", false, "Only list IDs exceeding a soft limit with an expired grace period")
+	cmd.Flags().Bool("at-hard", false, "Only list IDs which reached a hard limit")
+	cmd.Flags().Bool("has-limits", false, "Only list IDs with limits")
+	cmd.Flags().Bool("has-usage", false, "Only list IDs using any bytes or files")
+	cmd.Flags().Float64("min-percent", 0, "Only list IDs using at least the given percentage of their soft, or if unset, hard limit")
+	cmd.Flags().StringSlice("ids", nil, "Only list IDs within the given ranges, ie. 1000-59999")
+	cmd.Flags().String("name", "", "Only list IDs whose name matches the given regular expression")
+	cmd.Flags().String("sort", string(fsquota.ReportColumnID), "Column to sort by, one of "+strings.Join(columns, ", ")+"; used, soft, hard and percent refer to bytes")
+	cmd.Flags().Bool("reverse", false, "Reverse the sort order")
+	cmd.Flags().Int("top", 0, "Only list the given number of IDs")
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+)
+
+func parseExportsFlag(cmd *cobra.Command) (exports map[string]string, err error) {
+	var exportStrings []string
+	if exportStrings, err = cmd.Flags().GetStringSlice("export"); err != nil {
+		return
+	}
+
+	exports = make(map[string]string, len(exportStrings))
+	for _, exportString := range exportStrings {
+		parts := strings.SplitN(exportString, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			err = errortree.Add(err, exportString, errors.New("expected format is exported-path=local-path"))
+			continue
+		}
+		exports[parts[0]] = parts[1]
+	}
+
+	return
+}
+
+func buildSetQuotaACL(cmd *cobra.Command) (allowFn func(caller *fsquota.RquotaCaller) bool, err error) {
+	if enabled, _ := cmd.Flags().GetBool("setquota"); !enabled {
+		return
+	}
+
+	var uids []uint
+	if uids, err = cmd.Flags().GetUintSlice("setquota-uid"); err != nil {
+		return
+	}
+
+	var networkStrings []string
+	if networkStrings, err = cmd.Flags().GetStringSlice("setquota-network"); err != nil {
+		return
+	}
+
+	var networks []*net.IPNet
+	for _, networkString := range networkStrings {
+		_, network, parseErr := net.ParseCIDR(networkString)
+		if parseErr != nil {
+			err = errortree.Add(err, networkString, parseErr)
+			continue
+		}
+		networks = append(networks, network)
+	}
+
+	if err != nil {
+		return
+	}
+
+	allowFn = func(caller *fsquota.RquotaCaller) bool {
+		if !caller.Authenticated {
+			return false
+		}
+
+		uidAllowed := false
+		for _, uid := range uids {
+			if uint32(uid) == caller.UID {
+				uidAllowed = true
+				break
+			}
+		}
+
+		if !uidAllowed {
+			return false
+		}
+
+		if len(networks) == 0 {
+			return true
+		}
+
+		var ip net.IP
+		switch addr := caller.Addr.(type) {
+		case *net.TCPAddr:
+			ip = addr.IP
+		case *net.UDPAddr:
+			ip = addr.IP
+		}
+
+		for _, network := range networks {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return
+}
+
+var cmdRquotad = &cobra.Command{
+	Use:   "rquotad",
+	Short: "Serves local quotas to NFS clients via the rquota protocol",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 0 {
+			err = errors.New("no arguments expected")
+			return
+		}
+
+		server := &fsquota.RquotaServer{}
+
+		var flagErr error
+		if server.Exports, flagErr = parseExportsFlag(cmd); flagErr != nil {
+			err = errortree.Add(err, "export", flagErr)
+		}
+
+		if server.AllowSetQuota, flagErr = buildSetQuotaACL(cmd); flagErr != nil {
+			err = errortree.Add(err, "setquota", flagErr)
+		}
+
+		if err != nil {
+			return
+		}
+
+		port, _ := cmd.Flags().GetUint16("port")
+		address := fmt.Sprintf(":%d", port)
+
+		var listener net.Listener
+		if listener, err = net.Listen("tcp", address); err != nil {
+			return
+		}
+		defer listener.Close()
+		tcpPort := uint16(listener.Addr().(*net.TCPAddr).Port)
+
+		// Serve UDP on the same port as TCP
+		var packetConn net.PacketConn
+		if packetConn, err = net.ListenPacket("udp", fmt.Sprintf(":%d", tcpPort)); err != nil {
+			return
+		}
+		defer packetConn.Close()
+
+		if noRegister, _ := cmd.Flags().GetBool("no-register"); !noRegister {
+			if regErr := server.RegisterWithPortmapper(tcpPort, tcpPort); regErr != nil {
+				cmd.Printf("not registered with portmapper: %s\n", regErr)
+			} else {
+				defer server.UnregisterFromPortmapper()
+			}
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "serving rquota on port %d\n", tcpPort)
+
+		errs := make(chan error, 2)
+		go func() {
+			errs <- server.ServeTCP(listener)
+		}()
+		go func() {
+			errs <- server.ServeUDP(packetConn)
+		}()
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(signals)
+
+		select {
+		case err = <-errs:
+		case <-signals:
+		}
+
+		return
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdRquotad)
+	cmdRquotad.Flags().Uint16P("port", "p", 0, "Port to listen on for TCP and UDP, 0 picks a free port")
+	cmdRquotad.Flags().StringSliceP("export", "e", nil, "Map an exported path to a local path in exported-path=local-path format")
+	cmdRquotad.Flags().Bool("no-register", false, "Do not register with the portmapper")
+	cmdRquotad.Flags().Bool("setquota", false, "Allow clients to set quotas")
+	cmdRquotad.Flags().UintSlice("setquota-uid", []uint{0}, "UIDs allowed to set quotas")
+	cmdRquotad.Flags().StringSlice("setquota-network", nil, "Networks allowed to set quotas in CIDR notation, defaults to all")
+	cmdRoot.AddCommand(cmdRquotad)
+}
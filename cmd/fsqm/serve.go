@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anexia-it/fsquota/api"
+	"github.com/speijnik/go-errortree"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultServeConfigFile = "/etc/fsqm/serve.yaml"
+
+// serveDocument is the server configuration file format
+type serveDocument struct {
+	Grants []*grantEntry `yaml:"grants"`
+}
+
+type grantEntry struct {
+	Name               string   `yaml:"name"`
+	Token              string   `yaml:"token"`
+	TokenFile          string   `yaml:"token-file"`
+	CertificateSubject string   `yaml:"certificate-subject"`
+	Mounts             []string `yaml:"mounts"`
+	Operations         []string `yaml:"operations"`
+}
+
+func (e *grantEntry) toGrant() (grant *api.Grant, err error) {
+	grant = &api.Grant{
+		Name:               e.Name,
+		Token:              e.Token,
+		CertificateSubject: e.CertificateSubject,
+		Mounts:             e.Mounts,
+	}
+
+	if e.TokenFile != "" {
+		var data []byte
+		if data, err = ioutil.ReadFile(e.TokenFile); err != nil {
+			return
+		}
+		grant.Token = strings.TrimSpace(string(data))
+	}
+
+	if grant.Token == "" && grant.CertificateSubject == "" {
+		err = errors.New("token, token-file or certificate-subject required")
+		return
+	}
+
+	for i, op := range e.Operations {
+		switch api.Operation(op) {
+		case api.OperationGet, api.OperationReport, api.OperationSet, api.OperationClear, api.OperationForce, api.Wildcard:
+			grant.Operations = append(grant.Operations, api.Operation(op))
+		default:
+			err = errortree.Add(err, strconv.Itoa(i), errors.New("unknown operation "+op))
+		}
+	}
+	return
+}
+
+func loadGrants(fileName string) (grants []*api.Grant, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(fileName); err != nil {
+		return
+	}
+
+	document := &serveDocument{}
+	if err = yaml.UnmarshalStrict(data, document); err != nil {
+		return
+	}
+
+	for i, entry := range document.Grants {
+		grant, grantErr := entry.toGrant()
+		if grantErr != nil {
+			err = errortree.Add(err, strconv.Itoa(i), grantErr)
+			continue
+		}
+		grants = append(grants, grant)
+	}
+	return
+}
+
+var cmdServe = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves the quota management API",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 0 {
+			err = errors.New("no arguments expected")
+			return
+		}
+
+		configFile, _ := cmd.Flags().GetString("config")
+		var grants []*api.Grant
+		if grants, err = loadGrants(configFile); err != nil {
+			return
+		}
+
+		server := &http.Server{
+			Handler: api.NewServer(grants),
+		}
+		server.Addr, _ = cmd.Flags().GetString("listen")
+
+		certFile, _ := cmd.Flags().GetString("tls-cert")
+		keyFile, _ := cmd.Flags().GetString("tls-key")
+		if certFile == "" || keyFile == "" {
+			if insecure, _ := cmd.Flags().GetBool("insecure"); !insecure {
+				err = errors.New("tls-cert and tls-key required, use --insecure to serve plain HTTP")
+				return
+			}
+			return server.ListenAndServe()
+		}
+
+		server.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+		if clientCAFile, _ := cmd.Flags().GetString("client-ca"); clientCAFile != "" {
+			var data []byte
+			if data, err = ioutil.ReadFile(clientCAFile); err != nil {
+				return
+			}
+
+			server.TLSConfig.ClientCAs = x509.NewCertPool()
+			if !server.TLSConfig.ClientCAs.AppendCertsFromPEM(data) {
+				err = errors.New("no certificates found in " + clientCAFile)
+				return
+			}
+			// Certificates are optional, so clients may still authenticate using tokens
+			server.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+
+		return server.ListenAndServeTLS(certFile, keyFile)
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdServe)
+	cmdServe.Flags().String("listen", ":8443", "Address to listen on")
+	cmdServe.Flags().String("config", defaultServeConfigFile, "Server configuration file listing the grants")
+	cmdServe.Flags().String("tls-cert", "", "TLS certificate file")
+	cmdServe.Flags().String("tls-key", "", "TLS key file")
+	cmdServe.Flags().String("client-ca", "", "CA certificate file to verify client certificates with")
+	cmdServe.Flags().Bool("insecure", false, "Serve plain HTTP if no TLS certificate is configured")
+	cmdRoot.AddCommand(cmdServe)
+}
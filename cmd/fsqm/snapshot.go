@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var cmdSnapshot = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Quota report snapshots",
+}
+
+func init() {
+	cmdRoot.AddCommand(cmdSnapshot)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+func readSnapshotFile(fileName string) (snapshot *fsquota.Snapshot, err error) {
+	var f *os.File
+	if f, err = os.Open(fileName); err != nil {
+		return
+	}
+	defer f.Close()
+
+	return fsquota.ReadSnapshot(f)
+}
+
+// formatDelta formats a signed difference using formatFn for the absolute value
+func formatDelta(delta int64, formatFn func(uint64) string) string {
+	if delta < 0 {
+		return "-" + formatFn(uint64(-delta))
+	}
+	return "+" + formatFn(uint64(delta))
+}
+
+func formatSnapshotLimits(entry *fsquota.SnapshotEntry) string {
+	return "bytes " + humanize.IBytes(entry.BytesSoft) + "," + humanize.IBytes(entry.BytesHard) +
+		" files " + humanizeInodes(entry.FilesSoft) + "," + humanizeInodes(entry.FilesHard)
+}
+
+var cmdSnapshotDiff = &cobra.Command{
+	Use:   "diff old new",
+	Short: "Shows the changes in usage and limits between two snapshots, largest first",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 2 {
+			err = errors.New("exactly two arguments required")
+			return
+		}
+
+		var before, after *fsquota.Snapshot
+		if before, err = readSnapshotFile(args[0]); err != nil {
+			return
+		}
+		if after, err = readSnapshotFile(args[1]); err != nil {
+			return
+		}
+
+		if before.Host != after.Host || before.Path != after.Path {
+			fmt.Fprintf(cmd.OutOrStdout(), "warning: comparing %s:%s with %s:%s\n", before.Host, before.Path, after.Host, after.Path)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "changes from %s to %s (%s):\n", before.Timestamp.Format(time.RFC3339), after.Timestamp.Format(time.RFC3339), after.Timestamp.Sub(before.Timestamp))
+
+		changes := fsquota.DiffSnapshots(before, after)
+		if top, _ := cmd.Flags().GetInt("top"); top > 0 && len(changes) > top {
+			changes = changes[:top]
+		}
+
+		if len(changes) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "  no changes")
+			return
+		}
+
+		for _, change := range changes {
+			name := quotaTypeLookupFn(change.Type, false)(change.ID)
+
+			switch {
+			case change.Old == nil:
+				fmt.Fprintf(cmd.OutOrStdout(), "  %s %s: new, %s and %s files used, limits %s\n", change.Type, name,
+					humanize.IBytes(change.New.BytesUsed), humanizeInodes(change.New.FilesUsed), formatSnapshotLimits(change.New))
+			case change.New == nil:
+				fmt.Fprintf(cmd.OutOrStdout(), "  %s %s: removed, %s and %s files were used\n", change.Type, name,
+					humanize.IBytes(change.Old.BytesUsed), humanizeInodes(change.Old.FilesUsed))
+			default:
+				fmt.Fprintf(cmd.OutOrStdout(), "  %s %s: %s (%s -> %s), %s files (%s -> %s)\n", change.Type, name,
+					formatDelta(change.BytesUsedDelta, humanize.IBytes), humanize.IBytes(change.Old.BytesUsed), humanize.IBytes(change.New.BytesUsed),
+					formatDelta(change.FilesUsedDelta, humanizeInodes), humanizeInodes(change.Old.FilesUsed), humanizeInodes(change.New.FilesUsed))
+				if change.LimitsChanged {
+					fmt.Fprintf(cmd.OutOrStdout(), "    limits: %s -> %s\n", formatSnapshotLimits(change.Old), formatSnapshotLimits(change.New))
+				}
+			}
+		}
+
+		return
+	},
+}
+
+func init() {
+	cmdSnapshotDiff.Flags().IntP("top", "n", 0, "Only show the given number of largest changes")
+	cmdSnapshot.AddCommand(cmdSnapshotDiff)
+}
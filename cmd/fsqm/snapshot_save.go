@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdSnapshotSave = &cobra.Command{
+	Use:   "save path",
+	Short: "Saves the quota reports of a filesystem to a snapshot file",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		var snapshot *fsquota.Snapshot
+		if snapshot, err = fsquota.TakeSnapshot(args[0]); err != nil {
+			return
+		}
+
+		fileName, _ := cmd.Flags().GetString("output-file")
+		if fileName == "" || fileName == "-" {
+			return fsquota.WriteSnapshot(cmd.OutOrStdout(), snapshot)
+		}
+
+		var f *os.File
+		if f, err = os.Create(fileName); err != nil {
+			return
+		}
+
+		if err = fsquota.WriteSnapshot(f, snapshot); err != nil {
+			f.Close()
+			return
+		}
+		return f.Close()
+	},
+}
+
+func init() {
+	cmdSnapshotSave.Flags().StringP("output-file", "o", "", "File to write the snapshot to, defaults to standard output")
+	cmdSnapshot.AddCommand(cmdSnapshotSave)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdUndo = &cobra.Command{
+	Use:   "undo change-id",
+	Short: "Restores the limits replaced by a change recorded in the audit log",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 1 {
+			err = errors.New("exactly one argument required")
+			return
+		}
+
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
+		}
+
+		var records []*fsquota.AuditRecord
+		if records, err = readAuditLog(cmd); err != nil {
+			return
+		}
+
+		record := fsquota.FindAuditRecord(records, args[0])
+		if record == nil {
+			err = errors.New("change not found in audit log")
+			return
+		}
+
+		opts := setOptions(cmd)
+		if superseded, _ := cmd.Flags().GetBool("superseded"); superseded {
+			opts = append(opts, fsquota.UndoSuperseded())
+		}
+
+		var info *fsquota.Info
+		if info, err = fsquota.UndoChange(record, opts...); err != nil {
+			if err == fsquota.ErrChangeSuperseded {
+				err = errors.New("limits have been changed since, use --superseded to undo the change anyway")
+			}
+			return
+		}
+
+		return printer.printQuota(record.Path, record.Type, record.ID, resolvedName(quotaTypeLookupFn(record.Type, false), record.ID), info)
+	},
+}
+
+func init() {
+	cmdUndo.Flags().Bool("superseded", false, "Undo the change even if the limits have been changed since")
+	markChangesQuotas(cmdUndo)
+	addOutputFlags(cmdUndo)
+	cmdRoot.AddCommand(cmdUndo)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"os/user"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdUserClear = &cobra.Command{
+	Use:   "clear path user",
+	Short: "Removes all quota limits of a given user",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 2 {
+			err = errors.New("exactly two arguments required")
+			return
+		}
+
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
+		}
+
+		if isRemote(cmd) {
+			return remoteClear(cmd, printer, args[0], fsquota.QuotaTypeUser, args[1])
+		}
+
+		var u *user.User
+		if u, err = lookupUser(args[1]); err != nil {
+			return
+		}
+
+		var info *fsquota.Info
+		if info, err = fsquota.ClearUserQuota(args[0], u, setOptions(cmd)...); err != nil {
+			return
+		}
+
+		return printer.printQuota(args[0], fsquota.QuotaTypeUser, u.Uid, resolvedName(lookupUsernameByUid, u.Uid), info)
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdUserClear)
+	addOutputFlags(cmdUserClear)
+	addRemoteFlags(cmdUserClear)
+	cmdUser.AddCommand(cmdUserClear)
+}
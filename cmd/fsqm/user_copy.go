@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os/user"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+func resolveUid(userIdOrUsername string) (uid string, err error) {
+	var u *user.User
+	if u, err = lookupUser(userIdOrUsername); err == nil {
+		uid = u.Uid
+	}
+	return
+}
+
+var cmdUserCopy = &cobra.Command{
+	Use:   "copy [path] prototype user...",
+	Short: "Applies the quota limits of a prototype user to other users",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		return copyQuota(cmd, args, fsquota.QuotaTypeUser, resolveUid, lookupUsernameByUid)
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdUserCopy)
+	cmdUserCopy.Flags().Bool("all-mounts", false, "Copy limits on all filesystems with user quotas enabled, path must be omitted")
+	cmdUser.AddCommand(cmdUserCopy)
+}
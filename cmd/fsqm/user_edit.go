@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"os/user"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+var cmdUserEdit = &cobra.Command{
+	Use:   "edit user [path...]",
+	Short: "Edits a user's quota limits in $EDITOR, on all filesystems with user quotas enabled if no path is given",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) < 1 {
+			err = errors.New("at least one argument required")
+			return
+		}
+
+		var u *user.User
+		if u, err = lookupUser(args[0]); err != nil {
+			return
+		}
+
+		return editQuota(cmd, fsquota.QuotaTypeUser, u.Uid, lookupUsernameByUid(u.Uid), args[1:], func(path string) (*fsquota.Info, error) {
+			return fsquota.GetUserInfo(path, u)
+		})
+	},
+}
+
+func init() {
+	markChangesQuotas(cmdUserEdit)
+	cmdUser.AddCommand(cmdUserEdit)
+}
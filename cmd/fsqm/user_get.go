@@ -17,6 +17,15 @@ var cmdUserGet = &cobra.Command{
 			return
 		}
 
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
+		}
+
+		if isRemote(cmd) {
+			return remoteGet(cmd, printer, args[0], fsquota.QuotaTypeUser, args[1])
+		}
+
 		var u *user.User
 		if u, err = lookupUser(args[1]); err != nil {
 			return
@@ -27,12 +36,12 @@ var cmdUserGet = &cobra.Command{
 			return
 		}
 
-		printQuotaInfo(cmd, info)
-
-		return
+		return printer.printQuota(args[0], fsquota.QuotaTypeUser, u.Uid, resolvedName(lookupUsernameByUid, u.Uid), info)
 	},
 }
 
 func init() {
+	addOutputFlags(cmdUserGet)
+	addRemoteFlags(cmdUserGet)
 	cmdUser.AddCommand(cmdUserGet)
 }
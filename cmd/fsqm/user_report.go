@@ -24,23 +24,36 @@ var cmdUserReport = &cobra.Command{
 			return
 		}
 
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
+		}
+
+		if isRemote(cmd) {
+			return remoteReport(cmd, printer, args[0], fsquota.QuotaTypeUser)
+		}
+
 		var report *fsquota.Report
 		if report, err = fsquota.GetUserReport(args[0]); err != nil {
 			return
 		}
 
-		lookupFn := lookupUsernameByUid
-
-		if wantNumeric, _ := cmd.Flags().GetBool("numeric"); wantNumeric {
-			lookupFn = noopLookup
+		var entries []*fsquota.ReportEntry
+		if entries, err = queryReport(cmd, report, func(id string) string {
+			return resolvedName(lookupUsernameByUid, id)
+		}); err != nil {
+			return
 		}
 
-		printReport(cmd, report, "user", lookupFn)
-		return
+		numeric, _ := cmd.Flags().GetBool("numeric")
+		return printer.printEntries(args[0], fsquota.QuotaTypeUser, entries, numeric)
 	},
 }
 
 func init() {
 	cmdUserReport.Flags().BoolP("numeric", "n", false, "Print numeric user IDs")
+	addReportQueryFlags(cmdUserReport)
+	addOutputFlags(cmdUserReport)
+	addRemoteFlags(cmdUserReport)
 	cmdUser.AddCommand(cmdUserReport)
 }
@@ -5,7 +5,6 @@ import (
 	"os/user"
 
 	"github.com/anexia-it/fsquota"
-	"github.com/speijnik/go-errortree"
 	"github.com/spf13/cobra"
 )
 
@@ -18,20 +17,18 @@ var cmdUserSet = &cobra.Command{
 			return
 		}
 
-		var bytesSoft, bytesHard, filesSoft, filesHard uint64
-		var bytesPresent, filesPresent bool
-		var parseErr error
-
-		if bytesSoft, bytesHard, bytesPresent, parseErr = parseLimitsFlag(cmd, "bytes"); parseErr != nil {
-			err = errortree.Add(err, "bytes", parseErr)
+		var printer *quotaPrinter
+		if printer, err = newQuotaPrinter(cmd); err != nil {
+			return
 		}
 
-		if filesSoft, filesHard, filesPresent, parseErr = parseLimitsFlag(cmd, "files"); parseErr != nil {
-			err = errortree.Add(err, "files", parseErr)
+		var change *fsquota.LimitChange
+		if change, err = parseLimitChangeFlags(cmd); err != nil {
+			return
 		}
 
-		if err != nil {
-			return
+		if isRemote(cmd) {
+			return remoteSet(cmd, printer, args[0], fsquota.QuotaTypeUser, args[1], change)
 		}
 
 		var u *user.User
@@ -39,35 +36,24 @@ var cmdUserSet = &cobra.Command{
 			return
 		}
 
-		var info *fsquota.Info
-		limits := fsquota.Limits{}
-
-		if bytesPresent {
-			limits.Bytes.SetSoft(bytesSoft)
-			limits.Bytes.SetHard(bytesHard)
-		}
-
-		if filesPresent {
-			limits.Files.SetSoft(filesSoft)
-			limits.Files.SetHard(filesHard)
-		}
-
-		if !filesPresent && !bytesPresent {
-			err = errors.New("nothing to set")
+		var limits *fsquota.Limits
+		if limits, err = fsquota.ResolveLimits(args[0], fsquota.QuotaTypeUser, u.Uid, change); err != nil {
 			return
 		}
 
-		if info, err = fsquota.SetUserQuota(args[0], u, limits); err != nil {
+		var info *fsquota.Info
+		if info, err = fsquota.SetUserQuota(args[0], u, *limits, setOptions(cmd)...); err != nil {
 			return
 		}
 
-		printQuotaInfo(cmd, info)
-		return
+		return printer.printQuota(args[0], fsquota.QuotaTypeUser, u.Uid, resolvedName(lookupUsernameByUid, u.Uid), info)
 	},
 }
 
 func init() {
-	cmdUserSet.Flags().StringP("bytes", "b", "", "Byte limit in soft,hard format. ie. 1MiB,2GiB")
-	cmdUserSet.Flags().StringP("files", "f", "", "File limit in soft,hard format, ie. 1M,2G")
+	markChangesQuotas(cmdUserSet)
+	addLimitChangeFlags(cmdUserSet)
+	addOutputFlags(cmdUserSet)
+	addRemoteFlags(cmdUserSet)
 	cmdUser.AddCommand(cmdUserSet)
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/spf13/cobra"
+)
+
+func lookupWarningSubject(warning *fsquota.QuotaWarning) string {
+	id := fmt.Sprint(warning.ID)
+
+	switch warning.Type {
+	case fsquota.QuotaTypeUser:
+		return lookupUsernameByUid(id)
+	case fsquota.QuotaTypeGroup:
+		return lookupGroupnameByGid(id)
+	}
+	return id
+}
+
+func runWarningHook(hook string, warning *fsquota.QuotaWarning) error {
+	hookCmd := exec.Command(hook)
+	hookCmd.Stdout = os.Stdout
+	hookCmd.Stderr = os.Stderr
+	hookCmd.Env = append(os.Environ(),
+		fmt.Sprintf("FSQM_QUOTA_TYPE=%s", warning.Type),
+		fmt.Sprintf("FSQM_ID=%d", warning.ID),
+		fmt.Sprintf("FSQM_NAME=%s", lookupWarningSubject(warning)),
+		fmt.Sprintf("FSQM_WARNING=%d", uint32(warning.Kind)),
+		fmt.Sprintf("FSQM_WARNING_DESCRIPTION=%s", warning.Kind),
+		fmt.Sprintf("FSQM_DEVICE_MAJOR=%d", warning.DeviceMajor),
+		fmt.Sprintf("FSQM_DEVICE_MINOR=%d", warning.DeviceMinor),
+		fmt.Sprintf("FSQM_CALLER_UID=%d", warning.CallerUID),
+	)
+
+	return hookCmd.Run()
+}
+
+var cmdWarnings = &cobra.Command{
+	Use:   "warnings",
+	Short: "Listens for quota warnings emitted by the kernel",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		if len(args) != 0 {
+			err = errors.New("no arguments expected")
+			return
+		}
+
+		hook, _ := cmd.Flags().GetString("exec")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Stop listening on SIGINT and SIGTERM
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(signals)
+		go func() {
+			select {
+			case <-signals:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		var watcher *fsquota.QuotaWarningWatcher
+		if watcher, err = fsquota.WatchQuotaWarnings(ctx); err != nil {
+			return
+		}
+
+		for warning := range watcher.Warnings {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s on device %d:%d: %s (caused by uid %d)\n", warning.Type, lookupWarningSubject(warning),
+				warning.DeviceMajor, warning.DeviceMinor, warning.Kind, warning.CallerUID)
+
+			if hook != "" {
+				if hookErr := runWarningHook(hook, warning); hookErr != nil {
+					cmd.Printf("hook failed: %s\n", hookErr)
+				}
+			}
+		}
+
+		return watcher.Err()
+	},
+}
+
+func init() {
+	cmdWarnings.Flags().StringP("exec", "e", "", "Command to run for every warning, receiving details via FSQM_* environment variables")
+	cmdRoot.AddCommand(cmdWarnings)
+}
@@ -0,0 +1,71 @@
+package fsquota
+
+import (
+	"errors"
+
+	"github.com/docker/docker/pkg/mount"
+	"github.com/speijnik/go-errortree"
+)
+
+type getQuotaFn func(t quotaCtlType, path string, idString string) (*Info, error)
+type setQuotaFn func(t quotaCtlType, path string, idString string, limits *Limits) (*Info, error)
+
+// copyQuota applies the limits of the prototype fromID to all of toIDs.
+// Errors are collected per target ID, so a single failing ID does not prevent the others from being updated.
+func copyQuota(t quotaCtlType, path string, fromID string, toIDs []string, getFn getQuotaFn, setFn setQuotaFn) (infos map[string]*Info, err error) {
+	if len(toIDs) == 0 {
+		err = errors.New("no target IDs provided")
+		return
+	}
+
+	var prototype *Info
+	if prototype, err = getFn(t, path, fromID); err != nil {
+		return
+	}
+
+	// Copy all limits, including unset ones, so the targets end up with exactly the prototype's limits
+	bytesHard, bytesSoft, _ := prototype.Bytes.getValues()
+	filesHard, filesSoft, _ := prototype.Files.getValues()
+
+	infos = make(map[string]*Info, len(toIDs))
+	for _, id := range toIDs {
+		limits := &Limits{}
+		limits.Bytes.SetHard(bytesHard)
+		limits.Bytes.SetSoft(bytesSoft)
+		limits.Files.SetHard(filesHard)
+		limits.Files.SetSoft(filesSoft)
+
+		info, setErr := setFn(t, path, id, limits)
+		if setErr != nil {
+			err = errortree.Add(err, id, setErr)
+			continue
+		}
+		infos[id] = info
+	}
+
+	return
+}
+
+// quotaMountPoints returns the mount points of all local filesystems with quotas of the given type enabled.
+// Every filesystem is only listed once, even if it is mounted multiple times.
+func quotaMountPoints(t quotaCtlType) (mountPoints []string, err error) {
+	var mountInfos []*mount.Info
+	if mountInfos, err = mount.GetMounts(); err != nil {
+		return
+	}
+
+	seen := make(map[[2]int]bool)
+	for _, mountInfo := range mountInfos {
+		dev := [2]int{mountInfo.Major, mountInfo.Minor}
+		if seen[dev] || isNFSFilesystem(mountInfo.Fstype) {
+			continue
+		}
+
+		if supported, _ := quotasSupported(t, mountInfo.Mountpoint); supported {
+			seen[dev] = true
+			mountPoints = append(mountPoints, mountInfo.Mountpoint)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,61 @@
+package fsquota
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/speijnik/go-errortree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyQuota(t *testing.T) {
+	prototype := &Info{}
+	prototype.Bytes.SetSoft(1024)
+	prototype.Bytes.SetHard(2048)
+
+	setErr := errors.New("set failed")
+	applied := make(map[string]*Limits)
+
+	getFn := func(typ quotaCtlType, path string, idString string) (*Info, error) {
+		assert.EqualValues(t, groupQuota, typ)
+		assert.EqualValues(t, "/mnt", path)
+		assert.EqualValues(t, "100", idString)
+		return prototype, nil
+	}
+
+	setFn := func(typ quotaCtlType, path string, idString string, limits *Limits) (*Info, error) {
+		if idString == "102" {
+			return nil, setErr
+		}
+		applied[idString] = limits
+		return &Info{}, nil
+	}
+
+	infos, err := copyQuota(groupQuota, "/mnt", "100", []string{"101", "102", "103"}, getFn, setFn)
+	if assert.Error(t, err) {
+		assert.EqualValues(t, setErr, errortree.Get(err, "102"))
+		assert.Nil(t, errortree.Get(err, "101"))
+	}
+	assert.Len(t, infos, 2)
+	assert.Contains(t, infos, "101")
+	assert.Contains(t, infos, "103")
+
+	require.Contains(t, applied, "101")
+	bytesHard, bytesSoft, bytesOK := applied["101"].Bytes.getValues()
+	assert.True(t, bytesOK)
+	assert.EqualValues(t, 2048, bytesHard)
+	assert.EqualValues(t, 1024, bytesSoft)
+
+	// Unset prototype limits are copied as zero, clearing any existing target limits
+	filesHard, filesSoft, filesOK := applied["101"].Files.getValues()
+	assert.True(t, filesOK)
+	assert.EqualValues(t, 0, filesHard)
+	assert.EqualValues(t, 0, filesSoft)
+}
+
+func TestCopyQuotaNoTargets(t *testing.T) {
+	infos, err := copyQuota(userQuota, "/mnt", "100", nil, nil, nil)
+	assert.Error(t, err)
+	assert.Nil(t, infos)
+}
@@ -0,0 +1,145 @@
+package fsquota
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/mount"
+)
+
+var (
+	// ErrDefaultLimitsNotSupported is returned when the filesystem has no notion of default quota limits
+	ErrDefaultLimitsNotSupported = errors.New("default limits are not supported by this filesystem")
+	// ErrDefaultLimitsReadOnly is returned when default limits can only be configured at mount time
+	ErrDefaultLimitsReadOnly = errors.New("default limits can only be configured via mount options")
+)
+
+// tmpfsDefaultLimitOptions maps quota types to the tmpfs mount options carrying their default hard limits
+var tmpfsDefaultLimitOptions = map[quotaCtlType][2]string{
+	userQuota:  {"usrquota_block_hardlimit", "usrquota_inode_hardlimit"},
+	groupQuota: {"grpquota_block_hardlimit", "grpquota_inode_hardlimit"},
+}
+
+// tmpfsDefaultLimits parses the default limits from the super block options of a tmpfs mount
+func tmpfsDefaultLimits(t quotaCtlType, vfsOpts string) (limits *Limits, err error) {
+	optionNames, supported := tmpfsDefaultLimitOptions[t]
+	if !supported {
+		err = ErrDefaultLimitsNotSupported
+		return
+	}
+
+	limits = &Limits{}
+	for _, option := range strings.Split(vfsOpts, ",") {
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		var target *Limit
+		switch parts[0] {
+		case optionNames[0]:
+			target = &limits.Bytes
+		case optionNames[1]:
+			target = &limits.Files
+		default:
+			continue
+		}
+
+		value, parseErr := strconv.ParseUint(parts[1], 10, 64)
+		if parseErr != nil {
+			err = fmt.Errorf("invalid value for %s: %s", parts[0], parseErr)
+			limits = nil
+			return
+		}
+		target.SetHard(value)
+	}
+
+	return
+}
+
+func getDefaultLimits(path string, t quotaCtlType) (limits *Limits, err error) {
+	var mountInfo *mount.Info
+	if mountInfo, err = mountInfoForPath(path); err != nil {
+		return
+	}
+
+	switch mountInfo.Fstype {
+	case "xfs":
+		// XFS uses the limits stored for ID 0 as defaults
+		var device string
+		if device, err = pathToDevice(path); err != nil {
+			return
+		}
+
+		var info *Info
+		if info, err = internalGetQuota(t, device, 0); err != nil {
+			return
+		}
+		limits = &info.Limits
+	case "tmpfs":
+		limits, err = tmpfsDefaultLimits(t, mountInfo.VfsOpts)
+	default:
+		err = ErrDefaultLimitsNotSupported
+	}
+
+	return
+}
+
+func setDefaultLimits(path string, t quotaCtlType, limits *Limits, opts []SetOption) (result *Limits, err error) {
+	var mountInfo *mount.Info
+	if mountInfo, err = mountInfoForPath(path); err != nil {
+		return
+	}
+
+	switch mountInfo.Fstype {
+	case "xfs":
+		var info *Info
+		if info, err = policySetQuota(opts)(t, path, "0", limits); err != nil {
+			return
+		}
+		result = &info.Limits
+	case "tmpfs":
+		err = ErrDefaultLimitsReadOnly
+	default:
+		err = ErrDefaultLimitsNotSupported
+	}
+
+	return
+}
+
+func limitsEqual(a, b *Limits) bool {
+	aBytesHard, aBytesSoft, _ := a.Bytes.getValues()
+	aFilesHard, aFilesSoft, _ := a.Files.getValues()
+	bBytesHard, bBytesSoft, _ := b.Bytes.getValues()
+	bFilesHard, bFilesSoft, _ := b.Files.getValues()
+
+	return aBytesHard == bBytesHard && aBytesSoft == bBytesSoft && aFilesHard == bFilesHard && aFilesSoft == bFilesSoft
+}
+
+func limitsEmpty(l *Limits) bool {
+	return limitsEqual(l, &Limits{})
+}
+
+// markInheritedDefaults flags report entries whose effective limits are probably the filesystem's default limits.
+// This is only the case on XFS, where IDs without limits of their own fall back to the limits of ID 0.
+// As XFS copies the defaults into the quota of IDs it allocates, IDs whose limits were explicitly set to the
+// defaults are flagged as well; see Info.InheritsDefaultLimits.
+func markInheritedDefaults(report *Report, path string, t quotaCtlType) {
+	defaults, err := getDefaultLimits(path, t)
+	if err != nil || limitsEmpty(defaults) {
+		return
+	}
+
+	for id, info := range report.Infos {
+		if id == "0" {
+			continue
+		}
+
+		// XFS copies the default limits into the quota of IDs it allocates without limits
+		if limitsEmpty(&info.Limits) || limitsEqual(&info.Limits, defaults) {
+			info.InheritsDefaultLimits = true
+		}
+	}
+}
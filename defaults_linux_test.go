@@ -0,0 +1,50 @@
+package fsquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTmpfsDefaultLimits(t *testing.T) {
+	const vfsOpts = "rw,size=1048576k,usrquota,usrquota_block_hardlimit=1048576,usrquota_inode_hardlimit=100,grpquota"
+
+	t.Run("User", func(t *testing.T) {
+		limits, err := tmpfsDefaultLimits(userQuota, vfsOpts)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1048576, limits.Bytes.GetHard())
+		assert.EqualValues(t, 100, limits.Files.GetHard())
+		assert.EqualValues(t, 0, limits.Bytes.GetSoft())
+	})
+
+	t.Run("GroupWithoutDefaults", func(t *testing.T) {
+		limits, err := tmpfsDefaultLimits(groupQuota, vfsOpts)
+		require.NoError(t, err)
+		assert.True(t, limitsEmpty(limits))
+	})
+
+	t.Run("Project", func(t *testing.T) {
+		_, err := tmpfsDefaultLimits(projectQuota, vfsOpts)
+		assert.EqualValues(t, ErrDefaultLimitsNotSupported, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := tmpfsDefaultLimits(userQuota, "usrquota_inode_hardlimit=abc")
+		assert.Error(t, err)
+	})
+}
+
+func TestLimitsEqual(t *testing.T) {
+	a := &Limits{}
+	b := &Limits{}
+	assert.True(t, limitsEqual(a, b))
+	assert.True(t, limitsEmpty(a))
+
+	a.Bytes.SetHard(1024)
+	assert.False(t, limitsEqual(a, b))
+	assert.False(t, limitsEmpty(a))
+
+	b.Bytes.SetHard(1024)
+	assert.True(t, limitsEqual(a, b))
+}
@@ -0,0 +1,25 @@
+package fsquota
+
+// ChangePreview describes a change computed, but not applied, in dry-run mode
+type ChangePreview struct {
+	// Operation is either AuditOperationSet or AuditOperationClear
+	Operation string
+	Path      string
+	Type      QuotaType
+	ID        string
+	// Before is the current quota information, After the information expected once the change is applied
+	Before *Info
+	After  *Info
+}
+
+// PreviewFn receives the changes computed in dry-run mode
+type PreviewFn func(preview *ChangePreview)
+
+// DryRun computes changes without applying them. Calls return the expected results, fn receives every
+// change computed and may be nil.
+func DryRun(fn PreviewFn) SetOption {
+	return func(options *setOptions) {
+		options.dryRun = true
+		options.previewFn = fn
+	}
+}
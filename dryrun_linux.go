@@ -0,0 +1,86 @@
+package fsquota
+
+import "time"
+
+// previewLimit returns the limit expected once limit is applied on top of current
+func previewLimit(limit, current *Limit) *Limit {
+	if _, _, ok := limit.getValues(); !ok {
+		return current
+	}
+	return limit.completedFrom(current)
+}
+
+// previewQuota computes the result of setting limits, without applying them. Clearing also resets
+// the grace times.
+func previewQuota(operation string, t quotaCtlType, path string, idString string, limits *Limits, getFn getQuotaFn, fn PreviewFn) (info *Info, err error) {
+	var before *Info
+	if before, err = getFn(t, path, idString); err == ErrRquotaNoQuota {
+		before, err = &Info{}, nil
+	}
+	if err != nil {
+		return
+	}
+
+	info = &Info{
+		BytesUsed:         before.BytesUsed,
+		FilesUsed:         before.FilesUsed,
+		BytesGraceExpires: before.BytesGraceExpires,
+		FilesGraceExpires: before.FilesGraceExpires,
+	}
+	info.Bytes.set(previewLimit(&limits.Bytes, &before.Bytes))
+	info.Files.set(previewLimit(&limits.Files, &before.Files))
+
+	if operation == AuditOperationClear {
+		info.BytesGraceExpires, info.FilesGraceExpires = time.Time{}, time.Time{}
+	}
+
+	if fn != nil {
+		fn(&ChangePreview{
+			Operation: operation,
+			Path:      path,
+			Type:      QuotaType(t),
+			ID:        idString,
+			Before:    before,
+			After:     info,
+		})
+	}
+	return
+}
+
+// clearLimits returns limits removing all soft and hard limits
+func clearLimits() *Limits {
+	limits := &Limits{}
+	limits.Bytes.SetSoft(0)
+	limits.Bytes.SetHard(0)
+	limits.Files.SetSoft(0)
+	limits.Files.SetHard(0)
+	return limits
+}
+
+// optionsSetQuota returns the function setting quotas, which only previews changes in dry-run mode.
+// Unlike policySetQuota, it does not validate changes against the policy.
+func optionsSetQuota(opts []SetOption) setQuotaFn {
+	options := newSetOptions(opts)
+	if !options.dryRun {
+		return setQuota
+	}
+
+	return func(t quotaCtlType, path string, idString string, limits *Limits) (*Info, error) {
+		return previewQuota(AuditOperationSet, t, path, idString, limits, getQuota, options.previewFn)
+	}
+}
+
+// clearQuotaFn removes all limits of an ID
+type clearQuotaFn func(t quotaCtlType, path string, idString string) (*Info, error)
+
+// optionsClearQuota returns the function clearing quotas, which only previews changes in dry-run mode
+func optionsClearQuota(opts []SetOption) clearQuotaFn {
+	options := newSetOptions(opts)
+	if !options.dryRun {
+		return clearQuota
+	}
+
+	return func(t quotaCtlType, path string, idString string) (*Info, error) {
+		return previewQuota(AuditOperationClear, t, path, idString, clearLimits(), getQuota, options.previewFn)
+	}
+}
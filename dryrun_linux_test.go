@@ -0,0 +1,72 @@
+package fsquota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewQuota(t *testing.T) {
+	graceExpires := time.Unix(1500000000, 0)
+	getFn := func(t quotaCtlType, path string, idString string) (*Info, error) {
+		if idString == "1001" {
+			return nil, ErrRquotaNoQuota
+		}
+
+		info := &Info{BytesUsed: 150, FilesUsed: 3, BytesGraceExpires: graceExpires}
+		info.Bytes.SetSoft(100)
+		info.Bytes.SetHard(200)
+		info.Files.SetSoft(10)
+		info.Files.SetHard(20)
+		return info, nil
+	}
+
+	var previews []*ChangePreview
+	previewFn := func(preview *ChangePreview) {
+		previews = append(previews, preview)
+	}
+
+	// Limits not provided retain their current value
+	limits := &Limits{}
+	limits.Bytes.SetHard(300)
+	info, err := previewQuota(AuditOperationSet, userQuota, "/srv", "1000", limits, getFn, previewFn)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, info.Bytes.GetSoft())
+	assert.EqualValues(t, 300, info.Bytes.GetHard())
+	assert.EqualValues(t, 10, info.Files.GetSoft())
+	assert.EqualValues(t, 20, info.Files.GetHard())
+	assert.EqualValues(t, 150, info.BytesUsed)
+	assert.EqualValues(t, graceExpires, info.BytesGraceExpires)
+
+	// Clearing resets the grace times
+	info, err = previewQuota(AuditOperationClear, userQuota, "/srv", "1000", clearLimits(), getFn, previewFn)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, info.Bytes.GetHard())
+	assert.EqualValues(t, 0, info.Files.GetSoft())
+	assert.True(t, info.BytesGraceExpires.IsZero())
+
+	// IDs without quota entries start out without limits
+	limits = &Limits{}
+	limits.Files.SetSoft(5)
+	info, err = previewQuota(AuditOperationSet, userQuota, "/srv", "1001", limits, getFn, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, info.Files.GetSoft())
+	assert.EqualValues(t, 0, info.Files.GetHard())
+
+	if assert.Len(t, previews, 2) {
+		assert.EqualValues(t, AuditOperationSet, previews[0].Operation)
+		assert.EqualValues(t, QuotaTypeUser, previews[0].Type)
+		assert.EqualValues(t, "1000", previews[0].ID)
+		assert.EqualValues(t, 200, previews[0].Before.Bytes.GetHard())
+		assert.EqualValues(t, 300, previews[0].After.Bytes.GetHard())
+		assert.EqualValues(t, AuditOperationClear, previews[1].Operation)
+	}
+}
+
+func TestNewSetOptions_DryRun(t *testing.T) {
+	options := newSetOptions([]SetOption{DryRun(nil)})
+	assert.True(t, options.dryRun)
+	assert.False(t, options.overridePolicy)
+}
@@ -0,0 +1,291 @@
+package fsquota
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/speijnik/go-errortree"
+)
+
+// ExportVersion is the version of the export format written by WriteExport
+const ExportVersion = 1
+
+// errImportDefaultsID is reported for entries of ID 0, which holds the default limits on XFS
+var errImportDefaultsID = errors.New("ID 0 holds the default limits of some filesystems and is not imported")
+
+// ExportLimits contains the soft and hard limits of an export entry
+type ExportLimits struct {
+	BytesSoft uint64 `json:"bytes_soft"`
+	BytesHard uint64 `json:"bytes_hard"`
+	FilesSoft uint64 `json:"files_soft"`
+	FilesHard uint64 `json:"files_hard"`
+}
+
+func newExportLimits(limits *Limits) ExportLimits {
+	bytesHard, bytesSoft, _ := limits.Bytes.getValues()
+	filesHard, filesSoft, _ := limits.Files.getValues()
+	return ExportLimits{
+		BytesSoft: bytesSoft,
+		BytesHard: bytesHard,
+		FilesSoft: filesSoft,
+		FilesHard: filesHard,
+	}
+}
+
+// limits returns limits setting all soft and hard limits of l
+func (l *ExportLimits) limits() *Limits {
+	limits := &Limits{}
+	limits.Bytes.SetSoft(l.BytesSoft)
+	limits.Bytes.SetHard(l.BytesHard)
+	limits.Files.SetSoft(l.FilesSoft)
+	limits.Files.SetHard(l.FilesHard)
+	return limits
+}
+
+// ExportEntry contains the limits of a single user, group or project
+type ExportEntry struct {
+	Type QuotaType `json:"type"`
+	// Name of the user, group or project, empty if the ID could not be resolved on the exporting host
+	Name string `json:"name,omitempty"`
+	ID   string `json:"id"`
+
+	ExportLimits
+}
+
+// key returns the name identifying the entry across hosts, or its ID if it has no name
+func (e *ExportEntry) key() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.ID
+}
+
+// Export contains the limits configured on a filesystem keyed by name, for moving them to another host
+type Export struct {
+	Version   int       `json:"version"`
+	Host      string    `json:"host"`
+	Path      string    `json:"path"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Defaults contains the default limits of every quota type the filesystem has default limits configured for
+	Defaults map[QuotaType]*ExportLimits `json:"defaults,omitempty"`
+	Entries  []*ExportEntry              `json:"entries"`
+}
+
+// nameLookupFn resolves the name of an ID, returning an empty string if it is unknown
+type nameLookupFn func(t QuotaType, id string) string
+
+// idLookupFn resolves the ID of a name
+type idLookupFn func(t QuotaType, name string) (id string, err error)
+
+// protectedIDFn reports whether an ID is protected by the policy
+type protectedIDFn func(t QuotaType, id string) bool
+
+// newExport creates an export of the default limits and of all IDs with limits from reports.
+// ID 0, which holds the default limits on XFS, is skipped. Protected IDs are exported, importing rejects them.
+func newExport(host, path string, timestamp time.Time, reports map[QuotaType]*Report, defaults map[QuotaType]*Limits, nameFn nameLookupFn) *Export {
+	export := &Export{
+		Version:   ExportVersion,
+		Host:      host,
+		Path:      path,
+		Timestamp: timestamp,
+		Entries:   []*ExportEntry{},
+	}
+
+	for _, t := range QuotaTypes() {
+		if limits, ok := defaults[t]; ok && !limitsEmpty(limits) {
+			if export.Defaults == nil {
+				export.Defaults = make(map[QuotaType]*ExportLimits)
+			}
+			exportLimits := newExportLimits(limits)
+			export.Defaults[t] = &exportLimits
+		}
+
+		report, ok := reports[t]
+		if !ok {
+			continue
+		}
+
+		for _, id := range report.IDs() {
+			info := report.Infos[id]
+			if id == "0" || !hasLimits(info) {
+				continue
+			}
+
+			export.Entries = append(export.Entries, &ExportEntry{
+				Type:         t,
+				Name:         nameFn(t, id),
+				ID:           id,
+				ExportLimits: newExportLimits(&info.Limits),
+			})
+		}
+	}
+
+	return export
+}
+
+// WriteExport serializes an export
+func WriteExport(w io.Writer, export *Export) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// ReadExport deserializes an export written by WriteExport
+func ReadExport(r io.Reader) (export *Export, err error) {
+	export = &Export{}
+	if err = json.NewDecoder(r).Decode(export); err != nil {
+		export = nil
+		return
+	}
+
+	if export.Version != ExportVersion {
+		err = fmt.Errorf("unsupported export version %d", export.Version)
+		export = nil
+	}
+	return
+}
+
+// ImportMapping maps names or IDs of the exporting host to names or IDs on the importing host, by quota type
+type ImportMapping map[QuotaType]map[string]string
+
+// ReadImportMapping reads a mapping in "type old new" format, one entry per line.
+// Old is a name or ID of the exporting host, new a name or ID on the importing host.
+func ReadImportMapping(r io.Reader) (mapping ImportMapping, err error) {
+	mapping = make(ImportMapping)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineKey := fmt.Sprintf("line %d", lineNumber)
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			err = errortree.Add(err, lineKey, errors.New("expected format is: type old new"))
+			continue
+		}
+
+		var t QuotaType
+		if typeErr := t.UnmarshalText([]byte(fields[0])); typeErr != nil {
+			err = errortree.Add(err, lineKey, typeErr)
+			continue
+		}
+
+		if mapping[t] == nil {
+			mapping[t] = make(map[string]string)
+		}
+		if _, ok := mapping[t][fields[1]]; ok {
+			err = errortree.Add(err, lineKey, errors.New("duplicate mapping for "+fields[1]))
+			continue
+		}
+		mapping[t][fields[1]] = fields[2]
+	}
+
+	if scanErr := scanner.Err(); scanErr != nil {
+		err = scanErr
+	}
+	if err != nil {
+		mapping = nil
+	}
+	return
+}
+
+// ImportResult describes the outcome of importing an export
+type ImportResult struct {
+	// Changes contains the limits set, keyed by the IDs on the importing host
+	Changes []*QuotaChange
+	// Defaults contains the default limits set by quota type
+	Defaults map[QuotaType]*Limits
+	// Unresolved lists the entries which could not be mapped to an ID on the importing host or map to ID 0 or to
+	// protected IDs, they are skipped
+	Unresolved []*UnresolvedEntry
+}
+
+// UnresolvedEntry is an exported entry which could not be mapped to an ID
+type UnresolvedEntry struct {
+	Entry *ExportEntry
+	Err   error
+}
+
+// errImportNameless is reported for entries exported without a name, which are neither mapped nor kept by ID
+var errImportNameless = errors.New("exported without a name, map it to a name or ID or keep IDs")
+
+// resolveImportID maps an entry to an ID on the importing host. Entries are looked up by name, mapped names or
+// IDs take precedence. Numeric IDs are only used as is if the mapping asks for it, or if the entry has no name and
+// keepIDs is set, as IDs of the same name usually differ between hosts.
+func resolveImportID(entry *ExportEntry, mapping ImportMapping, keepIDs bool, lookupFn idLookupFn) (id string, err error) {
+	target, mapped := mapping[entry.Type][entry.key()]
+	if !mapped {
+		target, mapped = mapping[entry.Type][entry.ID]
+	}
+
+	switch {
+	case !mapped && entry.Name == "" && keepIDs:
+		id = entry.ID
+		return
+	case !mapped && entry.Name == "":
+		err = errImportNameless
+		return
+	case !mapped:
+		target = entry.Name
+	case isNumericID(target):
+		id = target
+		return
+	}
+
+	if id, err = lookupFn(entry.Type, target); err != nil {
+		id = ""
+	}
+	return
+}
+
+func isNumericID(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
+
+// importChanges resolves the entries of export to changes of path, see resolveImportID. Entries mapping to ID 0 or to
+// IDs protected by protectedFn are reported as unresolved.
+func importChanges(export *Export, path string, mapping ImportMapping, keepIDs bool, lookupFn idLookupFn, protectedFn protectedIDFn) (result *ImportResult) {
+	result = &ImportResult{}
+	for t, limits := range export.Defaults {
+		if result.Defaults == nil {
+			result.Defaults = make(map[QuotaType]*Limits)
+		}
+		result.Defaults[t] = limits.limits()
+	}
+
+	for _, entry := range export.Entries {
+		id, err := resolveImportID(entry, mapping, keepIDs, lookupFn)
+		switch {
+		case err != nil:
+		case id == "0":
+			err = errImportDefaultsID
+		case protectedFn(entry.Type, id):
+			err = fmt.Errorf("ID %s is protected by the policy", id)
+		}
+		if err != nil {
+			result.Unresolved = append(result.Unresolved, &UnresolvedEntry{Entry: entry, Err: err})
+			continue
+		}
+
+		result.Changes = append(result.Changes, &QuotaChange{
+			Path:   path,
+			Type:   entry.Type,
+			ID:     id,
+			Limits: *entry.limits(),
+		})
+	}
+	return
+}
@@ -0,0 +1,157 @@
+package fsquota
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/speijnik/go-errortree"
+)
+
+// lookupQuotaName resolves the name of a user, group or project on this host.
+// Users and groups os/user does not know, as it only reads /etc/passwd and /etc/group in builds without cgo, are
+// looked up via NSS.
+func lookupQuotaName(t QuotaType, id string) string {
+	switch t {
+	case QuotaTypeUser:
+		if u, err := user.LookupId(id); err == nil {
+			return u.Username
+		}
+		if name, _, found, _ := lookupNSSEntry("passwd", id); found {
+			return name
+		}
+	case QuotaTypeGroup:
+		if g, err := user.LookupGroupId(id); err == nil {
+			return g.Name
+		}
+		if name, _, found, _ := lookupNSSEntry("group", id); found {
+			return name
+		}
+	case QuotaTypeProject:
+		projects, _ := getProjectsFromProjidFile(projidFile)
+		for name, projectID := range projects {
+			if projectID == id {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// lookupNSSID resolves a name unknown to os/user via NSS, notFoundErr is returned if it is not found there either
+func lookupNSSID(database, name string, notFoundErr error) (id string, err error) {
+	var found bool
+	if _, id, found, err = lookupNSSEntry(database, name); err == nil && !found {
+		err = notFoundErr
+	}
+	return
+}
+
+// lookupQuotaID resolves the ID of a user, group or project name on this host, see lookupQuotaName
+func lookupQuotaID(t QuotaType, name string) (id string, err error) {
+	switch t {
+	case QuotaTypeUser:
+		var u *user.User
+		if u, err = user.Lookup(name); err == nil {
+			id = u.Uid
+			return
+		}
+		return lookupNSSID("passwd", name, err)
+	case QuotaTypeGroup:
+		var g *user.Group
+		if g, err = user.LookupGroup(name); err == nil {
+			id = g.Gid
+			return
+		}
+		return lookupNSSID("group", name, err)
+	}
+
+	var projects map[string]string
+	if projects, err = getProjectsFromProjidFile(projidFile); err != nil {
+		return
+	}
+
+	var ok bool
+	if id, ok = projects[name]; !ok {
+		err = fmt.Errorf("unknown project %s", name)
+	}
+	return
+}
+
+// policyProtectedID reports whether an ID is protected by the active policy, unless it has been overridden via opts
+func policyProtectedID(opts []SetOption) protectedIDFn {
+	return func(t QuotaType, id string) bool {
+		return policyProtected(quotaCtlType(t), opts)(id)
+	}
+}
+
+func exportQuotas(path string) (export *Export, err error) {
+	var host string
+	if host, err = os.Hostname(); err != nil {
+		return
+	}
+
+	var reports map[QuotaType]*Report
+	if reports, err = getEnabledReports(path); err != nil {
+		return
+	}
+
+	defaults := make(map[QuotaType]*Limits, len(reports))
+	for t := range reports {
+		limits, defaultsErr := getDefaultLimits(path, quotaCtlType(t))
+		if defaultsErr == ErrDefaultLimitsNotSupported {
+			continue
+		} else if defaultsErr != nil {
+			err = errortree.Add(err, "defaults:"+t.String(), defaultsErr)
+			continue
+		}
+		defaults[t] = limits
+	}
+	if err != nil {
+		return
+	}
+
+	export = newExport(host, path, time.Now().UTC(), reports, defaults, lookupQuotaName)
+	return
+}
+
+func resolveImport(export *Export, path string, mapping ImportMapping, keepIDs bool, opts []SetOption) (result *ImportResult) {
+	return importChanges(export, path, mapping, keepIDs, lookupQuotaID, policyProtectedID(opts))
+}
+
+// importDefaults sets the default limits of result on path. Quota types path has no configurable default limits for
+// are skipped and returned with the reason.
+func importDefaults(result *ImportResult, path string, opts []SetOption) (skipped map[QuotaType]error, err error) {
+	for _, t := range QuotaTypes() {
+		limits, ok := result.Defaults[t]
+		if !ok {
+			continue
+		}
+
+		_, setErr := setDefaultLimits(path, quotaCtlType(t), limits, opts)
+		switch setErr {
+		case nil:
+		case ErrDefaultLimitsNotSupported, ErrDefaultLimitsReadOnly:
+			if skipped == nil {
+				skipped = make(map[QuotaType]error)
+			}
+			skipped[t] = setErr
+		default:
+			err = errortree.Add(err, "defaults:"+t.String(), setErr)
+		}
+	}
+	return
+}
+
+func importQuotas(export *Export, path string, mapping ImportMapping, keepIDs bool, opts []SetOption) (result *ImportResult, err error) {
+	result = resolveImport(export, path, mapping, keepIDs, opts)
+	if len(result.Changes) > 0 {
+		if err = applyQuotaChanges(result.Changes, getQuota, optionsSetQuota(opts), policyCheck(opts)); err != nil {
+			return
+		}
+	}
+
+	_, err = importDefaults(result, path, opts)
+	return
+}
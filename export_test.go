@@ -0,0 +1,158 @@
+package fsquota
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	limited := &Info{BytesUsed: 100}
+	limited.Bytes.SetSoft(1000)
+	limited.Bytes.SetHard(2000)
+	limited.Files.SetHard(10)
+
+	inherited := &Info{InheritsDefaultLimits: true}
+	inherited.Bytes.SetHard(5000)
+
+	reports := map[QuotaType]*Report{
+		QuotaTypeUser: {Infos: map[string]*Info{
+			"0":    inherited,
+			"1000": limited,
+			"1001": {BytesUsed: 100},
+			"1002": inherited,
+			"2000": limited,
+			"3000": limited,
+		}},
+		QuotaTypeProject: {Infos: map[string]*Info{"10": limited}},
+	}
+	defaults := map[QuotaType]*Limits{
+		QuotaTypeUser:  &inherited.Limits,
+		QuotaTypeGroup: {},
+	}
+	names := map[string]string{"1000": "alice", "10": "web"}
+	nameFn := func(t QuotaType, id string) string {
+		return names[id]
+	}
+	timestamp := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	export := newExport("host", "/home", timestamp, reports, defaults, nameFn)
+	limits := ExportLimits{BytesSoft: 1000, BytesHard: 2000, FilesHard: 10}
+	assert.EqualValues(t, map[QuotaType]*ExportLimits{QuotaTypeUser: {BytesHard: 5000}}, export.Defaults)
+	assert.EqualValues(t, []*ExportEntry{
+		{Type: QuotaTypeUser, Name: "alice", ID: "1000", ExportLimits: limits},
+		{Type: QuotaTypeUser, ID: "1002", ExportLimits: ExportLimits{BytesHard: 5000}},
+		{Type: QuotaTypeUser, ID: "2000", ExportLimits: limits},
+		{Type: QuotaTypeUser, ID: "3000", ExportLimits: limits},
+		{Type: QuotaTypeProject, Name: "web", ID: "10", ExportLimits: limits},
+	}, export.Entries)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteExport(buf, export))
+	assert.Contains(t, buf.String(), `"project"`)
+	assert.Contains(t, buf.String(), `"bytes_soft": 1000`)
+
+	decoded, err := ReadExport(buf)
+	require.NoError(t, err)
+	assert.EqualValues(t, export, decoded)
+
+	_, err = ReadExport(bytes.NewBufferString(`{"version": 2}`))
+	assert.Error(t, err)
+}
+
+func TestReadImportMapping(t *testing.T) {
+	mapping, err := ReadImportMapping(bytes.NewBufferString(`
+# comment
+user alice alice2
+user 1000 2000
+group staff 100
+`))
+	require.NoError(t, err)
+	assert.EqualValues(t, ImportMapping{
+		QuotaTypeUser:  {"alice": "alice2", "1000": "2000"},
+		QuotaTypeGroup: {"staff": "100"},
+	}, mapping)
+
+	mapping, err = ReadImportMapping(bytes.NewBufferString("user alice\nteam a b\nuser a b\nuser a c\n"))
+	assert.Nil(t, mapping)
+	assert.EqualError(t, err, `3 errors occurred:
+
+* line 1: expected format is: type old new
+* line 2: unknown quota type "team"
+* line 4: duplicate mapping for a`)
+}
+
+func TestImportChanges(t *testing.T) {
+	export := &Export{
+		Defaults: map[QuotaType]*ExportLimits{QuotaTypeGroup: {FilesHard: 100}},
+		Entries: []*ExportEntry{
+			{Type: QuotaTypeUser, Name: "alice", ID: "1000", ExportLimits: ExportLimits{BytesHard: 2000}},
+			{Type: QuotaTypeUser, Name: "bob", ID: "1001", ExportLimits: ExportLimits{BytesHard: 3000}},
+			{Type: QuotaTypeUser, ID: "1002", ExportLimits: ExportLimits{FilesHard: 10}},
+			{Type: QuotaTypeUser, Name: "carol", ID: "1003"},
+			{Type: QuotaTypeGroup, Name: "staff", ID: "100"},
+			{Type: QuotaTypeProject, ID: "10"},
+			{Type: QuotaTypeUser, Name: "dave", ID: "1004"},
+			{Type: QuotaTypeUser, Name: "root", ID: "0"},
+			{Type: QuotaTypeUser, Name: "erin", ID: "1005"},
+		},
+	}
+	mapping := ImportMapping{
+		QuotaTypeUser:    {"carol": "carol2", "1004": "5000"},
+		QuotaTypeProject: {"10": "20"},
+	}
+	ids := map[QuotaType]map[string]string{
+		QuotaTypeUser:  {"alice": "2000", "carol2": "2003", "root": "0", "erin": "100"},
+		QuotaTypeGroup: {"staff": "50"},
+	}
+	lookupFn := func(t QuotaType, name string) (string, error) {
+		if id, ok := ids[t][name]; ok {
+			return id, nil
+		}
+		return "", errors.New("unknown " + name)
+	}
+	protectedFn := func(t QuotaType, id string) bool {
+		return t == QuotaTypeUser && id == "100"
+	}
+
+	result := importChanges(export, "/home", mapping, true, lookupFn, protectedFn)
+	require.Len(t, result.Unresolved, 3)
+	assert.EqualValues(t, export.Entries[1], result.Unresolved[0].Entry)
+	assert.EqualError(t, result.Unresolved[0].Err, "unknown bob")
+	assert.EqualValues(t, export.Entries[7], result.Unresolved[1].Entry)
+	assert.EqualValues(t, errImportDefaultsID, result.Unresolved[1].Err)
+	assert.EqualValues(t, export.Entries[8], result.Unresolved[2].Entry)
+	assert.EqualError(t, result.Unresolved[2].Err, "ID 100 is protected by the policy")
+
+	require.Len(t, result.Defaults, 1)
+	assert.EqualValues(t, 100, result.Defaults[QuotaTypeGroup].Files.GetHard())
+	_, _, ok := result.Defaults[QuotaTypeGroup].Bytes.getValues()
+	assert.True(t, ok)
+
+	var resolved []string
+	for _, change := range result.Changes {
+		assert.EqualValues(t, "/home", change.Path)
+		resolved = append(resolved, change.Type.String()+":"+change.ID)
+	}
+	assert.EqualValues(t, []string{"user:2000", "user:1002", "user:2003", "group:50", "project:20", "user:5000"}, resolved)
+
+	assert.EqualValues(t, 2000, result.Changes[0].Limits.Bytes.GetHard())
+	assert.EqualValues(t, 10, result.Changes[1].Limits.Files.GetHard())
+	_, _, ok = result.Changes[2].Limits.Bytes.getValues()
+	assert.True(t, ok)
+
+	// Nameless entries are only applied to the same ID if kept, mapped ones are always applied
+	result = importChanges(export, "/home", mapping, false, lookupFn, protectedFn)
+	require.Len(t, result.Unresolved, 4)
+	assert.EqualValues(t, export.Entries[2], result.Unresolved[1].Entry)
+	assert.EqualValues(t, errImportNameless, result.Unresolved[1].Err)
+
+	resolved = nil
+	for _, change := range result.Changes {
+		resolved = append(resolved, change.Type.String()+":"+change.ID)
+	}
+	assert.EqualValues(t, []string{"user:2000", "user:2003", "group:50", "project:20", "user:5000"}, resolved)
+}
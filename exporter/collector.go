@@ -0,0 +1,204 @@
+// Package exporter exposes filesystem quotas as Prometheus metrics
+package exporter
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "fsquota"
+
+// IDFilter selects the IDs exported. IDs within an exclude range are never exported;
+// if include ranges are set, only IDs within one of them are exported.
+type IDFilter struct {
+	Include []fsquota.IDRange
+	Exclude []fsquota.IDRange
+}
+
+func inRanges(ranges []fsquota.IDRange, id uint32) bool {
+	for _, r := range ranges {
+		if id >= r.Min && id <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the given ID passes the filter. Non-numeric IDs only pass unfiltered.
+func (f *IDFilter) Matches(id string) bool {
+	if f == nil || (len(f.Include) == 0 && len(f.Exclude) == 0) {
+		return true
+	}
+
+	parsed, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return false
+	}
+
+	if inRanges(f.Exclude, uint32(parsed)) {
+		return false
+	}
+
+	return len(f.Include) == 0 || inRanges(f.Include, uint32(parsed))
+}
+
+// Options configures a Collector
+type Options struct {
+	// Paths of the filesystems collected
+	Paths []string
+	// ResolveNames adds a name label with the user or group name of each ID
+	ResolveNames bool
+	// Filter selects the IDs exported
+	Filter *IDFilter
+}
+
+// reportFn retrieves the report of a quota type. A nil report without error indicates that the
+// quota type is not enabled.
+type reportFn func(path string, quotaType fsquota.QuotaType) (*fsquota.Report, error)
+
+// nameFn resolves an ID to a name
+type nameFn func(quotaType fsquota.QuotaType, id string) string
+
+// Collector collects quota usage and limits of a set of filesystems
+type Collector struct {
+	paths        []string
+	resolveNames bool
+	filter       *IDFilter
+	reportFn     reportFn
+	nameFn       nameFn
+
+	bytesUsed         *prometheus.Desc
+	bytesSoft         *prometheus.Desc
+	bytesHard         *prometheus.Desc
+	bytesGraceExpires *prometheus.Desc
+	filesUsed         *prometheus.Desc
+	filesSoft         *prometheus.Desc
+	filesHard         *prometheus.Desc
+	filesGraceExpires *prometheus.Desc
+	scrapeError       *prometheus.Desc
+}
+
+// NewCollector creates a collector configured by opts
+func NewCollector(opts Options) *Collector {
+	return newCollector(opts, fsquota.GetEnabledReport, fsquota.LookupQuotaName)
+}
+
+func newCollector(opts Options, reportFn reportFn, nameFn nameFn) *Collector {
+	labels := []string{"mountpoint", "type", "id"}
+	if opts.ResolveNames {
+		labels = append(labels, "name")
+	}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil)
+	}
+
+	return &Collector{
+		paths:        opts.Paths,
+		resolveNames: opts.ResolveNames,
+		filter:       opts.Filter,
+		reportFn:     reportFn,
+		nameFn:       nameFn,
+
+		bytesUsed:         desc("used_bytes", "Bytes used"),
+		bytesSoft:         desc("soft_limit_bytes", "Soft limit on bytes, 0 if unlimited"),
+		bytesHard:         desc("hard_limit_bytes", "Hard limit on bytes, 0 if unlimited"),
+		bytesGraceExpires: desc("bytes_grace_expiry_timestamp_seconds", "Time the byte grace period expires at, only present while it runs"),
+		filesUsed:         desc("used_files", "Inodes used"),
+		filesSoft:         desc("soft_limit_files", "Soft limit on inodes, 0 if unlimited"),
+		filesHard:         desc("hard_limit_files", "Hard limit on inodes, 0 if unlimited"),
+		filesGraceExpires: desc("files_grace_expiry_timestamp_seconds", "Time the inode grace period expires at, only present while it runs"),
+		scrapeError: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "scrape_error"),
+			"1 if retrieving the quota report failed, 0 otherwise", []string{"mountpoint", "type"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.bytesUsed, c.bytesSoft, c.bytesHard, c.bytesGraceExpires,
+		c.filesUsed, c.filesSoft, c.filesHard, c.filesGraceExpires,
+		c.scrapeError,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	// Names are only cached for the duration of a scrape, so renamed users and groups are picked up
+	names := make(map[fsquota.QuotaType]map[string]string)
+
+	for _, path := range c.paths {
+		for _, t := range fsquota.QuotaTypes() {
+			report, err := c.reportFn(path, t)
+
+			scrapeError := 0.0
+			if err != nil {
+				scrapeError = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError, path, t.String())
+
+			if report != nil {
+				c.collectReport(ch, path, t, report, names)
+			}
+		}
+	}
+}
+
+func (c *Collector) collectReport(ch chan<- prometheus.Metric, path string, t fsquota.QuotaType, report *fsquota.Report,
+	names map[fsquota.QuotaType]map[string]string) {
+	ids := make([]string, 0, len(report.Infos))
+	for id := range report.Infos {
+		if c.filter.Matches(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		info := report.Infos[id]
+
+		labels := []string{path, t.String(), id}
+		if c.resolveNames {
+			labels = append(labels, c.name(names, t, id))
+		}
+
+		gauge := func(desc *prometheus.Desc, value float64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+		}
+
+		gauge(c.bytesUsed, float64(info.BytesUsed))
+		gauge(c.bytesSoft, float64(info.Bytes.GetSoft()))
+		gauge(c.bytesHard, float64(info.Bytes.GetHard()))
+		if !info.BytesGraceExpires.IsZero() {
+			gauge(c.bytesGraceExpires, float64(info.BytesGraceExpires.Unix()))
+		}
+
+		gauge(c.filesUsed, float64(info.FilesUsed))
+		gauge(c.filesSoft, float64(info.Files.GetSoft()))
+		gauge(c.filesHard, float64(info.Files.GetHard()))
+		if !info.FilesGraceExpires.IsZero() {
+			gauge(c.filesGraceExpires, float64(info.FilesGraceExpires.Unix()))
+		}
+	}
+}
+
+// name resolves an ID, caching the result in names
+func (c *Collector) name(names map[fsquota.QuotaType]map[string]string, t fsquota.QuotaType, id string) string {
+	typeNames := names[t]
+	if typeNames == nil {
+		typeNames = make(map[string]string)
+		names[t] = typeNames
+	}
+
+	name, ok := typeNames[id]
+	if !ok {
+		name = c.nameFn(t, id)
+		typeNames[id] = name
+	}
+	return name
+}
@@ -0,0 +1,137 @@
+package exporter
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReportFn(path string, quotaType fsquota.QuotaType) (*fsquota.Report, error) {
+	switch quotaType {
+	case fsquota.QuotaTypeUser:
+		info := &fsquota.Info{BytesUsed: 150, FilesUsed: 3}
+		info.Bytes.SetSoft(100)
+		info.Bytes.SetHard(200)
+		info.BytesGraceExpires = time.Unix(2000000, 0)
+		return &fsquota.Report{Infos: map[string]*fsquota.Info{
+			"0":    {},
+			"1000": info,
+			"1001": {BytesUsed: 10},
+		}}, nil
+	case fsquota.QuotaTypeGroup:
+		return nil, errors.New("test error")
+	}
+	return nil, nil
+}
+
+func testNameFn(quotaType fsquota.QuotaType, id string) string {
+	return quotaType.String() + id
+}
+
+// gather collects all metrics of c, keyed by metric name and label values in label name order
+func gather(t *testing.T, c prometheus.Collector) map[string]float64 {
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(c))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			key := []string{family.GetName()}
+			for _, label := range metric.Label {
+				key = append(key, label.GetValue())
+			}
+			values[strings.Join(key, " ")] = metric.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestIDFilter_Matches(t *testing.T) {
+	var filter *IDFilter
+	assert.True(t, filter.Matches("0"))
+	assert.True(t, filter.Matches("name"))
+
+	filter = &IDFilter{Exclude: []fsquota.IDRange{{Min: 0, Max: 999}}}
+	assert.False(t, filter.Matches("0"))
+	assert.True(t, filter.Matches("1000"))
+	assert.False(t, filter.Matches("name"))
+
+	filter.Include = []fsquota.IDRange{{Min: 500, Max: 1000}}
+	assert.False(t, filter.Matches("600"))
+	assert.True(t, filter.Matches("1000"))
+	assert.False(t, filter.Matches("1001"))
+}
+
+func TestCollector(t *testing.T) {
+	c := newCollector(Options{
+		Paths:  []string{"/srv"},
+		Filter: &IDFilter{Exclude: []fsquota.IDRange{{Min: 0, Max: 0}}},
+	}, testReportFn, testNameFn)
+
+	values := gather(t, c)
+	assert.EqualValues(t, 150, values["fsquota_used_bytes 1000 /srv user"])
+	assert.EqualValues(t, 100, values["fsquota_soft_limit_bytes 1000 /srv user"])
+	assert.EqualValues(t, 200, values["fsquota_hard_limit_bytes 1000 /srv user"])
+	assert.EqualValues(t, 3, values["fsquota_used_files 1000 /srv user"])
+	assert.EqualValues(t, 2000000, values["fsquota_bytes_grace_expiry_timestamp_seconds 1000 /srv user"])
+	assert.EqualValues(t, 10, values["fsquota_used_bytes 1001 /srv user"])
+	assert.EqualValues(t, 0, values["fsquota_scrape_error /srv user"])
+	assert.EqualValues(t, 1, values["fsquota_scrape_error /srv group"])
+	assert.EqualValues(t, 0, values["fsquota_scrape_error /srv project"])
+
+	// Grace deadlines are only exported while running, filtered IDs not at all
+	assert.NotContains(t, values, "fsquota_bytes_grace_expiry_timestamp_seconds 1001 /srv user")
+	assert.NotContains(t, values, "fsquota_used_bytes 0 /srv user")
+	assert.Len(t, values, 16)
+}
+
+func TestCollector_ResolveNames(t *testing.T) {
+	c := newCollector(Options{
+		Paths:        []string{"/srv"},
+		ResolveNames: true,
+	}, testReportFn, testNameFn)
+
+	values := gather(t, c)
+	assert.EqualValues(t, 150, values["fsquota_used_bytes 1000 /srv user1000 user"])
+	assert.EqualValues(t, 0, values["fsquota_used_bytes 0 /srv user0 user"])
+
+	// Names are resolved again on the next scrape
+	c.nameFn = func(quotaType fsquota.QuotaType, id string) string {
+		return "renamed" + id
+	}
+	values = gather(t, c)
+	assert.EqualValues(t, 150, values["fsquota_used_bytes 1000 /srv renamed1000 user"])
+}
+
+func TestWriteTextfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsquota-exporter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(newCollector(Options{Paths: []string{"/srv"}}, testReportFn, testNameFn)))
+
+	filename := filepath.Join(dir, "fsquota.prom")
+	require.NoError(t, WriteTextfile(filename, registry))
+
+	data, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `fsquota_used_bytes{id="1000",mountpoint="/srv",type="user"} 150`)
+
+	// Only the textfile itself remains
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// WriteTextfile writes the metrics gathered by gatherer to filename in the text format read by the
+// node_exporter textfile collector. The file is replaced atomically so partial output is never read.
+func WriteTextfile(filename string, gatherer prometheus.Gatherer) (err error) {
+	var families []*dto.MetricFamily
+	if families, err = gatherer.Gather(); err != nil {
+		return
+	}
+
+	var tmp *os.File
+	if tmp, err = ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	for _, family := range families {
+		if _, err = expfmt.MetricFamilyToText(tmp, family); err != nil {
+			return
+		}
+	}
+
+	if err = tmp.Chmod(0644); err != nil {
+		return
+	}
+
+	if err = tmp.Close(); err != nil {
+		return
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
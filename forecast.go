@@ -0,0 +1,148 @@
+package fsquota
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ResourceForecast predicts when the usage of a resource crosses its limits
+type ResourceForecast struct {
+	// Usage at the time of the latest sample
+	Used uint64
+	// Growth per day estimated from the history
+	GrowthPerDay float64
+
+	// Times the soft and hard limit are expected to be reached.
+	// If a limit is already exceeded, the time of the latest sample is used.
+	// Zero if no limit is set or usage is not growing towards it.
+	SoftLimitReached time.Time
+	HardLimitReached time.Time
+}
+
+// Forecast predicts when an ID reaches its limits
+type Forecast struct {
+	Type QuotaType
+	ID   string
+
+	Bytes ResourceForecast
+	Files ResourceForecast
+}
+
+// usageSample is the usage of a resource at a point in time
+type usageSample struct {
+	at   time.Time
+	used uint64
+}
+
+// growthPerDay estimates the growth of samples using a least squares fit
+func growthPerDay(samples []usageSample) (growth float64, ok bool) {
+	if len(samples) < 2 {
+		return
+	}
+
+	origin := samples[0].at
+	var sumX, sumY, sumXY, sumXX float64
+	for _, sample := range samples {
+		x := sample.at.Sub(origin).Hours() / 24
+		y := float64(sample.used)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		// All samples were taken at the same time
+		return
+	}
+
+	growth = (n*sumXY - sumX*sumY) / denominator
+	ok = true
+	return
+}
+
+// limitReached predicts when a limit is reached, starting from the latest sample
+func limitReached(latest usageSample, growth float64, limit uint64) (reached time.Time) {
+	switch {
+	case limit == 0:
+	case latest.used >= limit:
+		reached = latest.at
+	case growth > 0:
+		days := float64(limit-latest.used) / growth
+		// Avoid overflowing time.Duration for negligible growth
+		if days < math.MaxInt64/float64(24*time.Hour) {
+			reached = latest.at.Add(time.Duration(days * float64(24*time.Hour)))
+		}
+	}
+	return
+}
+
+func forecastResource(samples []usageSample, soft, hard uint64) (forecast ResourceForecast) {
+	latest := samples[len(samples)-1]
+	forecast.Used = latest.used
+	forecast.GrowthPerDay, _ = growthPerDay(samples)
+	forecast.SoftLimitReached = limitReached(latest, forecast.GrowthPerDay, soft)
+	forecast.HardLimitReached = limitReached(latest, forecast.GrowthPerDay, hard)
+	return
+}
+
+// ForecastUsage predicts when each ID present in current reaches its limits, based on the growth over the history
+// records followed by current. The limits of current are used. Records must be ordered by time.
+func ForecastUsage(history []*UsageRecord, current *Snapshot) (forecasts []*Forecast) {
+	if current == nil {
+		return
+	}
+
+	for t, entries := range current.Reports {
+		for id, entry := range entries {
+			var bytesSamples, filesSamples []usageSample
+			for _, record := range history {
+				usage, ok := record.Usage[t]
+				if !ok || !record.Timestamp.Before(current.Timestamp) {
+					continue
+				}
+
+				// IDs without usage are omitted from records
+				sample := usage[id]
+				if sample == nil {
+					sample = &UsageEntry{}
+				}
+				bytesSamples = append(bytesSamples, usageSample{record.Timestamp, sample.BytesUsed})
+				filesSamples = append(filesSamples, usageSample{record.Timestamp, sample.FilesUsed})
+			}
+			bytesSamples = append(bytesSamples, usageSample{current.Timestamp, entry.BytesUsed})
+			filesSamples = append(filesSamples, usageSample{current.Timestamp, entry.FilesUsed})
+
+			forecasts = append(forecasts, &Forecast{
+				Type:  t,
+				ID:    id,
+				Bytes: forecastResource(bytesSamples, entry.BytesSoft, entry.BytesHard),
+				Files: forecastResource(filesSamples, entry.FilesSoft, entry.FilesHard),
+			})
+		}
+	}
+
+	sort.Slice(forecasts, func(i, j int) bool {
+		if forecasts[i].Type != forecasts[j].Type {
+			return forecasts[i].Type < forecasts[j].Type
+		}
+		a, _ := strconv.ParseUint(forecasts[i].ID, 10, 32)
+		b, _ := strconv.ParseUint(forecasts[j].ID, 10, 32)
+		return a < b
+	})
+	return
+}
+
+// FirstLimitReached returns the earliest time any limit is expected to be reached, zero if none is
+func (f *Forecast) FirstLimitReached() (first time.Time) {
+	for _, t := range []time.Time{f.Bytes.SoftLimitReached, f.Bytes.HardLimitReached, f.Files.SoftLimitReached, f.Files.HardLimitReached} {
+		if !t.IsZero() && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+	return
+}
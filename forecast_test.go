@@ -0,0 +1,87 @@
+package fsquota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrowthPerDay(t *testing.T) {
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	growth, ok := growthPerDay([]usageSample{{start, 100}})
+	assert.False(t, ok)
+
+	growth, ok = growthPerDay([]usageSample{{start, 100}, {start, 200}})
+	assert.False(t, ok)
+
+	growth, ok = growthPerDay([]usageSample{
+		{start, 100},
+		{start.Add(24 * time.Hour), 200},
+		{start.Add(48 * time.Hour), 300},
+	})
+	assert.True(t, ok)
+	assert.InDelta(t, 100, growth, 0.0001)
+}
+
+func TestForecastUsage(t *testing.T) {
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	newRecord := func(day int, usage map[string]*UsageEntry) *UsageRecord {
+		return &UsageRecord{
+			Version:   UsageRecordVersion,
+			Timestamp: start.Add(time.Duration(day) * 24 * time.Hour),
+			Usage:     map[QuotaType]map[string]*UsageEntry{QuotaTypeUser: usage},
+		}
+	}
+
+	history := []*UsageRecord{
+		newRecord(0, map[string]*UsageEntry{
+			"1000": {BytesUsed: 100},
+			"1001": {BytesUsed: 100, FilesUsed: 10},
+		}),
+		newRecord(1, map[string]*UsageEntry{
+			"1000": {BytesUsed: 200},
+			"1001": {BytesUsed: 100, FilesUsed: 5},
+		}),
+	}
+
+	current := &Snapshot{
+		Timestamp: start.Add(2 * 24 * time.Hour),
+		Reports: map[QuotaType]map[string]*SnapshotEntry{
+			QuotaTypeUser: {
+				"1000": {BytesUsed: 300, BytesSoft: 500, BytesHard: 1000},
+				"1001": {BytesUsed: 100, BytesSoft: 50, FilesUsed: 0, FilesHard: 100},
+				"1002": {BytesUsed: 10, BytesHard: 100},
+			},
+		},
+	}
+
+	forecasts := ForecastUsage(history, current)
+	require.Len(t, forecasts, 3)
+
+	growing := forecasts[0]
+	assert.EqualValues(t, "1000", growing.ID)
+	assert.EqualValues(t, 300, growing.Bytes.Used)
+	assert.InDelta(t, 100, growing.Bytes.GrowthPerDay, 0.0001)
+	assert.EqualValues(t, start.Add(4*24*time.Hour), growing.Bytes.SoftLimitReached)
+	assert.EqualValues(t, start.Add(9*24*time.Hour), growing.Bytes.HardLimitReached)
+	assert.True(t, growing.Files.SoftLimitReached.IsZero())
+	assert.EqualValues(t, growing.Bytes.SoftLimitReached, growing.FirstLimitReached())
+
+	exceeded := forecasts[1]
+	assert.EqualValues(t, "1001", exceeded.ID)
+	assert.EqualValues(t, start.Add(2*24*time.Hour), exceeded.Bytes.SoftLimitReached)
+	assert.True(t, exceeded.Bytes.HardLimitReached.IsZero())
+	// Shrinking usage never reaches the limit
+	assert.True(t, exceeded.Files.HardLimitReached.IsZero())
+
+	// IDs missing from records had no usage at the time
+	appeared := forecasts[2]
+	assert.EqualValues(t, "1002", appeared.ID)
+	assert.InDelta(t, 5, appeared.Bytes.GrowthPerDay, 0.0001)
+
+	assert.Nil(t, ForecastUsage(history, nil))
+}
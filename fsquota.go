@@ -1,11 +1,17 @@
 // Package fsquota provides functions for working with filesystem quotas
 package fsquota
 
-import "os/user"
+import (
+	"context"
+	"os/user"
+)
 
-// SetUserQuota configures a user's quota
-func SetUserQuota(path string, user *user.User, limits Limits) (info *Info, err error) {
-	return setUserQuota(path, user, &limits)
+// SetUserQuota configures a user's quota.
+// If only the soft or the hard limit of a resource is set, the other one retains its current value.
+// The limits are validated against the policy installed via SetPolicy, if any, unless overridden via OverridePolicy.
+// With DryRun, the expected result is returned without changing the quota.
+func SetUserQuota(path string, user *user.User, limits Limits, opts ...SetOption) (info *Info, err error) {
+	return setUserQuota(path, user, &limits, opts)
 }
 
 // GetUserInfo retrieves a user's quota information
@@ -18,9 +24,30 @@ func GetUserReport(path string) (report *Report, err error) {
 	return getUserReport(path)
 }
 
+// GetUserInfoAllMounts retrieves a user's quota information from every mounted filesystem with user quotas enabled.
+// Filesystems access is denied to, or which have no quota for the user, are skipped. Other errors are returned keyed by
+// mount point, along with the information retrieved from all other filesystems.
+func GetUserInfoAllMounts(user *user.User) (infos []*MountQuotaInfo, err error) {
+	return getUserInfoAllMounts(user)
+}
+
+// ClearUserQuota removes all limits configured for a user. Users protected by the active policy are rejected,
+// unless overridden via OverridePolicy.
+func ClearUserQuota(path string, user *user.User, opts ...SetOption) (info *Info, err error) {
+	return clearUserQuota(path, user, opts)
+}
+
+// PruneUserQuotas clears the quotas of all users present at the given path which no longer exist in the user database.
+// Users protected by the active policy are skipped, unless overridden via OverridePolicy.
+// Users are looked up via getent, nothing is pruned if the user database cannot be consulted for all of them.
+// The IDs of the pruned users are returned.
+func PruneUserQuotas(path string, opts ...SetOption) (pruned []string, err error) {
+	return pruneUserQuotas(path, opts)
+}
+
 // SetGroupQuota configures a group's quota
-func SetGroupQuota(path string, group *user.Group, limits Limits) (info *Info, err error) {
-	return setGroupQuota(path, group, &limits)
+func SetGroupQuota(path string, group *user.Group, limits Limits, opts ...SetOption) (info *Info, err error) {
+	return setGroupQuota(path, group, &limits, opts)
 }
 
 // GetGroupInfo retrieves a group's quota information
@@ -33,6 +60,61 @@ func GetGroupReport(path string) (report *Report, err error) {
 	return getGroupReport(path)
 }
 
+// GetGroupInfoAllMounts retrieves a group's quota information from every mounted filesystem with group quotas enabled.
+// Filesystems access is denied to, or which have no quota for the group, are skipped. Other errors are returned keyed by
+// mount point, along with the information retrieved from all other filesystems.
+func GetGroupInfoAllMounts(group *user.Group) (infos []*MountQuotaInfo, err error) {
+	return getGroupInfoAllMounts(group)
+}
+
+// ClearGroupQuota removes all limits configured for a group. Groups protected by the active policy are rejected,
+// unless overridden via OverridePolicy.
+func ClearGroupQuota(path string, group *user.Group, opts ...SetOption) (info *Info, err error) {
+	return clearGroupQuota(path, group, opts)
+}
+
+// PruneGroupQuotas clears the quotas of all groups present at the given path which no longer exist in the group database.
+// Groups protected by the active policy are skipped, unless overridden via OverridePolicy.
+// Groups are looked up via getent, nothing is pruned if the group database cannot be consulted for all of them.
+// The IDs of the pruned groups are returned.
+func PruneGroupQuotas(path string, opts ...SetOption) (pruned []string, err error) {
+	return pruneGroupQuotas(path, opts)
+}
+
+// SetProjectQuota configures a project's quota
+func SetProjectQuota(path string, projectID uint32, limits *Limits, opts ...SetOption) (info *Info, err error) {
+	return setProjectQuota(path, projectID, limits, opts)
+}
+
+// GetProjectInfo retrieves a project's quota information
+func GetProjectInfo(path string, projectID uint32) (info *Info, err error) {
+	return getProjectInfo(path, projectID)
+}
+
+// GetProjectReport retrieves a report of all project quotas present at the given path
+func GetProjectReport(path string) (report *Report, err error) {
+	return getProjectReport(path)
+}
+
+// ClearProjectQuota removes all limits configured for a project. Projects protected by the active policy are
+// rejected, unless overridden via OverridePolicy.
+func ClearProjectQuota(path string, projectID uint32, opts ...SetOption) (info *Info, err error) {
+	return clearProjectQuota(path, projectID, opts)
+}
+
+// GetDefaultLimits retrieves the default limits the filesystem at the given path applies to IDs without limits of their own.
+// ErrDefaultLimitsNotSupported is returned if the filesystem has no notion of default limits.
+func GetDefaultLimits(path string, quotaType QuotaType) (limits *Limits, err error) {
+	return getDefaultLimits(path, quotaCtlType(quotaType))
+}
+
+// SetDefaultLimits configures the default limits of the filesystem at the given path.
+// On XFS this sets the limits of ID 0, which act as defaults for all IDs without limits of their own.
+// The limits are validated against the active policy for ID 0, unless overridden via OverridePolicy.
+func SetDefaultLimits(path string, quotaType QuotaType, limits *Limits, opts ...SetOption) (result *Limits, err error) {
+	return setDefaultLimits(path, quotaCtlType(quotaType), limits, opts)
+}
+
 // UserQuotasSupported checks if quotas are supported on a given path
 func UserQuotasSupported(path string) (supported bool, err error) {
 	return userQuotasSupported(path)
@@ -42,3 +124,155 @@ func UserQuotasSupported(path string) (supported bool, err error) {
 func GroupQuotasSupported(path string) (supported bool, err error) {
 	return groupQuotasSupported(path)
 }
+
+// WatchQuotaWarnings subscribes to the quota warnings broadcast by the kernel.
+// Warnings are delivered on the watcher's channel, which is closed once ctx is done or receiving fails.
+func WatchQuotaWarnings(ctx context.Context) (watcher *QuotaWarningWatcher, err error) {
+	return watchQuotaWarnings(ctx)
+}
+
+// InspectImage reads the quota state of an unmounted ext4 or XFS filesystem from an image file or block device.
+// The image is opened read-only and never modified.
+func InspectImage(path string) (inspection *ImageInspection, err error) {
+	return inspectImageFile(path)
+}
+
+// CopyQuota applies the limits of the prototype fromID to all of toIDs, validating them against the active policy.
+// The resulting quota information is returned keyed by ID; errors are reported per ID.
+func CopyQuota(path string, quotaType QuotaType, fromID string, toIDs ...string) (infos map[string]*Info, err error) {
+	return CopyQuotaWithOptions(path, quotaType, fromID, toIDs)
+}
+
+// CopyQuotaWithOptions works like CopyQuota, applying opts to every change
+func CopyQuotaWithOptions(path string, quotaType QuotaType, fromID string, toIDs []string, opts ...SetOption) (infos map[string]*Info, err error) {
+	return copyQuota(quotaCtlType(quotaType), path, fromID, toIDs, getQuota, policySetQuota(opts))
+}
+
+// CheckQuotaChanges validates a batch of quota changes against the active policy without applying them.
+// Errors are keyed by the index of the change.
+func CheckQuotaChanges(changes []*QuotaChange, opts ...SetOption) (err error) {
+	return checkQuotaChanges(changes, policyCheck(opts))
+}
+
+// ApplyQuotaChanges applies a batch of quota changes.
+// All changes are validated and the previous limits of every affected ID are recorded before any change is applied.
+// If a change fails to apply, the recorded limits are restored, so the batch is either applied completely or not at all.
+// Changes applied without an audit record do not fail the batch and are reported by an *AuditError.
+// Errors are keyed by the index of the change.
+func ApplyQuotaChanges(changes []*QuotaChange, opts ...SetOption) (err error) {
+	return applyQuotaChanges(changes, getQuota, optionsSetQuota(opts), policyCheck(opts))
+}
+
+// PlanReconcile compares the desired state with the quotas currently configured and returns the steps required
+// to reach the desired state. With prune set, limits of IDs not declared are removed, except for IDs protected by the
+// active policy unless overridden via OverridePolicy.
+// Quota types without any declarations are left untouched.
+func PlanReconcile(state *DesiredState, prune bool, opts ...SetOption) (plan *ReconcilePlan, err error) {
+	return planReconcileState(state, prune, opts)
+}
+
+// ApplyReconcilePlan applies the steps of a plan as a single batch, see ApplyQuotaChanges
+func ApplyReconcilePlan(plan *ReconcilePlan, opts ...SetOption) (err error) {
+	return applyReconcilePlan(plan, opts)
+}
+
+// AssignQuotaPlans resolves every user of the user database against rules, without applying any limits.
+// The first matching rule determines a user's plan; users not matching any rule are not returned.
+// Users which cannot be looked up are reported via an *UnresolvedUsersError, along with the assignments of all
+// other users.
+func AssignQuotaPlans(plans []*QuotaPlan, rules []*AssignmentRule) (assignments []*PlanAssignment, err error) {
+	_, assignments, err = assignQuotaPlans(plans, rules)
+	return
+}
+
+// ApplyQuotaPlans resolves every user of the user database against rules and applies the limits of the matching plan
+// as a single batch, see ApplyQuotaChanges.
+// Users which cannot be looked up are skipped and reported via an *UnresolvedUsersError once all other users have been
+// updated.
+func ApplyQuotaPlans(plans []*QuotaPlan, rules []*AssignmentRule, opts ...SetOption) (assignments []*PlanAssignment, err error) {
+	return applyQuotaPlans(plans, rules, opts)
+}
+
+// ResolveLimits computes the absolute limits described by change for an ID.
+// Percentages are resolved against the capacity of the filesystem at path, deltas against the ID's current limits.
+// Limits not part of change are left unset.
+func ResolveLimits(path string, quotaType QuotaType, id string, change *LimitChange) (limits *Limits, err error) {
+	return resolveLimits(path, quotaCtlType(quotaType), id, change)
+}
+
+// AnalyzeCapacity compares the user, group and project quotas configured on the filesystem at path with its capacity
+func AnalyzeCapacity(path string) (analysis *CapacityAnalysis, err error) {
+	return analyzeCapacity(path)
+}
+
+// TakeSnapshot records the reports of all quota types enabled on the filesystem at path
+func TakeSnapshot(path string) (snapshot *Snapshot, err error) {
+	return takeSnapshot(path)
+}
+
+// ExportQuotas records the default limits and the limits of all quota types enabled on the filesystem at path, keyed
+// by the names of the users, groups and projects. IDs without limits and ID 0 are omitted.
+func ExportQuotas(path string) (export *Export, err error) {
+	return exportQuotas(path)
+}
+
+// ResolveImport maps the entries of an export to changes of the filesystem at path without applying them.
+// Entries are resolved to IDs by name, using mapping to rename them. Entries exported without a name are only applied
+// to the same ID if they are mapped or keepIDs is set, they are returned as unresolved otherwise. Entries mapping to
+// ID 0 or to IDs protected by the policy are returned as unresolved, unless the policy is overridden via opts.
+func ResolveImport(export *Export, path string, mapping ImportMapping, keepIDs bool, opts ...SetOption) (result *ImportResult) {
+	return resolveImport(export, path, mapping, keepIDs, opts)
+}
+
+// ImportDefaults sets the default limits of a resolved import on the filesystem at path, see SetDefaultLimits.
+// Quota types the filesystem has no configurable default limits for are skipped and returned with the reason.
+// Errors are keyed by "defaults:<type>".
+func ImportDefaults(result *ImportResult, path string, opts ...SetOption) (skipped map[QuotaType]error, err error) {
+	return importDefaults(result, path, opts)
+}
+
+// ImportQuotas applies the limits of an export to the filesystem at path as a single batch, see ApplyQuotaChanges,
+// followed by its default limits, see ImportDefaults. Default limits the filesystem does not support are skipped.
+// Entries are resolved as by ResolveImport, entries which cannot be resolved are skipped and returned as unresolved.
+func ImportQuotas(export *Export, path string, mapping ImportMapping, keepIDs bool, opts ...SetOption) (result *ImportResult, err error) {
+	return importQuotas(export, path, mapping, keepIDs, opts)
+}
+
+// MountPoint returns the mount point of the filesystem path resides on, after resolving symlinks.
+// Filesystems mounted more than once are identified by the first of their mount points.
+func MountPoint(path string) (mountPoint string, err error) {
+	return mountPointForPath(path)
+}
+
+// GetReport retrieves a report of all quotas of the given type present at the given path
+func GetReport(path string, quotaType QuotaType) (report *Report, err error) {
+	return getTypeReport(path, quotaCtlType(quotaType))
+}
+
+// GetEnabledReport retrieves a report like GetReport, but returns a nil report without error if quotas of the given
+// type are not enabled at the given path
+func GetEnabledReport(path string, quotaType QuotaType) (report *Report, err error) {
+	return getEnabledReport(path, quotaCtlType(quotaType))
+}
+
+// LookupQuotaName returns the name of the user, group or project of an ID on this host, empty if it has none
+func LookupQuotaName(quotaType QuotaType, id string) string {
+	return lookupQuotaName(quotaType, id)
+}
+
+// QuotaMountPoints lists the mount points of all local filesystems with quotas of the given type enabled
+func QuotaMountPoints(quotaType QuotaType) (mountPoints []string, err error) {
+	return quotaMountPoints(quotaCtlType(quotaType))
+}
+
+// ProjectQuotasSupported checks if project quotas are supported on a given path
+func ProjectQuotasSupported(path string) (supported bool, err error) {
+	return projectQuotasSupported(path)
+}
+
+// UndoChange restores the limits an audited change replaced.
+// ErrChangeSuperseded is returned if the limits have been changed again since, unless overridden via UndoSuperseded.
+// The restored limits are validated against the active policy, unless overridden via OverridePolicy.
+func UndoChange(record *AuditRecord, opts ...SetOption) (info *Info, err error) {
+	return undoChange(record, opts)
+}
@@ -17,6 +17,15 @@ import (
 )
 
 func getQuota(t quotaCtlType, path string, idString string) (info *Info, err error) {
+	// NFS mounts are handled via the rquota protocol
+	if remote, lookupErr := lookupNFSMount(path); lookupErr == nil && remote != nil {
+		var id uint32
+		if id, err = parseID(idString); err != nil {
+			return
+		}
+		return remote.getQuota(t, id)
+	}
+
 	var device string
 	var id uint32
 	if device, id, err = prepareArguments(path, idString); err != nil {
@@ -37,7 +46,105 @@ func internalGetQuota(t quotaCtlType, device string, id uint32) (info *Info, err
 	return
 }
 
+// completeLimits returns limits retaining the current value of soft or hard limits not provided.
+// With all set, byte or file limits not provided at all are retained as well, as required by rquota, which always
+// sets all limits. IDs without quota on an NFS server are treated as having no limits.
+func completeLimits(t quotaCtlType, path string, idString string, limits *Limits, getFn getQuotaFn, all bool) (completed *Limits, err error) {
+	_, _, haveBytesLimits := limits.Bytes.getValues()
+	_, _, haveFilesLimits := limits.Files.getValues()
+	missing := all && (!haveBytesLimits || !haveFilesLimits)
+
+	if !missing && !limits.Bytes.partial() && !limits.Files.partial() {
+		completed = limits
+		return
+	}
+
+	var current *Info
+	if current, err = getFn(t, path, idString); err != nil && err != ErrRquotaNoQuota {
+		return
+	}
+	err = nil
+
+	if current == nil {
+		current = &Info{}
+	}
+
+	completed = &Limits{}
+	completed.Bytes.set(limits.Bytes.completedFrom(&current.Bytes))
+	completed.Files.set(limits.Files.completedFrom(&current.Files))
+	if all && !haveBytesLimits {
+		completed.Bytes.set(&current.Bytes)
+	}
+	if all && !haveFilesLimits {
+		completed.Files.set(&current.Files)
+	}
+	return
+}
+
 func setQuota(t quotaCtlType, path string, idString string, limits *Limits) (info *Info, err error) {
+	if finishAudit := beginAudit(AuditOperationSet, t, path, idString); finishAudit != nil {
+		defer func() {
+			if err == nil {
+				err = finishAudit(info)
+			}
+		}()
+	}
+
+	// NFS mounts are handled via the rquota protocol
+	remote, lookupErr := lookupNFSMount(path)
+	isRemote := lookupErr == nil && remote != nil
+
+	if limits, err = completeLimits(t, path, idString, limits, getQuota, isRemote); err != nil {
+		return
+	}
+
+	if isRemote {
+		var id uint32
+		if id, err = parseID(idString); err != nil {
+			return
+		}
+		return remote.setQuota(t, id, limits)
+	}
+
+	var device string
+	var id uint32
+
+	if device, id, err = prepareArguments(path, idString); err != nil {
+		return
+	}
+
+	quotaInfoStruct := dqblkFromLimits(limits)
+
+	if err = quotactl(cmdSetQuota, t, device, id, unsafe.Pointer(quotaInfoStruct)); err != nil {
+		return
+	}
+
+	info, err = internalGetQuota(t, device, id)
+	return
+}
+
+// clearQuota resets all limits and grace times of an ID.
+// Filesystems drop quota entries without limits and usage, removing them from reports.
+func clearQuota(t quotaCtlType, path string, idString string) (info *Info, err error) {
+	if finishAudit := beginAudit(AuditOperationClear, t, path, idString); finishAudit != nil {
+		defer func() {
+			if err == nil {
+				err = finishAudit(info)
+			}
+		}()
+	}
+
+	limits := clearLimits()
+
+	// NFS mounts are handled via the rquota protocol
+	if remote, lookupErr := lookupNFSMount(path); lookupErr == nil && remote != nil {
+		var id uint32
+		if id, err = parseID(idString); err != nil {
+			return
+		}
+		return remote.setQuota(t, id, limits)
+	}
+
 	var device string
 	var id uint32
 
@@ -46,17 +153,19 @@ func setQuota(t quotaCtlType, path string, idString string, limits *Limits) (inf
 	}
 
 	quotaInfoStruct := dqblkFromLimits(limits)
+	// Reset grace times as well
+	quotaInfoStruct.dqbValid |= qifBTime | qifITime
 
 	if err = quotactl(cmdSetQuota, t, device, id, unsafe.Pointer(quotaInfoStruct)); err != nil {
 		return
 	}
 
-	info, err = internalGetQuota(userQuota, device, id)
+	info, err = internalGetQuota(t, device, id)
 	return
 }
 
-func setUserQuota(path string, usr *user.User, limits *Limits) (info *Info, err error) {
-	return setQuota(userQuota, path, usr.Uid, limits)
+func setUserQuota(path string, usr *user.User, limits *Limits, opts []SetOption) (info *Info, err error) {
+	return policySetQuota(opts)(userQuota, path, usr.Uid, limits)
 }
 
 func getUserInfo(path string, user *user.User) (info *Info, err error) {
@@ -64,14 +173,34 @@ func getUserInfo(path string, user *user.User) (info *Info, err error) {
 	return
 }
 
-func setGroupQuota(path string, group *user.Group, limits *Limits) (info *Info, err error) {
-	return setQuota(groupQuota, path, group.Gid, limits)
+func setGroupQuota(path string, group *user.Group, limits *Limits, opts []SetOption) (info *Info, err error) {
+	return policySetQuota(opts)(groupQuota, path, group.Gid, limits)
 }
 
 func getGroupInfo(path string, group *user.Group) (info *Info, err error) {
 	return getQuota(groupQuota, path, group.Gid)
 }
 
+func clearUserQuota(path string, user *user.User, opts []SetOption) (info *Info, err error) {
+	return policyClearQuota(opts)(userQuota, path, user.Uid)
+}
+
+func clearGroupQuota(path string, group *user.Group, opts []SetOption) (info *Info, err error) {
+	return policyClearQuota(opts)(groupQuota, path, group.Gid)
+}
+
+func setProjectQuota(path string, projectID uint32, limits *Limits, opts []SetOption) (info *Info, err error) {
+	return policySetQuota(opts)(projectQuota, path, fmt.Sprint(projectID), limits)
+}
+
+func getProjectInfo(path string, projectID uint32) (info *Info, err error) {
+	return getQuota(projectQuota, path, fmt.Sprint(projectID))
+}
+
+func clearProjectQuota(path string, projectID uint32, opts []SetOption) (info *Info, err error) {
+	return policyClearQuota(opts)(projectQuota, path, fmt.Sprint(projectID))
+}
+
 func pathToDevice(path string) (device string, err error) {
 	if path, err = filepath.EvalSymlinks(path); err != nil {
 		// Evaluate symlinks first
@@ -128,6 +257,59 @@ func pathToDevice(path string) (device string, err error) {
 	return
 }
 
+// mountPointForPath returns the mount point of the filesystem path resides on
+func mountPointForPath(path string) (mountPoint string, err error) {
+	var info *mount.Info
+	if info, err = mountInfoForPath(path); err != nil {
+		return
+	}
+	mountPoint = info.Mountpoint
+	return
+}
+
+// mountInfoForPath looks up the mount the given path resides on
+func mountInfoForPath(path string) (info *mount.Info, err error) {
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return
+	}
+
+	var statRes os.FileInfo
+	if statRes, err = os.Stat(path); err != nil {
+		return
+	}
+
+	var statT *syscall.Stat_t
+	var statTOK bool
+	if statT, statTOK = statRes.Sys().(*syscall.Stat_t); !statTOK {
+		err = errors.New("internal error: could not retrieve Stat_t from stat result")
+		return
+	}
+
+	// Devices are looked up by the device they represent rather than the one they reside on
+	dev := statT.Dev
+	if statRes.Mode()&os.ModeDevice != 0 && statRes.Mode()&os.ModeCharDevice == 0 {
+		dev = statT.Rdev
+	}
+
+	devMajor := unix.Major(dev)
+	devMinor := unix.Minor(dev)
+
+	var mountInfos []*mount.Info
+	if mountInfos, err = mount.GetMounts(); err != nil {
+		return
+	}
+
+	for _, mountInfo := range mountInfos {
+		if uint32(mountInfo.Major) == devMajor && uint32(mountInfo.Minor) == devMinor {
+			info = mountInfo
+			return
+		}
+	}
+	err = errors.New("unable to find mount point for path")
+
+	return
+}
+
 func prepareArguments(path string, idString string) (device string, id uint32, err error) {
 	// Look up the device beneath the provided path
 	var pathErr error
@@ -136,9 +318,17 @@ func prepareArguments(path string, idString string) (device string, id uint32, e
 	}
 
 	// Convert ID string to uint32
-	if id64, parseErr := strconv.ParseUint(idString, 10, 32); parseErr != nil {
+	var parseErr error
+	if id, parseErr = parseID(idString); parseErr != nil {
 		err = errortree.Add(err, "id", parseErr)
-	} else {
+	}
+
+	return
+}
+
+func parseID(idString string) (id uint32, err error) {
+	var id64 uint64
+	if id64, err = strconv.ParseUint(idString, 10, 32); err == nil {
 		id = uint32(id64)
 	}
 
@@ -168,6 +358,10 @@ func getReport(path string, typ quotaCtlType, idLookupFn reportLegacyIDLookupFn)
 		report, err = getReportByNextQuota(typ, device)
 	}
 
+	if err == nil {
+		markInheritedDefaults(report, path, typ)
+	}
+
 	return
 }
 
@@ -183,6 +377,35 @@ func getGroupReport(path string) (report *Report, err error) {
 	})
 }
 
+func getProjectReport(path string) (report *Report, err error) {
+	return getReport(path, projectQuota, func() ([]uint32, error) {
+		return getIDsFromProjidFile(projidFile)
+	})
+}
+
+// getTypeReport retrieves the report of a quota type
+func getTypeReport(path string, t quotaCtlType) (report *Report, err error) {
+	switch t {
+	case userQuota:
+		return getUserReport(path)
+	case groupQuota:
+		return getGroupReport(path)
+	case projectQuota:
+		return getProjectReport(path)
+	}
+
+	err = fmt.Errorf("unknown quota type %d", uint32(t))
+	return
+}
+
+// getEnabledReport retrieves the report of a quota type, nil if the quota type is not enabled on the filesystem
+func getEnabledReport(path string, t quotaCtlType) (report *Report, err error) {
+	if supported, _ := quotasSupported(t, path); !supported {
+		return
+	}
+	return getTypeReport(path, t)
+}
+
 type nextdqblk struct {
 	dqbBHardlimit uint64
 	dqbBSoftlimit uint64
@@ -272,13 +495,16 @@ func getReportLegacy(t quotaCtlType, device string, idLookupFn reportLegacyIDLoo
 	return
 }
 
+// quotasSupported checks whether quotas of type t are enabled on the filesystem of path.
+// Unlike retrieving a quota, Q_GETINFO does not require CAP_SYS_ADMIN, so this works for unprivileged callers.
 func quotasSupported(t quotaCtlType, path string) (supported bool, err error) {
 	var device string
 	if device, _, err = prepareArguments(path, "0"); err != nil {
 		return
 	}
 
-	if _, err = internalGetQuota(t, device, 0); err == nil {
+	info := &dqinfo{}
+	if err = quotactl(cmdGetInfo, t, device, 0, unsafe.Pointer(info)); err == nil {
 		supported = true
 	}
 
@@ -292,3 +518,7 @@ func userQuotasSupported(path string) (supported bool, err error) {
 func groupQuotasSupported(path string) (supported bool, err error) {
 	return quotasSupported(groupQuota, path)
 }
+
+func projectQuotasSupported(path string) (supported bool, err error) {
+	return quotasSupported(projectQuota, path)
+}
@@ -0,0 +1,264 @@
+package fsquota
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultHistoryDir is the directory usage history is stored in by default
+const DefaultHistoryDir = "/var/lib/fsqm/history"
+
+// DefaultHistoryRetention is the period usage history is kept for by default
+const DefaultHistoryRetention = 90 * 24 * time.Hour
+
+// UsageRecordVersion is the version of the records kept in the history
+const UsageRecordVersion = 1
+
+// UsageEntry is the usage of a single ID
+type UsageEntry struct {
+	BytesUsed uint64 `json:"bytes_used"`
+	FilesUsed uint64 `json:"files_used"`
+}
+
+// UsageRecord is the usage of all IDs of a filesystem at a point in time
+type UsageRecord struct {
+	Version   int       `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Usage contains the entries of every quota type keyed by ID. IDs without any usage are omitted.
+	Usage map[QuotaType]map[string]*UsageEntry `json:"usage"`
+}
+
+// newUsageRecord extracts the usage of all IDs from a snapshot
+func newUsageRecord(snapshot *Snapshot) *UsageRecord {
+	record := &UsageRecord{
+		Version:   UsageRecordVersion,
+		Timestamp: snapshot.Timestamp,
+		Usage:     make(map[QuotaType]map[string]*UsageEntry, len(snapshot.Reports)),
+	}
+
+	for t, entries := range snapshot.Reports {
+		usage := make(map[string]*UsageEntry)
+		for id, entry := range entries {
+			if entry.BytesUsed != 0 || entry.FilesUsed != 0 {
+				usage[id] = &UsageEntry{
+					BytesUsed: entry.BytesUsed,
+					FilesUsed: entry.FilesUsed,
+				}
+			}
+		}
+		record.Usage[t] = usage
+	}
+
+	return record
+}
+
+// HistoryStore is an append-only store of usage records, keeping one file per filesystem.
+// Records older than the retention period are dropped by compacting the file once they make up a tenth of it.
+type HistoryStore struct {
+	// Dir is the directory the history files are kept in
+	Dir string
+	// Retention is the period records are kept for, zero keeps records forever
+	Retention time.Duration
+
+	// mountPointFn allows replacing the mount point lookup in tests
+	mountPointFn func(path string) (string, error)
+}
+
+// NewHistoryStore creates a history store keeping its files in dir for DefaultHistoryRetention
+func NewHistoryStore(dir string) *HistoryStore {
+	return &HistoryStore{
+		Dir:       dir,
+		Retention: DefaultHistoryRetention,
+	}
+}
+
+// fileName returns the name of the history file of the filesystem path resides on.
+// Files are keyed by mount point, paths which are not mounted anymore are used as given.
+func (s *HistoryStore) fileName(path string) string {
+	mountPointFn := mountPointForPath
+	if s.mountPointFn != nil {
+		mountPointFn = s.mountPointFn
+	}
+
+	if mountPoint, err := mountPointFn(path); err == nil {
+		path = mountPoint
+	}
+	return filepath.Join(s.Dir, url.PathEscape(filepath.Clean(path))+".jsonl")
+}
+
+// lock acquires an exclusive lock on the history of path, serializing appends and compactions.
+// The lock is held on a separate file, as compacting replaces the history file.
+func (s *HistoryStore) lock(path string) (unlock func(), err error) {
+	if err = os.MkdirAll(s.Dir, 0755); err != nil {
+		return
+	}
+
+	var f *os.File
+	if f, err = os.OpenFile(s.fileName(path)+".lock", os.O_RDWR|os.O_CREATE, 0644); err != nil {
+		return
+	}
+
+	if err = unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close()
+		return
+	}
+
+	// Closing the file releases the lock
+	unlock = func() {
+		f.Close()
+	}
+	return
+}
+
+// Append adds the usage recorded by snapshot to the history of its path
+func (s *HistoryStore) Append(snapshot *Snapshot) (err error) {
+	var data []byte
+	if data, err = json.Marshal(newUsageRecord(snapshot)); err != nil {
+		return
+	}
+
+	var unlock func()
+	if unlock, err = s.lock(snapshot.Path); err != nil {
+		return
+	}
+	defer unlock()
+
+	var f *os.File
+	if f, err = os.OpenFile(s.fileName(snapshot.Path), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644); err != nil {
+		return
+	}
+
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	return s.compactExpired(snapshot.Path, snapshot.Timestamp)
+}
+
+// compactExpired compacts the history of path if its oldest record is past the retention period by more than
+// a tenth of it, so files are not rewritten on every append. The caller must hold the lock of path.
+func (s *HistoryStore) compactExpired(path string, now time.Time) (err error) {
+	if s.Retention <= 0 {
+		return
+	}
+
+	var f *os.File
+	if f, err = os.Open(s.fileName(path)); err != nil {
+		return
+	}
+
+	oldest := time.Time{}
+	scanRecords(f, func(record *UsageRecord) bool {
+		oldest = record.Timestamp
+		return false
+	})
+	f.Close()
+
+	if oldest.IsZero() || !oldest.Before(now.Add(-s.Retention-s.Retention/10)) {
+		return
+	}
+	return s.compact(path, now)
+}
+
+// Compact rewrites the history of path, dropping all records older than the retention period before now
+// as well as damaged records
+func (s *HistoryStore) Compact(path string, now time.Time) (err error) {
+	var unlock func()
+	if unlock, err = s.lock(path); err != nil {
+		return
+	}
+	defer unlock()
+
+	return s.compact(path, now)
+}
+
+// compact implements Compact, the caller must hold the lock of path
+func (s *HistoryStore) compact(path string, now time.Time) (err error) {
+	var records []*UsageRecord
+	since := time.Time{}
+	if s.Retention > 0 {
+		since = now.Add(-s.Retention)
+	}
+	if records, err = s.Read(path, since); err != nil {
+		return
+	}
+
+	var f *os.File
+	if f, err = ioutil.TempFile(s.Dir, ".compact-"); err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+
+	writer := bufio.NewWriter(f)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err = encoder.Encode(record); err != nil {
+			f.Close()
+			return
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Chmod(0644); err != nil {
+		f.Close()
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	return os.Rename(f.Name(), s.fileName(path))
+}
+
+// scanRecords calls fn for every record of r in order, until fn returns false.
+// Records damaged by interrupted writes or written by other versions are skipped.
+func scanRecords(r io.Reader, fn func(record *UsageRecord) bool) error {
+	scanner := bufio.NewScanner(r)
+	// Records of large filesystems exceed the default line length
+	scanner.Buffer(make([]byte, 64*1024), 256*1024*1024)
+
+	for scanner.Scan() {
+		record := &UsageRecord{}
+		if decodeErr := json.Unmarshal(scanner.Bytes(), record); decodeErr != nil || record.Version != UsageRecordVersion {
+			continue
+		}
+
+		if !fn(record) {
+			break
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Read returns the records of path taken at or after since, in the order they were appended
+func (s *HistoryStore) Read(path string, since time.Time) (records []*UsageRecord, err error) {
+	var f *os.File
+	if f, err = os.Open(s.fileName(path)); err != nil {
+		return
+	}
+	defer f.Close()
+
+	err = scanRecords(f, func(record *UsageRecord) bool {
+		if !record.Timestamp.Before(since) {
+			records = append(records, record)
+		}
+		return true
+	})
+	return
+}
@@ -0,0 +1,111 @@
+package fsquota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMountPoint treats /home and /srv as mount points
+func testMountPoint(path string) (string, error) {
+	for _, mountPoint := range []string{"/home", "/srv"} {
+		if path == mountPoint || strings.HasPrefix(path, mountPoint+"/") {
+			return mountPoint, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+func TestHistoryStore(t *testing.T) {
+	dirName, err := ioutil.TempDir("", "fsquota-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirName)
+
+	store := NewHistoryStore(filepath.Join(dirName, "history"))
+	store.mountPointFn = testMountPoint
+
+	_, err = store.Read("/home", time.Time{})
+	assert.True(t, os.IsNotExist(err))
+
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day < 3; day++ {
+		require.NoError(t, store.Append(&Snapshot{
+			Version:   SnapshotVersion,
+			Path:      "/home",
+			Timestamp: start.Add(time.Duration(day) * 24 * time.Hour),
+			Reports: map[QuotaType]map[string]*SnapshotEntry{
+				QuotaTypeUser: {
+					"1000": {BytesUsed: uint64(day), BytesHard: 100},
+					"1001": {BytesHard: 100},
+				},
+			},
+		}))
+	}
+	require.NoError(t, store.Append(&Snapshot{Version: SnapshotVersion, Path: "/srv", Timestamp: start}))
+
+	// Damaged lines are skipped
+	f, err := os.OpenFile(store.fileName("/home"), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("{\"version\":1,\"tru")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Paths are resolved to their mount point
+	records, err := store.Read("/home/alice", start.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.EqualValues(t, 1, records[0].Usage[QuotaTypeUser]["1000"].BytesUsed)
+	assert.EqualValues(t, 2, records[1].Usage[QuotaTypeUser]["1000"].BytesUsed)
+	// Only usage is recorded, IDs without usage are omitted
+	assert.NotContains(t, records[1].Usage[QuotaTypeUser], "1001")
+
+	records, err = store.Read("/srv", time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestHistoryStoreRetention(t *testing.T) {
+	dirName, err := ioutil.TempDir("", "fsquota-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirName)
+
+	store := NewHistoryStore(dirName)
+	store.mountPointFn = testMountPoint
+	store.Retention = 10 * 24 * time.Hour
+
+	start := time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)
+	appendDay := func(day int) {
+		require.NoError(t, store.Append(&Snapshot{
+			Path:      "/home",
+			Timestamp: start.Add(time.Duration(day) * 24 * time.Hour),
+		}))
+	}
+
+	// Expired records are kept until they are past the retention period by a tenth of it
+	for day := 0; day <= 11; day++ {
+		appendDay(day)
+	}
+	records, err := store.Read("/home", time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, records, 12)
+
+	appendDay(12)
+	records, err = store.Read("/home", time.Time{})
+	require.NoError(t, err)
+	if assert.Len(t, records, 11) {
+		assert.EqualValues(t, start.Add(2*24*time.Hour), records[0].Timestamp)
+	}
+
+	// Without retention all records are kept
+	store.Retention = 0
+	appendDay(100)
+	records, err = store.Read("/home", time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, records, 12)
+}
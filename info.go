@@ -1,5 +1,7 @@
 package fsquota
 
+import "time"
+
 // Info contains quota information
 type Info struct {
 	Limits
@@ -8,6 +10,34 @@ type Info struct {
 	BytesUsed uint64
 	// File usage
 	FilesUsed uint64
+
+	// BytesGraceExpires is the time the byte soft limit turns into a hard limit.
+	// It is zero unless the soft limit is exceeded.
+	BytesGraceExpires time.Time
+	// FilesGraceExpires is the time the file soft limit turns into a hard limit.
+	// It is zero unless the soft limit is exceeded.
+	FilesGraceExpires time.Time
+
+	// InheritsDefaultLimits indicates the limits of this ID are probably the filesystem's default limits.
+	// It is a guess: XFS copies the defaults into the quota of new IDs, so explicit limits equal to the
+	// defaults can not be told apart from inherited ones. It must not be relied on to discard limits.
+	InheritsDefaultLimits bool
+}
+
+// graceExpiresAt converts a grace deadline in seconds since the epoch, zero if unset
+func graceExpiresAt(seconds uint64) (expires time.Time) {
+	if seconds != 0 {
+		expires = time.Unix(int64(seconds), 0)
+	}
+	return
+}
+
+// graceTimeLeft returns the number of seconds left until expires, zero if unset
+func graceTimeLeft(expires time.Time, now time.Time) uint32 {
+	if expires.IsZero() || !expires.After(now) {
+		return 0
+	}
+	return uint32(expires.Sub(now) / time.Second)
 }
 
 func (i *Info) isEmpty() bool {
@@ -17,3 +47,36 @@ func (i *Info) isEmpty() bool {
 	return bytesSoft == 0 && bytesHard == 0 && i.BytesUsed == 0 &&
 		filesHard == 0 && filesSoft == 0 && i.FilesUsed == 0
 }
+
+// GraceState describes whether a soft limit is exceeded and whether its grace period has expired
+type GraceState string
+
+const (
+	// GraceStateNone indicates the soft limit is not exceeded
+	GraceStateNone GraceState = "none"
+	// GraceStateActive indicates the soft limit is exceeded and the grace period has not expired yet
+	GraceStateActive GraceState = "active"
+	// GraceStateExpired indicates the soft limit is exceeded and the grace period has expired
+	GraceStateExpired GraceState = "expired"
+)
+
+// newGraceState classifies usage against a soft limit at now
+func newGraceState(used, soft uint64, expires time.Time, now time.Time) GraceState {
+	switch over, expired := graceState(used, soft, expires, now); {
+	case expired:
+		return GraceStateExpired
+	case over:
+		return GraceStateActive
+	}
+	return GraceStateNone
+}
+
+// BytesGraceState returns the grace state of the byte limits at now
+func (i *Info) BytesGraceState(now time.Time) GraceState {
+	return newGraceState(i.BytesUsed, i.Bytes.GetSoft(), i.BytesGraceExpires, now)
+}
+
+// FilesGraceState returns the grace state of the file limits at now
+func (i *Info) FilesGraceState(now time.Time) GraceState {
+	return newGraceState(i.FilesUsed, i.Files.GetSoft(), i.FilesGraceExpires, now)
+}
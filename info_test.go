@@ -2,6 +2,7 @@ package fsquota
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -74,3 +75,16 @@ func TestInfoIsEmpty(t *testing.T) {
 		assert.False(t, i.isEmpty())
 	})
 }
+
+func TestInfo_GraceState(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+
+	i := &Info{BytesUsed: 150, FilesUsed: 5, BytesGraceExpires: now.Add(time.Hour)}
+	assert.EqualValues(t, GraceStateNone, i.BytesGraceState(now))
+
+	i.Bytes.SetSoft(100)
+	i.Files.SetSoft(10)
+	assert.EqualValues(t, GraceStateActive, i.BytesGraceState(now))
+	assert.EqualValues(t, GraceStateExpired, i.BytesGraceState(now.Add(time.Hour)))
+	assert.EqualValues(t, GraceStateNone, i.FilesGraceState(now))
+}
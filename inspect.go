@@ -0,0 +1,148 @@
+package fsquota
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// ImageInspection contains the quota-related state of an unmounted filesystem
+type ImageInspection struct {
+	// Filesystem type, either "ext4" or "xfs"
+	Filesystem string
+	// QuotaFeature indicates if the filesystem has quota support enabled
+	QuotaFeature bool
+	// ProjectQuotaFeature indicates if the filesystem supports project quotas
+	ProjectQuotaFeature bool
+	// QuotaInodes maps quota types to the inode numbers of their hidden quota files
+	QuotaInodes map[QuotaType]uint64
+	// QuotaFiles contains the contents of the hidden quota files, if they could be read
+	QuotaFiles map[QuotaType]*QuotaFileInfo
+	// Accounting indicates for which quota types usage accounting is enabled (XFS only)
+	Accounting map[QuotaType]bool
+	// Enforcement indicates for which quota types limits are enforced (XFS only)
+	Enforcement map[QuotaType]bool
+}
+
+// xfsMagic is the XFS superblock magic, "XFSB"
+const xfsMagic = 0x58465342
+
+const (
+	// XFS_UQUOTA_ACCT
+	xfsQuotaFlagUserAcct = 0x0001
+	// XFS_UQUOTA_ENFD
+	xfsQuotaFlagUserEnfd = 0x0002
+	// XFS_PQUOTA_ACCT
+	xfsQuotaFlagProjectAcct = 0x0008
+	// XFS_OQUOTA_ENFD, used for group or project enforcement on v4 superblocks
+	xfsQuotaFlagOtherEnfd = 0x0010
+	// XFS_GQUOTA_ACCT
+	xfsQuotaFlagGroupAcct = 0x0040
+	// XFS_GQUOTA_ENFD
+	xfsQuotaFlagGroupEnfd = 0x0080
+	// XFS_PQUOTA_ENFD
+	xfsQuotaFlagProjectEnfd = 0x0200
+
+	// XFS_SB_VERSION_NUMBITS
+	xfsVersionNumBits = 0x000f
+	// XFS_SB_VERSION_QUOTABIT
+	xfsVersionQuotaBit = 0x0040
+
+	// Size of the XFS superblock fields inspected
+	xfsSuperblockSize = 240
+	// NULLFSINO
+	xfsNullIno = 0xffffffffffffffff
+)
+
+func inspectXFS(b []byte) (inspection *ImageInspection, err error) {
+	be := binary.BigEndian
+
+	if len(b) < xfsSuperblockSize || be.Uint32(b[0:]) != xfsMagic {
+		err = errors.New("not an XFS filesystem")
+		return
+	}
+
+	versionNum := be.Uint16(b[100:])
+	version := versionNum & xfsVersionNumBits
+	qflags := be.Uint16(b[176:])
+
+	insp := &ImageInspection{
+		Filesystem:   "xfs",
+		QuotaFeature: version == 5 || versionNum&xfsVersionQuotaBit != 0,
+		// Version 5 superblocks have a dedicated project quota inode; older ones share it with group quotas
+		ProjectQuotaFeature: version == 5 || qflags&xfsQuotaFlagProjectAcct != 0,
+		QuotaInodes:         make(map[QuotaType]uint64),
+		QuotaFiles:          make(map[QuotaType]*QuotaFileInfo),
+		Accounting: map[QuotaType]bool{
+			QuotaTypeUser:    qflags&xfsQuotaFlagUserAcct != 0,
+			QuotaTypeGroup:   qflags&xfsQuotaFlagGroupAcct != 0,
+			QuotaTypeProject: qflags&xfsQuotaFlagProjectAcct != 0,
+		},
+		Enforcement: map[QuotaType]bool{
+			QuotaTypeUser: qflags&xfsQuotaFlagUserEnfd != 0,
+		},
+	}
+
+	if version == 5 {
+		insp.Enforcement[QuotaTypeGroup] = qflags&xfsQuotaFlagGroupEnfd != 0
+		insp.Enforcement[QuotaTypeProject] = qflags&xfsQuotaFlagProjectEnfd != 0
+	} else {
+		otherEnforced := qflags&xfsQuotaFlagOtherEnfd != 0
+		insp.Enforcement[QuotaTypeGroup] = otherEnforced && insp.Accounting[QuotaTypeGroup]
+		insp.Enforcement[QuotaTypeProject] = otherEnforced && insp.Accounting[QuotaTypeProject]
+	}
+
+	addInode := func(t QuotaType, ino uint64) {
+		if ino != 0 && ino != xfsNullIno {
+			insp.QuotaInodes[t] = ino
+		}
+	}
+
+	addInode(QuotaTypeUser, be.Uint64(b[160:]))
+	if version == 5 {
+		addInode(QuotaTypeGroup, be.Uint64(b[168:]))
+		addInode(QuotaTypeProject, be.Uint64(b[232:]))
+	} else if insp.Accounting[QuotaTypeProject] {
+		addInode(QuotaTypeProject, be.Uint64(b[168:]))
+	} else {
+		addInode(QuotaTypeGroup, be.Uint64(b[168:]))
+	}
+
+	inspection = insp
+	return
+}
+
+func inspectImage(r io.ReaderAt) (inspection *ImageInspection, err error) {
+	// XFS keeps its superblock at the start of the device
+	xfsSuperblock := make([]byte, xfsSuperblockSize)
+	if _, err = r.ReadAt(xfsSuperblock, 0); err != nil {
+		return
+	}
+
+	if binary.BigEndian.Uint32(xfsSuperblock) == xfsMagic {
+		return inspectXFS(xfsSuperblock)
+	}
+
+	ext4Superblock := make([]byte, ext4SuperblockSize)
+	if _, err = r.ReadAt(ext4Superblock, ext4SuperblockOffset); err != nil {
+		return
+	}
+
+	if binary.LittleEndian.Uint16(ext4Superblock[0x38:]) == ext4Magic {
+		return inspectExt4(r, ext4Superblock)
+	}
+
+	err = errors.New("unsupported filesystem: neither ext4 nor XFS superblock found")
+	return
+}
+
+func inspectImageFile(path string) (inspection *ImageInspection, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+
+	return inspectImage(f)
+}
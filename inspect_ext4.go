@@ -0,0 +1,365 @@
+package fsquota
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// Offset of the ext2/3/4 superblock
+	ext4SuperblockOffset = 1024
+	// Size of the ext2/3/4 superblock
+	ext4SuperblockSize = 1024
+	// EXT4_SUPER_MAGIC
+	ext4Magic = 0xef53
+
+	// EXT4_FEATURE_RO_COMPAT_QUOTA
+	ext4FeatureROCompatQuota = 0x0100
+	// EXT4_FEATURE_RO_COMPAT_PROJECT
+	ext4FeatureROCompatProject = 0x2000
+	// EXT4_FEATURE_INCOMPAT_64BIT
+	ext4FeatureIncompat64Bit = 0x0080
+
+	// EXT4_EXTENTS_FL
+	ext4InodeFlagExtents = 0x80000
+	// Magic number of extent tree nodes
+	ext4ExtentMagic = 0xf30a
+
+	// EXT4_NDIR_BLOCKS
+	ext4DirectBlocks = 12
+	// EXT2_GOOD_OLD_INODE_SIZE
+	ext4GoodOldInodeSize = 128
+)
+
+// ext4Superblock contains the superblock fields relevant for quota inspection
+type ext4Superblock struct {
+	blockSize       uint64
+	firstDataBlock  uint32
+	inodesPerGroup  uint32
+	inodeSize       uint16
+	descSize        uint16
+	featureROCompat uint32
+	featureIncompat uint32
+	quotaInodes     map[QuotaType]uint32
+}
+
+func parseExt4Superblock(b []byte) (sb *ext4Superblock, err error) {
+	le := binary.LittleEndian
+
+	if len(b) < ext4SuperblockSize {
+		err = errors.New("ext4 superblock too short")
+		return
+	}
+
+	if le.Uint16(b[0x38:]) != ext4Magic {
+		err = errors.New("not an ext2/3/4 filesystem")
+		return
+	}
+
+	logBlockSize := le.Uint32(b[0x18:])
+	if logBlockSize > 6 {
+		err = fmt.Errorf("invalid ext4 block size exponent %d", logBlockSize)
+		return
+	}
+
+	s := &ext4Superblock{
+		blockSize:       1024 << logBlockSize,
+		firstDataBlock:  le.Uint32(b[0x14:]),
+		inodesPerGroup:  le.Uint32(b[0x28:]),
+		inodeSize:       ext4GoodOldInodeSize,
+		descSize:        32,
+		featureIncompat: le.Uint32(b[0x60:]),
+		featureROCompat: le.Uint32(b[0x64:]),
+		quotaInodes:     make(map[QuotaType]uint32),
+	}
+
+	// Dynamic revision filesystems carry the inode size in the superblock
+	if le.Uint32(b[0x4c:]) >= 1 {
+		s.inodeSize = le.Uint16(b[0x58:])
+	}
+
+	if s.featureIncompat&ext4FeatureIncompat64Bit != 0 {
+		if descSize := le.Uint16(b[0xfe:]); descSize >= 32 {
+			s.descSize = descSize
+		}
+	}
+
+	if s.inodesPerGroup == 0 || s.inodeSize == 0 {
+		err = errors.New("invalid ext4 inode geometry")
+		return
+	}
+
+	for t, offset := range map[QuotaType]int{QuotaTypeUser: 0x240, QuotaTypeGroup: 0x244, QuotaTypeProject: 0x26c} {
+		if inum := le.Uint32(b[offset:]); inum != 0 {
+			s.quotaInodes[t] = inum
+		}
+	}
+
+	sb = s
+	return
+}
+
+// ext4Reader provides read access to the inodes of an ext4 filesystem image
+type ext4Reader struct {
+	r  io.ReaderAt
+	sb *ext4Superblock
+}
+
+func (e *ext4Reader) readAt(size int, offset uint64) (data []byte, err error) {
+	data = make([]byte, size)
+	if _, err = e.r.ReadAt(data, int64(offset)); err != nil {
+		data = nil
+	}
+	return
+}
+
+func (e *ext4Reader) readBlock(block uint64) ([]byte, error) {
+	return e.readAt(int(e.sb.blockSize), block*e.sb.blockSize)
+}
+
+// readInode reads the raw inode with the given number
+func (e *ext4Reader) readInode(inum uint32) (inode []byte, err error) {
+	if inum == 0 {
+		err = errors.New("invalid inode number 0")
+		return
+	}
+
+	group := uint64((inum - 1) / e.sb.inodesPerGroup)
+	index := uint64((inum - 1) % e.sb.inodesPerGroup)
+
+	// Group descriptors start in the block following the superblock
+	descOffset := (uint64(e.sb.firstDataBlock)+1)*e.sb.blockSize + group*uint64(e.sb.descSize)
+
+	var desc []byte
+	if desc, err = e.readAt(int(e.sb.descSize), descOffset); err != nil {
+		return
+	}
+
+	inodeTable := uint64(binary.LittleEndian.Uint32(desc[0x8:]))
+	if e.sb.descSize >= 64 {
+		inodeTable |= uint64(binary.LittleEndian.Uint32(desc[0x28:])) << 32
+	}
+
+	return e.readAt(int(e.sb.inodeSize), inodeTable*e.sb.blockSize+index*uint64(e.sb.inodeSize))
+}
+
+// openInode returns a reader for the data of the given inode
+func (e *ext4Reader) openInode(inum uint32) (file *ext4File, err error) {
+	var inode []byte
+	if inode, err = e.readInode(inum); err != nil {
+		return
+	}
+
+	le := binary.LittleEndian
+	file = &ext4File{
+		fs:      e,
+		size:    uint64(le.Uint32(inode[0x4:])) | uint64(le.Uint32(inode[0x6c:]))<<32,
+		flags:   le.Uint32(inode[0x20:]),
+		iBlock:  inode[0x28 : 0x28+60],
+		mapping: make(map[uint64]uint64),
+	}
+
+	if err = file.buildMapping(); err != nil {
+		file = nil
+	}
+	return
+}
+
+// ext4File is an io.ReaderAt over the data blocks of an inode
+type ext4File struct {
+	fs     *ext4Reader
+	size   uint64
+	flags  uint32
+	iBlock []byte
+	// Mapping of logical to physical blocks
+	mapping map[uint64]uint64
+}
+
+func (f *ext4File) buildMapping() (err error) {
+	if f.flags&ext4InodeFlagExtents != 0 {
+		return f.mapExtents(f.iBlock, 0)
+	}
+
+	// Classic block map: 12 direct blocks, followed by single, double and triple indirect blocks
+	le := binary.LittleEndian
+	logical := uint64(0)
+	for i := 0; i < ext4DirectBlocks; i++ {
+		if physical := uint64(le.Uint32(f.iBlock[i*4:])); physical != 0 {
+			f.mapping[logical] = physical
+		}
+		logical++
+	}
+
+	for level := 1; level <= 3; level++ {
+		if err = f.mapIndirect(uint64(le.Uint32(f.iBlock[(ext4DirectBlocks+level-1)*4:])), level, &logical); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (f *ext4File) mapIndirect(block uint64, level int, logical *uint64) (err error) {
+	pointersPerBlock := f.fs.sb.blockSize / 4
+
+	if block == 0 {
+		// Skip the range covered by this (sparse) indirect block
+		span := uint64(1)
+		for i := 0; i < level; i++ {
+			span *= pointersPerBlock
+		}
+		*logical += span
+		return
+	}
+
+	var data []byte
+	if data, err = f.fs.readBlock(block); err != nil {
+		return
+	}
+
+	for i := uint64(0); i < pointersPerBlock; i++ {
+		pointer := uint64(binary.LittleEndian.Uint32(data[i*4:]))
+		if level == 1 {
+			if pointer != 0 {
+				f.mapping[*logical] = pointer
+			}
+			*logical++
+		} else if err = f.mapIndirect(pointer, level-1, logical); err != nil {
+			return
+		}
+
+		if *logical*f.fs.sb.blockSize >= f.size {
+			return
+		}
+	}
+	return
+}
+
+func (f *ext4File) mapExtents(node []byte, depth int) (err error) {
+	le := binary.LittleEndian
+
+	if len(node) < 12 || le.Uint16(node[0:]) != ext4ExtentMagic {
+		err = errors.New("invalid ext4 extent header")
+		return
+	} else if depth > 5 {
+		err = errors.New("ext4 extent tree too deep")
+		return
+	}
+
+	entries := int(le.Uint16(node[2:]))
+	treeDepth := le.Uint16(node[6:])
+	if entries > (len(node)-12)/12 {
+		err = errors.New("ext4 extent node truncated")
+		return
+	}
+
+	for i := 0; i < entries; i++ {
+		entry := node[12+i*12 : 24+i*12]
+
+		if treeDepth == 0 {
+			logical := uint64(le.Uint32(entry[0:]))
+			length := uint64(le.Uint16(entry[4:]))
+			if length > 32768 {
+				// Uninitialized extent: the data reads as zeroes
+				continue
+			}
+			physical := uint64(le.Uint16(entry[6:]))<<32 | uint64(le.Uint32(entry[8:]))
+
+			for j := uint64(0); j < length; j++ {
+				f.mapping[logical+j] = physical + j
+			}
+			continue
+		}
+
+		leaf := uint64(le.Uint16(entry[8:]))<<32 | uint64(le.Uint32(entry[4:]))
+		var child []byte
+		if child, err = f.fs.readBlock(leaf); err != nil {
+			return
+		}
+
+		if err = f.mapExtents(child, depth+1); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ReadAt implements io.ReaderAt
+func (f *ext4File) ReadAt(p []byte, off int64) (n int, err error) {
+	blockSize := f.fs.sb.blockSize
+
+	for n < len(p) {
+		pos := uint64(off) + uint64(n)
+		if pos >= f.size {
+			err = io.EOF
+			return
+		}
+
+		logical := pos / blockSize
+		inBlock := pos % blockSize
+
+		chunk := blockSize - inBlock
+		if remaining := uint64(len(p) - n); chunk > remaining {
+			chunk = remaining
+		}
+		if remaining := f.size - pos; chunk > remaining {
+			chunk = remaining
+		}
+
+		if physical, mapped := f.mapping[logical]; mapped {
+			var data []byte
+			if data, err = f.fs.readAt(int(chunk), physical*blockSize+inBlock); err != nil {
+				return
+			}
+			copy(p[n:], data)
+		} else {
+			// Holes read as zeroes
+			for i := uint64(0); i < chunk; i++ {
+				p[uint64(n)+i] = 0
+			}
+		}
+
+		n += int(chunk)
+	}
+
+	return
+}
+
+func inspectExt4(r io.ReaderAt, superblock []byte) (inspection *ImageInspection, err error) {
+	var sb *ext4Superblock
+	if sb, err = parseExt4Superblock(superblock); err != nil {
+		return
+	}
+
+	insp := &ImageInspection{
+		Filesystem:          "ext4",
+		QuotaFeature:        sb.featureROCompat&ext4FeatureROCompatQuota != 0,
+		ProjectQuotaFeature: sb.featureROCompat&ext4FeatureROCompatProject != 0,
+		QuotaInodes:         make(map[QuotaType]uint64),
+		QuotaFiles:          make(map[QuotaType]*QuotaFileInfo),
+	}
+
+	reader := &ext4Reader{
+		r:  r,
+		sb: sb,
+	}
+
+	for t, inum := range sb.quotaInodes {
+		insp.QuotaInodes[t] = uint64(inum)
+
+		var file *ext4File
+		var fileErr error
+		if file, fileErr = reader.openInode(inum); fileErr == nil {
+			insp.QuotaFiles[t], fileErr = readQuotaFile(file, t)
+		}
+
+		if fileErr != nil {
+			err = fmt.Errorf("reading %s quota inode %d: %s", t, inum, fileErr)
+			return
+		}
+	}
+
+	inspection = insp
+	return
+}
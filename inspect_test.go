@@ -0,0 +1,170 @@
+package fsquota
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildTestQuotaFile creates a v2r1 quota file containing a single entry for ID 1234
+func buildTestQuotaFile() []byte {
+	le := binary.LittleEndian
+	data := make([]byte, 6*quotaTreeBlockSize)
+
+	le.PutUint32(data[0:], quotaFileMagicUser)
+	le.PutUint32(data[4:], 1)
+	le.PutUint32(data[8:], 3600)
+	le.PutUint32(data[12:], 7200)
+
+	block := func(n int) []byte {
+		return data[n*quotaTreeBlockSize : (n+1)*quotaTreeBlockSize]
+	}
+
+	// ID 1234 is 0x000004d2, each tree level is indexed by one byte of the ID
+	le.PutUint32(block(1)[0x00*4:], 2)
+	le.PutUint32(block(2)[0x00*4:], 3)
+	le.PutUint32(block(3)[0x04*4:], 4)
+	le.PutUint32(block(4)[0xd2*4:], 5)
+
+	entry := block(5)[quotaTreeLeafHeaderSize:]
+	le.PutUint32(entry[0:], 1234)
+	le.PutUint64(entry[8:], 200)    // inode hard limit
+	le.PutUint64(entry[16:], 100)   // inode soft limit
+	le.PutUint64(entry[24:], 5)     // inodes used
+	le.PutUint64(entry[32:], 2048)  // block hard limit
+	le.PutUint64(entry[40:], 1024)  // block soft limit
+	le.PutUint64(entry[48:], 12345) // bytes used
+
+	return data
+}
+
+func TestReadQuotaFile(t *testing.T) {
+	t.Run("InvalidMagic", func(t *testing.T) {
+		_, err := readQuotaFile(bytes.NewReader(buildTestQuotaFile()), QuotaTypeGroup)
+		assert.Error(t, err)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		info, err := readQuotaFile(bytes.NewReader(buildTestQuotaFile()), QuotaTypeUser)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+
+		assert.EqualValues(t, 1, info.Version)
+		assert.EqualValues(t, 3600, info.BytesGracePeriod)
+		assert.EqualValues(t, 7200, info.FilesGracePeriod)
+
+		require.Len(t, info.Report.Infos, 1)
+		entry := info.Report.Infos["1234"]
+		require.NotNil(t, entry)
+		assert.EqualValues(t, 2048*1024, entry.Bytes.GetHard())
+		assert.EqualValues(t, 1024*1024, entry.Bytes.GetSoft())
+		assert.EqualValues(t, 12345, entry.BytesUsed)
+		assert.EqualValues(t, 200, entry.Files.GetHard())
+		assert.EqualValues(t, 100, entry.Files.GetSoft())
+		assert.EqualValues(t, 5, entry.FilesUsed)
+	})
+}
+
+func TestParseExt4Superblock(t *testing.T) {
+	le := binary.LittleEndian
+
+	t.Run("InvalidMagic", func(t *testing.T) {
+		_, err := parseExt4Superblock(make([]byte, ext4SuperblockSize))
+		assert.Error(t, err)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		b := make([]byte, ext4SuperblockSize)
+		le.PutUint16(b[0x38:], ext4Magic)
+		le.PutUint32(b[0x18:], 2)
+		le.PutUint32(b[0x28:], 8192)
+		le.PutUint32(b[0x4c:], 1)
+		le.PutUint16(b[0x58:], 256)
+		le.PutUint32(b[0x64:], ext4FeatureROCompatQuota|ext4FeatureROCompatProject)
+		le.PutUint32(b[0x240:], 3)
+		le.PutUint32(b[0x244:], 4)
+		le.PutUint32(b[0x26c:], 12)
+
+		sb, err := parseExt4Superblock(b)
+		require.NoError(t, err)
+		assert.EqualValues(t, 4096, sb.blockSize)
+		assert.EqualValues(t, 256, sb.inodeSize)
+		assert.EqualValues(t, 32, sb.descSize)
+		assert.EqualValues(t, map[QuotaType]uint32{
+			QuotaTypeUser:    3,
+			QuotaTypeGroup:   4,
+			QuotaTypeProject: 12,
+		}, sb.quotaInodes)
+	})
+}
+
+func TestExt4File_MapExtents(t *testing.T) {
+	le := binary.LittleEndian
+
+	node := make([]byte, 24)
+	le.PutUint16(node[0:], ext4ExtentMagic)
+	le.PutUint16(node[2:], 1)
+	le.PutUint32(node[12:], 5)
+	le.PutUint16(node[16:], 2)
+	le.PutUint32(node[20:], 100)
+
+	f := &ext4File{mapping: make(map[uint64]uint64)}
+	require.NoError(t, f.mapExtents(node, 0))
+	assert.EqualValues(t, map[uint64]uint64{5: 100, 6: 101}, f.mapping)
+
+	t.Run("Truncated", func(t *testing.T) {
+		le.PutUint16(node[2:], 2)
+		assert.EqualError(t, f.mapExtents(node, 0), "ext4 extent node truncated")
+
+		le.PutUint16(node[2:], 0xffff)
+		assert.EqualError(t, f.mapExtents(node[:13], 0), "ext4 extent node truncated")
+	})
+}
+
+func TestInspectXFS(t *testing.T) {
+	be := binary.BigEndian
+
+	t.Run("V5", func(t *testing.T) {
+		b := make([]byte, xfsSuperblockSize)
+		be.PutUint32(b[0:], xfsMagic)
+		be.PutUint16(b[100:], 5)
+		be.PutUint64(b[160:], 131)
+		be.PutUint64(b[168:], 132)
+		be.PutUint64(b[232:], xfsNullIno)
+		be.PutUint16(b[176:], xfsQuotaFlagUserAcct|xfsQuotaFlagUserEnfd|xfsQuotaFlagGroupAcct)
+
+		inspection, err := inspectImage(bytes.NewReader(b))
+		require.NoError(t, err)
+
+		assert.EqualValues(t, "xfs", inspection.Filesystem)
+		assert.True(t, inspection.QuotaFeature)
+		assert.EqualValues(t, map[QuotaType]uint64{QuotaTypeUser: 131, QuotaTypeGroup: 132}, inspection.QuotaInodes)
+		assert.EqualValues(t, map[QuotaType]bool{QuotaTypeUser: true, QuotaTypeGroup: true, QuotaTypeProject: false}, inspection.Accounting)
+		assert.EqualValues(t, map[QuotaType]bool{QuotaTypeUser: true, QuotaTypeGroup: false, QuotaTypeProject: false}, inspection.Enforcement)
+	})
+
+	t.Run("V4Project", func(t *testing.T) {
+		b := make([]byte, xfsSuperblockSize)
+		be.PutUint32(b[0:], xfsMagic)
+		be.PutUint16(b[100:], 4|xfsVersionQuotaBit)
+		be.PutUint64(b[168:], 133)
+		be.PutUint16(b[176:], xfsQuotaFlagProjectAcct|xfsQuotaFlagOtherEnfd)
+
+		inspection, err := inspectXFS(b)
+		require.NoError(t, err)
+
+		assert.True(t, inspection.QuotaFeature)
+		assert.True(t, inspection.ProjectQuotaFeature)
+		assert.EqualValues(t, map[QuotaType]uint64{QuotaTypeProject: 133}, inspection.QuotaInodes)
+		assert.True(t, inspection.Enforcement[QuotaTypeProject])
+		assert.False(t, inspection.Enforcement[QuotaTypeGroup])
+	})
+}
+
+func TestInspectImage_Unsupported(t *testing.T) {
+	_, err := inspectImage(bytes.NewReader(make([]byte, 4096)))
+	assert.Error(t, err)
+}
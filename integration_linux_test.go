@@ -345,3 +345,30 @@ func TestGetGroupReport(t *testing.T) {
 		assert.Nil(t, report)
 	})
 }
+
+func TestGetUserInfoAllMounts(t *testing.T) {
+	testMountPointQuotasEnabled, _ := prepareIntegrationTest(t)
+
+	testUser := &user.User{
+		Uid: "10002",
+	}
+
+	limits := fsquota.Limits{}
+	limits.Bytes.SetSoft(12 * 1024 * 1024)
+	limits.Bytes.SetHard(24 * 1024 * 1024)
+
+	_, err := fsquota.SetUserQuota(testMountPointQuotasEnabled, testUser, limits)
+	require.NoError(t, err)
+
+	infos, err := fsquota.GetUserInfoAllMounts(testUser)
+	require.NoError(t, err)
+
+	found := false
+	for _, mountInfo := range infos {
+		if mountInfo.Info.Bytes.GetSoft() == 12*1024*1024 {
+			found = true
+			assert.EqualValues(t, 24*1024*1024, mountInfo.Info.Bytes.GetHard())
+		}
+	}
+	assert.True(t, found)
+}
@@ -60,3 +60,40 @@ func (l *Limit) getValues() (hard, soft uint64, ok bool) {
 
 	return
 }
+
+// set copies the limits configured on other
+func (l *Limit) set(other *Limit) {
+	other.mu.Lock()
+	soft, hard := other.soft, other.hard
+	other.mu.Unlock()
+
+	if soft != nil {
+		l.SetSoft(*soft)
+	}
+
+	if hard != nil {
+		l.SetHard(*hard)
+	}
+}
+
+// completedFrom returns a copy of l. If only one of the soft and hard limit is set on l,
+// the other one is taken from current.
+func (l *Limit) completedFrom(current *Limit) *Limit {
+	l.mu.Lock()
+	soft, hard := l.soft, l.hard
+	l.mu.Unlock()
+
+	completed := &Limit{}
+	if (soft == nil) != (hard == nil) {
+		completed.set(current)
+	}
+	completed.set(l)
+	return completed
+}
+
+// partial reports whether only one of the soft and hard limit is set
+func (l *Limit) partial() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return (l.soft == nil) != (l.hard == nil)
+}
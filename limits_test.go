@@ -54,3 +54,29 @@ func TestLimit_SetSoft(t *testing.T) {
 	require.NotNil(t, l.soft)
 	assert.EqualValues(t, 64, *l.soft)
 }
+
+func TestLimit_completedFrom(t *testing.T) {
+	current := &Limit{}
+	current.SetSoft(1)
+	current.SetHard(2)
+
+	softOnly := &Limit{}
+	softOnly.SetSoft(10)
+	completed := softOnly.completedFrom(current)
+	assert.EqualValues(t, 10, completed.GetSoft())
+	assert.EqualValues(t, 2, completed.GetHard())
+	assert.True(t, softOnly.partial())
+	assert.False(t, completed.partial())
+
+	hardOnly := &Limit{}
+	hardOnly.SetHard(20)
+	completed = hardOnly.completedFrom(current)
+	assert.EqualValues(t, 1, completed.GetSoft())
+	assert.EqualValues(t, 20, completed.GetHard())
+
+	// Limits with neither or both values set are copied as-is
+	completed = (&Limit{}).completedFrom(current)
+	_, _, ok := completed.getValues()
+	assert.False(t, ok)
+	assert.False(t, (&Limit{}).partial())
+}
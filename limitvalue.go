@@ -0,0 +1,123 @@
+package fsquota
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LimitValueKind determines how a LimitValue is resolved
+type LimitValueKind uint8
+
+const (
+	// LimitAbsolute is a limit given as absolute value
+	LimitAbsolute LimitValueKind = iota
+	// LimitPercent is a limit given as percentage of the filesystem's capacity
+	LimitPercent
+	// LimitDelta is a limit given relative to the current limit
+	LimitDelta
+)
+
+// LimitValue is a single limit which may depend on the filesystem's capacity or the current limit
+type LimitValue struct {
+	Kind LimitValueKind
+
+	// Absolute value, used by LimitAbsolute
+	Value uint64
+	// Percentage of the filesystem's capacity, used by LimitPercent
+	Percent float64
+	// Difference to the current limit, used by LimitDelta
+	Delta int64
+}
+
+// LimitChange describes new limits which may be relative. Values which are nil are left unchanged.
+type LimitChange struct {
+	BytesSoft *LimitValue
+	BytesHard *LimitValue
+	FilesSoft *LimitValue
+	FilesHard *LimitValue
+}
+
+// needsCapacity reports whether any of the values is relative to the filesystem's capacity
+func (c *LimitChange) needsCapacity() bool {
+	for _, v := range []*LimitValue{c.BytesSoft, c.BytesHard, c.FilesSoft, c.FilesHard} {
+		if v != nil && v.Kind == LimitPercent {
+			return true
+		}
+	}
+	return false
+}
+
+// needsCurrent reports whether any of the values is relative to the current limits
+func (c *LimitChange) needsCurrent() bool {
+	for _, v := range []*LimitValue{c.BytesSoft, c.BytesHard, c.FilesSoft, c.FilesHard} {
+		if v != nil && v.Kind == LimitDelta {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve computes the absolute value of v
+func (v *LimitValue) resolve(current, capacity uint64) (value uint64, err error) {
+	switch v.Kind {
+	case LimitAbsolute:
+		value = v.Value
+	case LimitPercent:
+		if v.Percent < 0 {
+			err = errors.New("percentage must not be negative")
+			return
+		}
+		if capacity == 0 {
+			err = errors.New("filesystem capacity unknown")
+			return
+		}
+		value = uint64(float64(capacity) * v.Percent / 100)
+	case LimitDelta:
+		if current == 0 {
+			// Zero limits are unlimited, so there is nothing to adjust
+			err = errors.New("cannot adjust a limit which is not set")
+			return
+		}
+		if v.Delta < 0 && uint64(-v.Delta) > current {
+			err = fmt.Errorf("decreasing %d by %d results in a negative limit", current, -v.Delta)
+			return
+		}
+		value = uint64(int64(current) + v.Delta)
+	default:
+		err = errors.New("unknown limit value kind")
+	}
+
+	return
+}
+
+// resolveLimitChange computes the absolute limits described by change
+func resolveLimitChange(change *LimitChange, current *Info, capacityBytes, capacityFiles uint64) (limits *Limits, err error) {
+	limits = &Limits{}
+
+	for _, target := range []struct {
+		name     string
+		value    *LimitValue
+		current  uint64
+		capacity uint64
+		setFn    func(uint64)
+	}{
+		{"bytes soft", change.BytesSoft, current.Bytes.GetSoft(), capacityBytes, limits.Bytes.SetSoft},
+		{"bytes hard", change.BytesHard, current.Bytes.GetHard(), capacityBytes, limits.Bytes.SetHard},
+		{"files soft", change.FilesSoft, current.Files.GetSoft(), capacityFiles, limits.Files.SetSoft},
+		{"files hard", change.FilesHard, current.Files.GetHard(), capacityFiles, limits.Files.SetHard},
+	} {
+		if target.value == nil {
+			continue
+		}
+
+		value, resolveErr := target.value.resolve(target.current, target.capacity)
+		if resolveErr != nil {
+			err = fmt.Errorf("%s: %s", target.name, resolveErr)
+			limits = nil
+			return
+		}
+		target.setFn(value)
+	}
+
+	return
+}
@@ -0,0 +1,36 @@
+package fsquota
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// filesystemCapacity returns the size and the inode count of the filesystem at path. Block counts are in units
+// of the fragment size, which differs from the block size on some filesystems.
+func filesystemCapacity(path string) (capacityBytes, capacityFiles uint64, err error) {
+	var statfs unix.Statfs_t
+	if err = unix.Statfs(path, &statfs); err != nil {
+		return
+	}
+
+	capacityBytes = statfs.Blocks * uint64(statfs.Frsize)
+	capacityFiles = statfs.Files
+	return
+}
+
+func resolveLimits(path string, t quotaCtlType, idString string, change *LimitChange) (limits *Limits, err error) {
+	var capacityBytes, capacityFiles uint64
+	if change.needsCapacity() {
+		if capacityBytes, capacityFiles, err = filesystemCapacity(path); err != nil {
+			return
+		}
+	}
+
+	current := &Info{}
+	if change.needsCurrent() {
+		if current, err = getQuota(t, path, idString); err != nil {
+			return
+		}
+	}
+
+	return resolveLimitChange(change, current, capacityBytes, capacityFiles)
+}
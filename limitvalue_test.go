@@ -0,0 +1,61 @@
+package fsquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveLimitChange(t *testing.T) {
+	current := &Info{}
+	current.Bytes.SetSoft(10 << 30)
+	current.Bytes.SetHard(20 << 30)
+	current.Files.SetSoft(0)
+	current.Files.SetHard(1000)
+
+	t.Run("OK", func(t *testing.T) {
+		change := &LimitChange{
+			BytesSoft: &LimitValue{Kind: LimitPercent, Percent: 5},
+			BytesHard: &LimitValue{Kind: LimitDelta, Delta: 10 << 30},
+			FilesHard: &LimitValue{Kind: LimitDelta, Delta: -100},
+		}
+		assert.True(t, change.needsCapacity())
+		assert.True(t, change.needsCurrent())
+
+		limits, err := resolveLimitChange(change, current, 200<<30, 0)
+		require.NoError(t, err)
+		assert.EqualValues(t, 10<<30, limits.Bytes.GetSoft())
+		assert.EqualValues(t, 30<<30, limits.Bytes.GetHard())
+		assert.EqualValues(t, 900, limits.Files.GetHard())
+		assert.True(t, limits.Files.partial(), "files soft limit must remain unset")
+	})
+
+	t.Run("Absolute", func(t *testing.T) {
+		change := &LimitChange{
+			FilesSoft: &LimitValue{Kind: LimitAbsolute, Value: 5},
+		}
+		assert.False(t, change.needsCapacity())
+		assert.False(t, change.needsCurrent())
+
+		limits, err := resolveLimitChange(change, &Info{}, 0, 0)
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, limits.Files.GetSoft())
+		_, _, haveBytes := limits.Bytes.getValues()
+		assert.False(t, haveBytes)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for name, change := range map[string]*LimitChange{
+			"NegativeResult":   {BytesHard: &LimitValue{Kind: LimitDelta, Delta: -(21 << 30)}},
+			"UnsetLimit":       {FilesSoft: &LimitValue{Kind: LimitDelta, Delta: 1}},
+			"UnknownCapacity":  {FilesSoft: &LimitValue{Kind: LimitPercent, Percent: 1}},
+			"NegativePercent":  {BytesSoft: &LimitValue{Kind: LimitPercent, Percent: -1}},
+			"UnknownValueKind": {BytesSoft: &LimitValue{Kind: LimitValueKind(42)}},
+		} {
+			limits, err := resolveLimitChange(change, current, 100, 0)
+			assert.Error(t, err, name)
+			assert.Nil(t, limits, name)
+		}
+	})
+}
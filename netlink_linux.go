@@ -0,0 +1,289 @@
+package fsquota
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// genlMsghdrLen is the size of the generic netlink header following the netlink message header
+const genlMsghdrLen = int(unsafe.Sizeof(unix.Genlmsghdr{}))
+
+// nlAttrHdrLen is the size of a netlink attribute header
+const nlAttrHdrLen = int(unsafe.Sizeof(unix.NlAttr{}))
+
+func nlAlign(length int) int {
+	return (length + unix.NLMSG_ALIGNTO - 1) & ^(unix.NLMSG_ALIGNTO - 1)
+}
+
+// nlAttr represents a single netlink attribute
+type nlAttr struct {
+	typ  uint16
+	data []byte
+}
+
+func (a nlAttr) uint16() uint16 {
+	if len(a.data) < 2 {
+		return 0
+	}
+	return *(*uint16)(unsafe.Pointer(&a.data[0]))
+}
+
+func (a nlAttr) uint32() uint32 {
+	if len(a.data) < 4 {
+		return 0
+	}
+	return *(*uint32)(unsafe.Pointer(&a.data[0]))
+}
+
+func (a nlAttr) uint64() uint64 {
+	if len(a.data) < 8 {
+		return 0
+	}
+	return *(*uint64)(unsafe.Pointer(&a.data[0]))
+}
+
+func (a nlAttr) string() string {
+	data := a.data
+	for i, b := range data {
+		if b == 0 {
+			data = data[:i]
+			break
+		}
+	}
+	return string(data)
+}
+
+func parseNlAttrs(b []byte) (attrs []nlAttr, err error) {
+	for len(b) >= nlAttrHdrLen {
+		hdr := (*unix.NlAttr)(unsafe.Pointer(&b[0]))
+		attrLen := int(hdr.Len)
+
+		if attrLen < nlAttrHdrLen || attrLen > len(b) {
+			err = errors.New("malformed netlink attribute")
+			return
+		}
+
+		attrs = append(attrs, nlAttr{
+			// Strip the nested and byte-order flags
+			typ:  hdr.Type & ^uint16(unix.NLA_F_NESTED|unix.NLA_F_NET_BYTEORDER),
+			data: b[nlAttrHdrLen:attrLen],
+		})
+
+		if alignedLen := nlAlign(attrLen); alignedLen < len(b) {
+			b = b[alignedLen:]
+		} else {
+			break
+		}
+	}
+
+	return
+}
+
+func encodeNlAttr(typ uint16, data []byte) []byte {
+	attrLen := nlAttrHdrLen + len(data)
+	b := make([]byte, nlAlign(attrLen))
+
+	hdr := (*unix.NlAttr)(unsafe.Pointer(&b[0]))
+	hdr.Len = uint16(attrLen)
+	hdr.Type = typ
+	copy(b[nlAttrHdrLen:], data)
+
+	return b
+}
+
+func encodeGenlMessage(family uint16, flags uint16, seq uint32, cmd uint8, version uint8, payload []byte) []byte {
+	msgLen := unix.SizeofNlMsghdr + genlMsghdrLen + len(payload)
+	b := make([]byte, nlAlign(msgLen))
+
+	hdr := (*unix.NlMsghdr)(unsafe.Pointer(&b[0]))
+	hdr.Len = uint32(msgLen)
+	hdr.Type = family
+	hdr.Flags = flags
+	hdr.Seq = seq
+	hdr.Pid = 0
+
+	genlHdr := (*unix.Genlmsghdr)(unsafe.Pointer(&b[unix.SizeofNlMsghdr]))
+	genlHdr.Cmd = cmd
+	genlHdr.Version = version
+
+	copy(b[unix.SizeofNlMsghdr+genlMsghdrLen:], payload)
+
+	return b
+}
+
+// genlMessage represents a received generic netlink message
+type genlMessage struct {
+	typ   uint16
+	cmd   uint8
+	attrs []nlAttr
+}
+
+func parseGenlMessages(b []byte) (messages []genlMessage, err error) {
+	var nlMessages []syscall.NetlinkMessage
+	if nlMessages, err = syscall.ParseNetlinkMessage(b); err != nil {
+		return
+	}
+
+	for _, nlMessage := range nlMessages {
+		switch nlMessage.Header.Type {
+		case unix.NLMSG_DONE, unix.NLMSG_NOOP:
+			continue
+		case unix.NLMSG_ERROR:
+			if len(nlMessage.Data) >= 4 {
+				if errno := -*(*int32)(unsafe.Pointer(&nlMessage.Data[0])); errno != 0 {
+					err = os.NewSyscallError("netlink", syscall.Errno(errno))
+					return
+				}
+			}
+			continue
+		}
+
+		if len(nlMessage.Data) < genlMsghdrLen {
+			err = errors.New("generic netlink message too short")
+			return
+		}
+
+		genlHdr := (*unix.Genlmsghdr)(unsafe.Pointer(&nlMessage.Data[0]))
+		message := genlMessage{
+			typ: nlMessage.Header.Type,
+			cmd: genlHdr.Cmd,
+		}
+
+		if message.attrs, err = parseNlAttrs(nlMessage.Data[genlMsghdrLen:]); err != nil {
+			return
+		}
+
+		messages = append(messages, message)
+	}
+
+	return
+}
+
+// genlFamily contains the information of a resolved generic netlink family
+type genlFamily struct {
+	id     uint16
+	groups map[string]uint32
+}
+
+func parseGenlFamily(attrs []nlAttr) (family *genlFamily, err error) {
+	fam := &genlFamily{
+		groups: make(map[string]uint32),
+	}
+
+	for _, attr := range attrs {
+		switch attr.typ {
+		case unix.CTRL_ATTR_FAMILY_ID:
+			fam.id = attr.uint16()
+		case unix.CTRL_ATTR_MCAST_GROUPS:
+			var groupAttrs []nlAttr
+			if groupAttrs, err = parseNlAttrs(attr.data); err != nil {
+				return
+			}
+
+			for _, groupAttr := range groupAttrs {
+				var fields []nlAttr
+				if fields, err = parseNlAttrs(groupAttr.data); err != nil {
+					return
+				}
+
+				var name string
+				var id uint32
+				for _, field := range fields {
+					switch field.typ {
+					case unix.CTRL_ATTR_MCAST_GRP_NAME:
+						name = field.string()
+					case unix.CTRL_ATTR_MCAST_GRP_ID:
+						id = field.uint32()
+					}
+				}
+
+				if name != "" {
+					fam.groups[name] = id
+				}
+			}
+		}
+	}
+
+	if fam.id == 0 {
+		err = errors.New("generic netlink family ID missing from response")
+		return
+	}
+
+	family = fam
+	return
+}
+
+// genlConn is a minimal generic netlink socket
+type genlConn struct {
+	fd  int
+	seq uint32
+}
+
+func dialGenl() (conn *genlConn, err error) {
+	var fd int
+	if fd, err = unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_GENERIC); err != nil {
+		err = os.NewSyscallError("socket", err)
+		return
+	}
+
+	if err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		err = os.NewSyscallError("bind", err)
+		return
+	}
+
+	conn = &genlConn{
+		fd: fd,
+	}
+	return
+}
+
+func (c *genlConn) Close() error {
+	return unix.Close(c.fd)
+}
+
+func (c *genlConn) receive(buf []byte) (messages []genlMessage, err error) {
+	var n int
+	if n, _, err = unix.Recvfrom(c.fd, buf, 0); err != nil {
+		return
+	}
+
+	return parseGenlMessages(buf[:n])
+}
+
+func (c *genlConn) resolveFamily(name string) (family *genlFamily, err error) {
+	c.seq++
+	request := encodeGenlMessage(unix.GENL_ID_CTRL, unix.NLM_F_REQUEST, c.seq, unix.CTRL_CMD_GETFAMILY, 1,
+		encodeNlAttr(unix.CTRL_ATTR_FAMILY_NAME, append([]byte(name), 0)))
+
+	if err = unix.Sendto(c.fd, request, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		err = os.NewSyscallError("sendto", err)
+		return
+	}
+
+	buf := make([]byte, os.Getpagesize())
+	var messages []genlMessage
+	if messages, err = c.receive(buf); err != nil {
+		return
+	}
+
+	for _, message := range messages {
+		if message.typ == unix.GENL_ID_CTRL && message.cmd == unix.CTRL_CMD_NEWFAMILY {
+			return parseGenlFamily(message.attrs)
+		}
+	}
+
+	err = errors.New("no family information received")
+	return
+}
+
+func (c *genlConn) joinGroup(group uint32) (err error) {
+	if err = unix.SetsockoptInt(c.fd, unix.SOL_NETLINK, unix.NETLINK_ADD_MEMBERSHIP, int(group)); err != nil {
+		err = os.NewSyscallError("setsockopt", err)
+	}
+	return
+}
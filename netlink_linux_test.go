@@ -0,0 +1,124 @@
+package fsquota
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func nativeUint16(v uint16) []byte {
+	b := make([]byte, 2)
+	*(*uint16)(unsafe.Pointer(&b[0])) = v
+	return b
+}
+
+func nativeUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	*(*uint32)(unsafe.Pointer(&b[0])) = v
+	return b
+}
+
+func nativeUint64(v uint64) []byte {
+	b := make([]byte, 8)
+	*(*uint64)(unsafe.Pointer(&b[0])) = v
+	return b
+}
+
+func concatBytes(parts ...[]byte) (b []byte) {
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return
+}
+
+func TestParseNlAttrs(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		attrs, err := parseNlAttrs(nil)
+		assert.NoError(t, err)
+		assert.Empty(t, attrs)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		b := encodeNlAttr(1, []byte{1, 2, 3, 4})
+		// Claim a length beyond the end of the buffer
+		b[0] = 64
+		_, err := parseNlAttrs(b)
+		assert.Error(t, err)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		b := concatBytes(
+			encodeNlAttr(1, []byte("abc\x00")),
+			encodeNlAttr(2, nativeUint32(42)),
+			encodeNlAttr(3|unix.NLA_F_NESTED, nativeUint64(1<<40)),
+		)
+
+		attrs, err := parseNlAttrs(b)
+		require.NoError(t, err)
+		require.Len(t, attrs, 3)
+
+		assert.EqualValues(t, 1, attrs[0].typ)
+		assert.EqualValues(t, "abc", attrs[0].string())
+		assert.EqualValues(t, 2, attrs[1].typ)
+		assert.EqualValues(t, 42, attrs[1].uint32())
+		assert.EqualValues(t, 3, attrs[2].typ)
+		assert.EqualValues(t, 1<<40, attrs[2].uint64())
+	})
+}
+
+func TestParseGenlFamily(t *testing.T) {
+	t.Run("MissingID", func(t *testing.T) {
+		_, err := parseGenlFamily(nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		groups := encodeNlAttr(unix.CTRL_ATTR_MCAST_GROUPS|unix.NLA_F_NESTED, encodeNlAttr(1|unix.NLA_F_NESTED, concatBytes(
+			encodeNlAttr(unix.CTRL_ATTR_MCAST_GRP_ID, nativeUint32(9)),
+			encodeNlAttr(unix.CTRL_ATTR_MCAST_GRP_NAME, []byte("events\x00")),
+		)))
+
+		attrs, err := parseNlAttrs(concatBytes(
+			encodeNlAttr(unix.CTRL_ATTR_FAMILY_ID, nativeUint16(27)),
+			encodeNlAttr(unix.CTRL_ATTR_FAMILY_NAME, []byte("VFS_DQUOT\x00")),
+			groups,
+		))
+		require.NoError(t, err)
+
+		family, err := parseGenlFamily(attrs)
+		require.NoError(t, err)
+		require.NotNil(t, family)
+		assert.EqualValues(t, 27, family.id)
+		assert.EqualValues(t, map[string]uint32{"events": 9}, family.groups)
+	})
+}
+
+func TestQuotaWarningFromMessage(t *testing.T) {
+	payload := concatBytes(
+		encodeNlAttr(quotaNlAttrQType, nativeUint32(uint32(QuotaTypeGroup))),
+		encodeNlAttr(quotaNlAttrExcessID, nativeUint64(1000)),
+		encodeNlAttr(quotaNlAttrWarning, nativeUint32(uint32(WarningBytesSoft))),
+		encodeNlAttr(quotaNlAttrDevMajor, nativeUint32(8)),
+		encodeNlAttr(quotaNlAttrDevMinor, nativeUint32(1)),
+		encodeNlAttr(quotaNlAttrCausedID, nativeUint64(1001)),
+	)
+
+	messages, err := parseGenlMessages(encodeGenlMessage(27, 0, 0, quotaNlCmdWarning, 1, payload))
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.EqualValues(t, 27, messages[0].typ)
+	assert.EqualValues(t, quotaNlCmdWarning, messages[0].cmd)
+
+	warning := quotaWarningFromAttrs(messages[0].attrs)
+	assert.EqualValues(t, &QuotaWarning{
+		Type:        QuotaTypeGroup,
+		ID:          1000,
+		Kind:        WarningBytesSoft,
+		DeviceMajor: 8,
+		DeviceMinor: 1,
+		CallerUID:   1001,
+	}, warning)
+}
@@ -0,0 +1,63 @@
+package fsquota
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/docker/docker/pkg/mount"
+)
+
+// nfsMount describes the remote side of an NFS mount
+type nfsMount struct {
+	// Host exporting the filesystem
+	host string
+	// Path of the export on host
+	exportPath string
+}
+
+func isNFSFilesystem(fstype string) bool {
+	return fstype == "nfs" || fstype == "nfs4"
+}
+
+// parseNFSSource splits an NFS mount source of the form host:/path
+func parseNFSSource(source string) (mnt *nfsMount, err error) {
+	sepIndex := strings.LastIndex(source, ":/")
+	if sepIndex <= 0 {
+		err = errors.New("invalid NFS mount source")
+		return
+	}
+
+	host := source[:sepIndex]
+	// Strip brackets from IPv6 addresses
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	mnt = &nfsMount{
+		host:       host,
+		exportPath: source[sepIndex+1:],
+	}
+	return
+}
+
+// lookupNFSMount checks if path resides on an NFS mount and returns the mount's details if so.
+// A nil result without an error indicates path is not located on an NFS mount.
+func lookupNFSMount(path string) (mnt *nfsMount, err error) {
+	var mountInfo *mount.Info
+	if mountInfo, err = mountInfoForPath(path); err != nil {
+		return
+	}
+
+	if isNFSFilesystem(mountInfo.Fstype) {
+		return parseNFSSource(mountInfo.Source)
+	}
+
+	return
+}
+
+func (m *nfsMount) getQuota(t quotaCtlType, id uint32) (info *Info, err error) {
+	return newRquotaClient(m.host).getQuota(t, m.exportPath, id)
+}
+
+// setQuota sets all limits of id, limits must have been completed via completeLimits
+func (m *nfsMount) setQuota(t quotaCtlType, id uint32, limits *Limits) (info *Info, err error) {
+	return newRquotaClient(m.host).setQuota(t, m.exportPath, id, limits)
+}
@@ -0,0 +1,27 @@
+package fsquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNFSSource(t *testing.T) {
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := parseNFSSource("/dev/sda1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Hostname", func(t *testing.T) {
+		mnt, err := parseNFSSource("fileserver:/export/home")
+		require.NoError(t, err)
+		assert.EqualValues(t, &nfsMount{host: "fileserver", exportPath: "/export/home"}, mnt)
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		mnt, err := parseNFSSource("[fd00::1]:/export")
+		require.NoError(t, err)
+		assert.EqualValues(t, &nfsMount{host: "fd00::1", exportPath: "/export"}, mnt)
+	})
+}
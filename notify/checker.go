@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/speijnik/go-errortree"
+)
+
+// MountConfig configures the notifications of a single filesystem
+type MountConfig struct {
+	// Path of the filesystem
+	Path string
+	// Types of the quotas evaluated, all enabled types if empty
+	Types []fsquota.QuotaType
+	// GraceWarning is how long before the grace period expires ConditionGraceExpiring is reported
+	GraceWarning time.Duration
+	// Notifiers receive a notification per ID with newly exceeded soft limits
+	Notifiers []Notifier
+	// DigestNotifiers receive a single notification listing all exceeded soft limits of the filesystem,
+	// whenever any of them is due to be notified
+	DigestNotifiers []Notifier
+}
+
+// reportFn retrieves the report of a quota type. A nil report without error indicates that the
+// quota type is not enabled.
+type reportFn func(path string, quotaType fsquota.QuotaType) (*fsquota.Report, error)
+
+// Checker evaluates quota reports and sends notifications about exceeded soft limits
+type Checker struct {
+	mounts []*MountConfig
+	state  *State
+	repeat time.Duration
+	host   string
+
+	reportFn reportFn
+	nameFn   func(fsquota.QuotaType, string) string
+	nowFn    func() time.Time
+}
+
+// NewChecker creates a checker for mounts, which must have unique paths, tracking notifications in state.
+// Unchanged conditions are notified again after repeat, or never if repeat is zero.
+func NewChecker(mounts []*MountConfig, state *State, repeat time.Duration) *Checker {
+	host, _ := os.Hostname()
+	return &Checker{
+		mounts:   mounts,
+		state:    state,
+		repeat:   repeat,
+		host:     host,
+		reportFn: fsquota.GetEnabledReport,
+		nameFn:   fsquota.LookupQuotaName,
+		nowFn:    time.Now,
+	}
+}
+
+// Check evaluates all mounts once and sends the notifications due
+func (c *Checker) Check() (err error) {
+	now := c.nowFn()
+
+	for _, mount := range c.mounts {
+		if checkErr := c.checkMount(mount, now); checkErr != nil {
+			err = errortree.Add(err, mount.Path, checkErr)
+		}
+	}
+	return
+}
+
+func (c *Checker) checkMount(mount *MountConfig, now time.Time) (err error) {
+	types := mount.Types
+	if len(types) == 0 {
+		types = fsquota.QuotaTypes()
+	}
+
+	var events []*Event
+	for _, t := range types {
+		report, reportErr := c.reportFn(mount.Path, t)
+		if reportErr != nil {
+			err = errortree.Add(err, t.String(), reportErr)
+			continue
+		}
+		if report == nil {
+			continue
+		}
+
+		typeEvents := evaluate(mount.Path, t, report, now, mount.GraceWarning, c.nameFn)
+		c.state.resolve(mount.Path, t, typeEvents)
+		events = append(events, typeEvents...)
+	}
+
+	// Events of the same ID are notified together
+	var due [][]*Event
+	for _, event := range events {
+		if len(mount.Notifiers) == 0 || !c.state.due(event, now, c.repeat) {
+			continue
+		}
+
+		if n := len(due); n > 0 && due[n-1][0].Type == event.Type && due[n-1][0].ID == event.ID {
+			due[n-1] = append(due[n-1], event)
+		} else {
+			due = append(due, []*Event{event})
+		}
+	}
+
+	// State is only recorded for delivered notifications, so failed ones are retried on the next check
+	for _, idEvents := range due {
+		notifyErr := c.notify(mount.Notifiers, &Notification{
+			Host:   c.host,
+			Path:   mount.Path,
+			Events: idEvents,
+		})
+		if notifyErr != nil {
+			err = errortree.Add(err, idEvents[0].Type.String()+" "+idEvents[0].ID, notifyErr)
+			continue
+		}
+		c.record(idEvents, now)
+	}
+
+	// Digests are tracked separately, so they are only sent when their content is due, regardless of
+	// whether the notifications per ID were delivered
+	if len(mount.DigestNotifiers) == 0 || !c.digestDue(events, now) {
+		return
+	}
+
+	if digestErr := c.notify(mount.DigestNotifiers, &Notification{
+		Host:   c.host,
+		Path:   mount.Path,
+		Digest: true,
+		Events: events,
+	}); digestErr != nil {
+		err = errortree.Add(err, "digest", digestErr)
+		return
+	}
+
+	for _, event := range events {
+		c.state.recordDigest(event, now)
+	}
+	return
+}
+
+// digestDue reports whether any of events is due to be included in a digest at now
+func (c *Checker) digestDue(events []*Event, now time.Time) bool {
+	for _, event := range events {
+		if c.state.digestDue(event, now, c.repeat) {
+			return true
+		}
+	}
+	return false
+}
+
+// record marks events as notified at now
+func (c *Checker) record(events []*Event, now time.Time) {
+	for _, event := range events {
+		c.state.record(event, now)
+	}
+}
+
+func (c *Checker) notify(notifiers []Notifier, n *Notification) (err error) {
+	for i, notifier := range notifiers {
+		if notifyErr := notifier.Notify(n); notifyErr != nil {
+			err = errortree.Add(err, strconv.Itoa(i), notifyErr)
+		}
+	}
+	return
+}
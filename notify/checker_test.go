@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingNotifier struct {
+	notifications []*Notification
+	err           error
+}
+
+func (r *recordingNotifier) Notify(n *Notification) error {
+	r.notifications = append(r.notifications, n)
+	return r.err
+}
+
+func TestChecker_Check(t *testing.T) {
+	now := time.Unix(1000000, 0)
+
+	info := &fsquota.Info{BytesUsed: 150, FilesUsed: 20}
+	info.Bytes.SetSoft(100)
+	info.Files.SetSoft(10)
+	report := &fsquota.Report{Infos: map[string]*fsquota.Info{"1000": info, "1001": {}}}
+
+	perID := &recordingNotifier{}
+	digest := &recordingNotifier{}
+	checker := NewChecker([]*MountConfig{{
+		Path:            "/srv",
+		Types:           []fsquota.QuotaType{fsquota.QuotaTypeUser},
+		Notifiers:       []Notifier{perID},
+		DigestNotifiers: []Notifier{digest},
+	}}, NewState(), 24*time.Hour)
+	checker.host = "storage1"
+	checker.reportFn = func(path string, t fsquota.QuotaType) (*fsquota.Report, error) {
+		return report, nil
+	}
+	checker.nameFn = noName
+	checker.nowFn = func() time.Time {
+		return now
+	}
+
+	assert.NoError(t, checker.Check())
+	if assert.Len(t, perID.notifications, 1) {
+		// Both resources of the same ID are notified together
+		assert.Len(t, perID.notifications[0].Events, 2)
+		assert.False(t, perID.notifications[0].Digest)
+	}
+	if assert.Len(t, digest.notifications, 1) {
+		assert.True(t, digest.notifications[0].Digest)
+		assert.EqualValues(t, "storage1", digest.notifications[0].Host)
+	}
+
+	// Nothing is sent again until the repeat interval has passed
+	now = now.Add(time.Hour)
+	assert.NoError(t, checker.Check())
+	assert.Len(t, perID.notifications, 1)
+	assert.Len(t, digest.notifications, 1)
+
+	now = now.Add(24 * time.Hour)
+	assert.NoError(t, checker.Check())
+	assert.Len(t, perID.notifications, 2)
+	assert.Len(t, digest.notifications, 2)
+
+	// Failed notifications are retried on the next check, without repeating the digest
+	info.Files.SetSoft(100)
+	info.BytesUsed = 300
+	info.BytesGraceExpires = now.Add(-time.Minute)
+	perID.err = errors.New("test error")
+	assert.Error(t, checker.Check())
+	assert.Len(t, perID.notifications, 3)
+	assert.Len(t, perID.notifications[2].Events, 1)
+	assert.Len(t, digest.notifications, 3)
+	assert.Error(t, checker.Check())
+	assert.Len(t, perID.notifications, 4)
+	assert.Len(t, digest.notifications, 3)
+
+	perID.err = nil
+	assert.NoError(t, checker.Check())
+	assert.Len(t, perID.notifications, 5)
+	assert.NoError(t, checker.Check())
+	assert.Len(t, perID.notifications, 5)
+	assert.Len(t, digest.notifications, 3)
+
+	// A failing digest is retried without repeating notifications delivered per ID
+	now = now.Add(25 * time.Hour)
+	digest.err = errors.New("test error")
+	assert.Error(t, checker.Check())
+	assert.Len(t, perID.notifications, 6)
+	assert.Len(t, digest.notifications, 4)
+
+	digest.err = nil
+	assert.NoError(t, checker.Check())
+	assert.Len(t, perID.notifications, 6)
+	assert.Len(t, digest.notifications, 5)
+	assert.NoError(t, checker.Check())
+	assert.Len(t, digest.notifications, 5)
+}
+
+func TestChecker_CheckDigestOnly(t *testing.T) {
+	now := time.Unix(1000000, 0)
+
+	info := &fsquota.Info{BytesUsed: 150}
+	info.Bytes.SetSoft(100)
+	report := &fsquota.Report{Infos: map[string]*fsquota.Info{"1000": info}}
+
+	digest := &recordingNotifier{err: errors.New("test error")}
+	checker := NewChecker([]*MountConfig{{
+		Path:            "/srv",
+		Types:           []fsquota.QuotaType{fsquota.QuotaTypeUser},
+		DigestNotifiers: []Notifier{digest},
+	}}, NewState(), 24*time.Hour)
+	checker.reportFn = func(path string, t fsquota.QuotaType) (*fsquota.Report, error) {
+		return report, nil
+	}
+	checker.nameFn = noName
+	checker.nowFn = func() time.Time {
+		return now
+	}
+
+	// Failed digests are retried on the next check, if they are the only notification
+	assert.Error(t, checker.Check())
+	digest.err = nil
+	assert.NoError(t, checker.Check())
+	assert.Len(t, digest.notifications, 2)
+	assert.NoError(t, checker.Check())
+	assert.Len(t, digest.notifications, 2)
+}
@@ -0,0 +1,125 @@
+// Package notify tells users and administrators about exceeded soft limits and expiring grace periods
+package notify
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+)
+
+// Condition describes the state of a resource exceeding its soft limit
+type Condition string
+
+const (
+	// ConditionOverSoftLimit indicates the soft limit is exceeded
+	ConditionOverSoftLimit Condition = "over-soft-limit"
+	// ConditionGraceExpiring indicates the soft limit is exceeded and the grace period expires soon
+	ConditionGraceExpiring Condition = "grace-expiring"
+	// ConditionGraceExpired indicates the soft limit is exceeded and the grace period has expired,
+	// so the soft limit is enforced like a hard limit
+	ConditionGraceExpired Condition = "grace-expired"
+)
+
+// Resource is the quota resource an event refers to
+type Resource string
+
+const (
+	// ResourceBytes refers to the byte limits
+	ResourceBytes Resource = "bytes"
+	// ResourceFiles refers to the file limits
+	ResourceFiles Resource = "files"
+)
+
+// Event describes a resource of an ID exceeding its soft limit
+type Event struct {
+	Path      string            `json:"path"`
+	Type      fsquota.QuotaType `json:"type"`
+	ID        string            `json:"id"`
+	Name      string            `json:"name,omitempty"`
+	Resource  Resource          `json:"resource"`
+	Condition Condition         `json:"condition"`
+
+	Used uint64 `json:"used"`
+	Soft uint64 `json:"soft"`
+	Hard uint64 `json:"hard"`
+
+	// GraceExpires is the time the soft limit turns into a hard limit, zero if unknown
+	GraceExpires time.Time `json:"grace_expires"`
+}
+
+// Subject returns the name of the ID the event refers to, or the ID if it has no name
+func (e *Event) Subject() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.ID
+}
+
+// key identifies the ID and resource an event refers to
+func (e *Event) key() string {
+	return e.Path + "|" + e.Type.String() + "|" + e.ID + "|" + string(e.Resource)
+}
+
+// condition classifies usage against a soft limit, ok is false if the soft limit is not exceeded
+func condition(used, soft uint64, expires, now time.Time, graceWarning time.Duration) (c Condition, ok bool) {
+	if soft == 0 || used <= soft {
+		return
+	}
+
+	switch {
+	case !expires.IsZero() && !expires.After(now):
+		return ConditionGraceExpired, true
+	case !expires.IsZero() && expires.Sub(now) <= graceWarning:
+		return ConditionGraceExpiring, true
+	}
+	return ConditionOverSoftLimit, true
+}
+
+// evaluate returns the events of all IDs in report exceeding a soft limit at now, sorted by ID
+func evaluate(path string, t fsquota.QuotaType, report *fsquota.Report, now time.Time, graceWarning time.Duration,
+	nameFn func(fsquota.QuotaType, string) string) (events []*Event) {
+	for id, info := range report.Infos {
+		for _, r := range []struct {
+			resource     Resource
+			used         uint64
+			limit        *fsquota.Limit
+			graceExpires time.Time
+		}{
+			{ResourceBytes, info.BytesUsed, &info.Bytes, info.BytesGraceExpires},
+			{ResourceFiles, info.FilesUsed, &info.Files, info.FilesGraceExpires},
+		} {
+			c, ok := condition(r.used, r.limit.GetSoft(), r.graceExpires, now, graceWarning)
+			if !ok {
+				continue
+			}
+
+			events = append(events, &Event{
+				Path:         path,
+				Type:         t,
+				ID:           id,
+				Name:         nameFn(t, id),
+				Resource:     r.resource,
+				Condition:    c,
+				Used:         r.used,
+				Soft:         r.limit.GetSoft(),
+				Hard:         r.limit.GetHard(),
+				GraceExpires: r.graceExpires,
+			})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].ID != events[j].ID {
+			a, aErr := strconv.ParseUint(events[i].ID, 10, 64)
+			b, bErr := strconv.ParseUint(events[j].ID, 10, 64)
+			if aErr != nil || bErr != nil {
+				return events[i].ID < events[j].ID
+			}
+			return a < b
+		}
+		return events[i].Resource < events[j].Resource
+	})
+	return
+}
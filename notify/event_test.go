@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/stretchr/testify/assert"
+)
+
+func noName(fsquota.QuotaType, string) string {
+	return ""
+}
+
+func TestCondition(t *testing.T) {
+	now := time.Unix(1000000, 0)
+
+	_, ok := condition(10, 0, time.Time{}, now, time.Hour)
+	assert.False(t, ok)
+	_, ok = condition(10, 10, time.Time{}, now, time.Hour)
+	assert.False(t, ok)
+
+	c, ok := condition(11, 10, time.Time{}, now, time.Hour)
+	assert.True(t, ok)
+	assert.EqualValues(t, ConditionOverSoftLimit, c)
+
+	c, _ = condition(11, 10, now.Add(2*time.Hour), now, time.Hour)
+	assert.EqualValues(t, ConditionOverSoftLimit, c)
+
+	c, _ = condition(11, 10, now.Add(time.Hour), now, time.Hour)
+	assert.EqualValues(t, ConditionGraceExpiring, c)
+
+	c, _ = condition(11, 10, now, now, time.Hour)
+	assert.EqualValues(t, ConditionGraceExpired, c)
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Unix(1000000, 0)
+
+	overBoth := &fsquota.Info{BytesUsed: 150, FilesUsed: 20}
+	overBoth.Bytes.SetSoft(100)
+	overBoth.Files.SetSoft(10)
+	overBoth.FilesGraceExpires = now.Add(-time.Minute)
+
+	withinLimits := &fsquota.Info{BytesUsed: 50}
+	withinLimits.Bytes.SetSoft(100)
+
+	overBytes := &fsquota.Info{BytesUsed: 150}
+	overBytes.Bytes.SetSoft(100)
+	overBytes.Bytes.SetHard(200)
+
+	report := &fsquota.Report{Infos: map[string]*fsquota.Info{
+		"1000": overBoth,
+		"999":  withinLimits,
+		"20":   overBytes,
+	}}
+
+	events := evaluate("/srv", fsquota.QuotaTypeUser, report, now, time.Hour, func(t fsquota.QuotaType, id string) string {
+		if id == "1000" {
+			return "alice"
+		}
+		return ""
+	})
+
+	if assert.Len(t, events, 3) {
+		assert.EqualValues(t, &Event{Path: "/srv", Type: fsquota.QuotaTypeUser, ID: "20", Resource: ResourceBytes,
+			Condition: ConditionOverSoftLimit, Used: 150, Soft: 100, Hard: 200}, events[0])
+		assert.EqualValues(t, "1000", events[1].ID)
+		assert.EqualValues(t, ResourceBytes, events[1].Resource)
+		assert.EqualValues(t, "alice", events[1].Subject())
+		assert.EqualValues(t, ResourceFiles, events[2].Resource)
+		assert.EqualValues(t, ConditionGraceExpired, events[2].Condition)
+	}
+}
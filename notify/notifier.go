@@ -0,0 +1,231 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Notification is a set of events delivered by a notifier. Unless it is a digest, all events
+// refer to the same ID.
+type Notification struct {
+	Host   string   `json:"host"`
+	Path   string   `json:"path"`
+	Digest bool     `json:"digest"`
+	Events []*Event `json:"events"`
+}
+
+// Event returns the first event, which is the subject of notifications that are not digests
+func (n *Notification) Event() *Event {
+	if len(n.Events) == 0 {
+		return &Event{}
+	}
+	return n.Events[0]
+}
+
+// Notifier delivers notifications
+type Notifier interface {
+	Notify(n *Notification) error
+}
+
+var templateFuncs = template.FuncMap{
+	"bytes": humanize.IBytes,
+	"files": func(v uint64) string {
+		return humanize.Comma(int64(v))
+	},
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return "unknown"
+		}
+		return t.Format(time.RFC1123)
+	},
+}
+
+// DefaultSubject is the default email subject template
+const DefaultSubject = `{{if .Digest}}Quota digest for {{.Path}} on {{.Host}}: {{len .Events}} soft limits exceeded` +
+	`{{else}}Quota soft limit exceeded on {{.Path}}{{end}}`
+
+// DefaultBody is the default email body template
+const DefaultBody = `{{if .Digest}}The following soft limits are exceeded on {{.Path}} on {{.Host}}:
+{{else}}Hello {{.Event.Subject}},
+
+you exceed the following soft limits on {{.Path}} on {{.Host}}:
+{{end}}
+{{range .Events}}{{if $.Digest}}{{.Type}} {{.Subject}}: {{end}}{{.Resource}} {{.Condition}}, used {{if eq .Resource "bytes"}}{{bytes .Used}} of {{bytes .Soft}} (hard limit {{bytes .Hard}}){{else}}{{files .Used}} of {{files .Soft}} (hard limit {{files .Hard}}){{end}}, grace period ends {{time .GraceExpires}}
+{{end}}{{if not .Digest}}
+Once the grace period ends, no further data can be written until usage drops below the soft limit.
+{{end}}`
+
+// parseTemplate parses a notification template, text defaults to fallback if empty
+func parseTemplate(name, text, fallback string) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+func render(t *template.Template, n *Notification) (s string, err error) {
+	buffer := &bytes.Buffer{}
+	if err = t.Execute(buffer, n); err == nil {
+		s = buffer.String()
+	}
+	return
+}
+
+// EmailNotifier sends notifications via SMTP
+type EmailNotifier struct {
+	addr    string
+	auth    smtp.Auth
+	from    string
+	to      *template.Template
+	subject *template.Template
+	body    *template.Template
+}
+
+// NewEmailNotifier creates a notifier sending mail through the SMTP server at addr. to, subject and body are
+// templates executed with the Notification; to renders a comma-separated list of recipients and notifications
+// rendering none are skipped. Empty subject and body templates default to DefaultSubject and DefaultBody.
+func NewEmailNotifier(addr string, auth smtp.Auth, from, to, subject, body string) (n *EmailNotifier, err error) {
+	n = &EmailNotifier{
+		addr: addr,
+		auth: auth,
+		from: from,
+	}
+
+	if n.to, err = parseTemplate("to", to, ""); err == nil {
+		if n.subject, err = parseTemplate("subject", subject, DefaultSubject); err == nil {
+			n.body, err = parseTemplate("body", body, DefaultBody)
+		}
+	}
+
+	if err != nil {
+		n = nil
+	}
+	return
+}
+
+// Notify implements Notifier
+func (e *EmailNotifier) Notify(n *Notification) (err error) {
+	var to, subject, body string
+	if to, err = render(e.to, n); err != nil {
+		return
+	}
+	if subject, err = render(e.subject, n); err != nil {
+		return
+	}
+	if body, err = render(e.body, n); err != nil {
+		return
+	}
+
+	var recipients []string
+	for _, recipient := range strings.Split(to, ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	message := &bytes.Buffer{}
+	fmt.Fprintf(message, "From: %s\r\n", e.from)
+	fmt.Fprintf(message, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(message, "Subject: %s\r\n", strings.TrimSpace(subject))
+	fmt.Fprintf(message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(strings.Replace(strings.Replace(body, "\r\n", "\n", -1), "\n", "\r\n", -1))
+
+	return smtp.SendMail(e.addr, e.auth, e.from, recipients, message.Bytes())
+}
+
+// WebhookNotifier posts notifications as JSON to a URL
+type WebhookNotifier struct {
+	URL string
+	// Headers added to every request, ie. for authentication
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url
+func NewWebhookNotifier(url string, headers map[string]string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:     url,
+		Headers: headers,
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(n *Notification) (err error) {
+	var data []byte
+	if data, err = json.Marshal(n); err != nil {
+		return
+	}
+
+	var request *http.Request
+	if request, err = http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(data)); err != nil {
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range w.Headers {
+		request.Header.Set(name, value)
+	}
+
+	var response *http.Response
+	if response, err = w.Client.Do(request); err != nil {
+		return
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		err = fmt.Errorf("webhook returned %s", response.Status)
+	}
+	return
+}
+
+// ExecNotifier runs a command for every notification. The notification is passed as JSON on stdin; the
+// details of non-digest notifications are also passed in FSQM_* environment variables.
+type ExecNotifier struct {
+	Command string
+	Args    []string
+}
+
+// Notify implements Notifier
+func (x *ExecNotifier) Notify(n *Notification) (err error) {
+	var data []byte
+	if data, err = json.Marshal(n); err != nil {
+		return
+	}
+
+	cmd := exec.Command(x.Command, x.Args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("FSQM_PATH=%s", n.Path),
+		fmt.Sprintf("FSQM_DIGEST=%t", n.Digest),
+	)
+	if !n.Digest {
+		event := n.Event()
+		cmd.Env = append(cmd.Env,
+			fmt.Sprintf("FSQM_QUOTA_TYPE=%s", event.Type),
+			fmt.Sprintf("FSQM_ID=%s", event.ID),
+			fmt.Sprintf("FSQM_NAME=%s", event.Name),
+		)
+	}
+
+	if output, runErr := cmd.CombinedOutput(); runErr != nil {
+		err = fmt.Errorf("%s: %s: %s", x.Command, runErr, strings.TrimSpace(string(output)))
+	}
+	return
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpStub accepts a single SMTP session and returns the recipients and data of the mail sent
+func smtpStub(t *testing.T) (addr string, result <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ch := make(chan []string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var session []string
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("220 localhost ESMTP stub\r\n"))
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+			switch command {
+			case "EHLO", "HELO":
+				conn.Write([]byte("250 localhost\r\n"))
+			case "MAIL":
+				conn.Write([]byte("250 OK\r\n"))
+			case "RCPT":
+				session = append(session, line)
+				conn.Write([]byte("250 OK\r\n"))
+			case "DATA":
+				conn.Write([]byte("354 go ahead\r\n"))
+				var data []string
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data = append(data, dataLine)
+				}
+				session = append(session, strings.Join(data, ""))
+				conn.Write([]byte("250 OK\r\n"))
+			case "QUIT":
+				conn.Write([]byte("221 bye\r\n"))
+				ch <- session
+				return
+			default:
+				conn.Write([]byte("250 OK\r\n"))
+			}
+		}
+	}()
+
+	return listener.Addr().String(), ch
+}
+
+func testNotification() *Notification {
+	return &Notification{
+		Host: "storage1",
+		Path: "/srv",
+		Events: []*Event{
+			{Path: "/srv", Type: fsquota.QuotaTypeUser, ID: "1000", Name: "alice", Resource: ResourceBytes,
+				Condition: ConditionGraceExpiring, Used: 2048, Soft: 1024, Hard: 4096, GraceExpires: time.Unix(1000000, 0)},
+			{Path: "/srv", Type: fsquota.QuotaTypeUser, ID: "1000", Name: "alice", Resource: ResourceFiles,
+				Condition: ConditionOverSoftLimit, Used: 12000, Soft: 10000},
+		},
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	addr, result := smtpStub(t)
+
+	notifier, err := NewEmailNotifier(addr, nil, "quota@example.com", "{{.Event.Name}}@example.com", "", "")
+	require.NoError(t, err)
+	require.NoError(t, notifier.Notify(testNotification()))
+
+	select {
+	case session := <-result:
+		require.Len(t, session, 2)
+		assert.EqualValues(t, "RCPT TO:<alice@example.com>", session[0])
+		assert.Contains(t, session[1], "Subject: Quota soft limit exceeded on /srv\r\n")
+		assert.Contains(t, session[1], "Hello alice,\r\n")
+		assert.Contains(t, session[1], "bytes grace-expiring, used 2.0 KiB of 1.0 KiB (hard limit 4.0 KiB)")
+		assert.Contains(t, session[1], "files over-soft-limit, used 12,000 of 10,000 (hard limit 0), grace period ends unknown")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+}
+
+func TestEmailNotifier_NoRecipients(t *testing.T) {
+	notifier, err := NewEmailNotifier("127.0.0.1:1", nil, "quota@example.com", "{{with .Event.Name}}{{.}}@example.com{{end}}", "", "")
+	require.NoError(t, err)
+
+	// Nothing is sent for IDs without a name
+	assert.NoError(t, notifier.Notify(&Notification{Events: []*Event{{ID: "1000"}}}))
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan *Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		assert.EqualValues(t, "secret", r.Header.Get("X-Token"))
+		n := &Notification{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(n))
+		received <- n
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, map[string]string{"X-Token": "secret"})
+	require.NoError(t, notifier.Notify(testNotification()))
+
+	n := <-received
+	assert.EqualValues(t, "storage1", n.Host)
+	if assert.Len(t, n.Events, 2) {
+		assert.EqualValues(t, fsquota.QuotaTypeUser, n.Events[0].Type)
+		assert.EqualValues(t, ConditionGraceExpiring, n.Events[0].Condition)
+	}
+
+	assert.Error(t, NewWebhookNotifier(server.URL+"/missing", nil).Notify(testNotification()))
+}
+
+func TestExecNotifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsquota-notify")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "output")
+	notifier := &ExecNotifier{
+		Command: "/bin/sh",
+		Args:    []string{"-c", `echo "$FSQM_QUOTA_TYPE $FSQM_ID $FSQM_NAME" > "$0"; cat >> "$0"`, output},
+	}
+	require.NoError(t, notifier.Notify(testNotification()))
+
+	data, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	lines := strings.SplitN(string(data), "\n", 2)
+	assert.EqualValues(t, "user 1000 alice", lines[0])
+	assert.Contains(t, lines[1], `"digest":false`)
+
+	assert.Error(t, (&ExecNotifier{Command: "/bin/false"}).Notify(testNotification()))
+}
@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+)
+
+// DefaultStateFile is the file the notification state is kept in by default
+const DefaultStateFile = "/var/lib/fsqm/notify-state.json"
+
+// StateEntry records the last notification sent about a resource of an ID
+type StateEntry struct {
+	Path       string            `json:"path"`
+	Type       fsquota.QuotaType `json:"type"`
+	Condition  Condition         `json:"condition"`
+	NotifiedAt time.Time         `json:"notified_at"`
+}
+
+// State tracks the notifications sent, so unchanged conditions are not notified again on every check
+type State struct {
+	Entries map[string]*StateEntry `json:"entries"`
+}
+
+// NewState creates an empty state
+func NewState() *State {
+	return &State{
+		Entries: make(map[string]*StateEntry),
+	}
+}
+
+// LoadState reads the state from fileName. A missing file yields an empty state.
+func LoadState(fileName string) (state *State, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(fileName); os.IsNotExist(err) {
+		return NewState(), nil
+	} else if err != nil {
+		return
+	}
+
+	state = NewState()
+	if err = json.Unmarshal(data, state); err != nil {
+		state = nil
+		return
+	}
+	if state.Entries == nil {
+		state.Entries = make(map[string]*StateEntry)
+	}
+	return
+}
+
+// Save writes the state to fileName, replacing it atomically
+func (s *State) Save(fileName string) (err error) {
+	var data []byte
+	if data, err = json.Marshal(s); err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return
+	}
+
+	var tmp *os.File
+	if tmp, err = ioutil.TempFile(filepath.Dir(fileName), "."+filepath.Base(fileName)); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
+
+// digestKey returns the key of the entry tracking the digest notifications about event
+func digestKey(event *Event) string {
+	return "digest|" + event.key()
+}
+
+// due reports whether event has to be notified at now. Events are notified when their condition changes
+// and, if repeat is positive, again after repeat has passed.
+func (s *State) due(event *Event, now time.Time, repeat time.Duration) bool {
+	return s.dueEntry(event.key(), event, now, repeat)
+}
+
+// digestDue reports whether event has to be included in a digest at now, see due
+func (s *State) digestDue(event *Event, now time.Time, repeat time.Duration) bool {
+	return s.dueEntry(digestKey(event), event, now, repeat)
+}
+
+func (s *State) dueEntry(key string, event *Event, now time.Time, repeat time.Duration) bool {
+	entry, ok := s.Entries[key]
+	if !ok || entry.Condition != event.Condition {
+		return true
+	}
+	return repeat > 0 && now.Sub(entry.NotifiedAt) >= repeat
+}
+
+// record marks event as notified at now
+func (s *State) record(event *Event, now time.Time) {
+	s.recordEntry(event.key(), event, now)
+}
+
+// recordDigest marks event as included in a digest at now
+func (s *State) recordDigest(event *Event, now time.Time) {
+	s.recordEntry(digestKey(event), event, now)
+}
+
+func (s *State) recordEntry(key string, event *Event, now time.Time) {
+	s.Entries[key] = &StateEntry{
+		Path:       event.Path,
+		Type:       event.Type,
+		Condition:  event.Condition,
+		NotifiedAt: now,
+	}
+}
+
+// resolve removes the entries of path and t without a current event, so they are notified again
+// once they exceed their soft limit again
+func (s *State) resolve(path string, t fsquota.QuotaType, events []*Event) {
+	current := make(map[string]bool)
+	for _, event := range events {
+		current[event.key()] = true
+		current[digestKey(event)] = true
+	}
+
+	for key, entry := range s.Entries {
+		if entry.Path == path && entry.Type == t && !current[key] {
+			delete(s.Entries, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/fsquota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	state := NewState()
+	event := &Event{Path: "/srv", Type: fsquota.QuotaTypeUser, ID: "1000", Resource: ResourceBytes, Condition: ConditionOverSoftLimit}
+
+	assert.True(t, state.due(event, now, 0))
+	state.record(event, now)
+	assert.False(t, state.due(event, now.Add(time.Hour), 0))
+
+	// Repeated after the repeat interval
+	assert.False(t, state.due(event, now.Add(time.Hour), 2*time.Hour))
+	assert.True(t, state.due(event, now.Add(2*time.Hour), 2*time.Hour))
+
+	// Notified again when the condition changes
+	escalated := *event
+	escalated.Condition = ConditionGraceExpiring
+	assert.True(t, state.due(&escalated, now, 0))
+
+	// Digests are tracked separately
+	assert.True(t, state.digestDue(event, now, 0))
+	state.recordDigest(event, now)
+	assert.False(t, state.digestDue(event, now, 0))
+
+	// Resolved conditions are forgotten, other paths and types are kept
+	other := &Event{Path: "/home", Type: fsquota.QuotaTypeUser, ID: "1000", Resource: ResourceBytes, Condition: ConditionOverSoftLimit}
+	state.record(other, now)
+	state.resolve("/srv", fsquota.QuotaTypeGroup, nil)
+	assert.Len(t, state.Entries, 3)
+	state.resolve("/srv", fsquota.QuotaTypeUser, []*Event{event})
+	assert.Len(t, state.Entries, 3)
+	state.resolve("/srv", fsquota.QuotaTypeUser, nil)
+	assert.Len(t, state.Entries, 1)
+	assert.True(t, state.digestDue(event, now, 0))
+	assert.True(t, state.due(event, now, 0))
+	assert.False(t, state.due(other, now, 0))
+}
+
+func TestState_SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsquota-notify")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "state", "notify.json")
+	state, err := LoadState(fileName)
+	require.NoError(t, err)
+	assert.Len(t, state.Entries, 0)
+
+	now := time.Unix(1000000, 0).UTC()
+	event := &Event{Path: "/srv", Type: fsquota.QuotaTypeGroup, ID: "100", Resource: ResourceFiles, Condition: ConditionGraceExpired}
+	state.record(event, now)
+	require.NoError(t, state.Save(fileName))
+
+	loaded, err := LoadState(fileName)
+	require.NoError(t, err)
+	assert.EqualValues(t, state, loaded)
+}
@@ -0,0 +1,79 @@
+package fsquota
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// getentKeyNotFound is the exit status of getent if a key does not exist in the database
+const getentKeyNotFound = 2
+
+// getent queries an NSS database such as passwd or group via getent(1). Unlike os/user in builds without cgo,
+// this covers all sources configured in nsswitch.conf, ie. LDAP or SSSD.
+// found is false if the key does not exist. All other failures, including getent not being available, are returned
+// as errors, as the database could not be consulted.
+func getent(database, key string) (output []byte, found bool, err error) {
+	if output, err = exec.Command("getent", database, key).Output(); err == nil {
+		found = true
+		return
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == getentKeyNotFound {
+		output = nil
+		err = nil
+		return
+	}
+
+	output = nil
+	err = fmt.Errorf("looking up %s in the %s database: %s", key, database, err)
+	return
+}
+
+// lookupNSSEntry looks up key, a name or numeric ID, in the passwd or group database via getent and returns the name
+// and ID of the entry found
+func lookupNSSEntry(database, key string) (name, id string, found bool, err error) {
+	var output []byte
+	if output, found, err = getent(database, key); !found {
+		return
+	}
+
+	// Entries are in name:password:id:... format
+	fields := strings.Split(strings.SplitN(string(output), "\n", 2)[0], ":")
+	if len(fields) < 3 {
+		found = false
+		err = fmt.Errorf("unexpected %s entry for %s", database, key)
+		return
+	}
+
+	name, id = fields[0], fields[2]
+	return
+}
+
+// listNSSIDs returns the IDs of the passwd or group database. Besides file, entries are enumerated via getent,
+// which covers NSS sources allowing enumeration. IDs may be returned more than once.
+func listNSSIDs(database, file string) (ids []uint32, err error) {
+	if ids, err = getIDsFromUserOrGroupFile(file); err != nil {
+		return
+	}
+
+	output, getentErr := exec.Command("getent", database).Output()
+	if getentErr != nil && !isExecNotFound(getentErr) {
+		err = fmt.Errorf("enumerating the %s database: %s", database, getentErr)
+		return
+	}
+
+	var nssIDs []uint32
+	if nssIDs, err = readIDsFromUserOrGroupFile(bytes.NewReader(output)); err != nil {
+		return
+	}
+	ids = append(ids, nssIDs...)
+	return
+}
+
+// isExecNotFound reports whether err indicates the executable could not be found
+func isExecNotFound(err error) bool {
+	execErr, ok := err.(*exec.Error)
+	return ok && execErr.Err == exec.ErrNotFound
+}
@@ -0,0 +1,104 @@
+package fsquota
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGetent installs a getent script in PATH for the duration of a test
+func fakeGetent(t *testing.T, script string) {
+	dirName, err := ioutil.TempDir("", "fsquota-test-")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dirName)
+	})
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirName, "getent"), []byte("#!/bin/sh\n"+script), 0755))
+	t.Setenv("PATH", dirName)
+}
+
+func TestGetent(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		fakeGetent(t, `echo "alice:x:$2:$2::/home/alice:/bin/sh"`)
+
+		output, found, err := getent("passwd", "1000")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.EqualValues(t, "alice:x:1000:1000::/home/alice:/bin/sh\n", string(output))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		fakeGetent(t, "exit 2")
+
+		output, found, err := getent("passwd", "1000")
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Nil(t, output)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		fakeGetent(t, "exit 1")
+
+		_, found, err := getent("group", "100")
+		assert.Error(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		t.Setenv("PATH", "")
+
+		_, found, err := getent("group", "100")
+		assert.Error(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestLookupNSSEntry(t *testing.T) {
+	fakeGetent(t, `case "$2" in
+alice|1000) echo "alice:x:1000:1000::/home/alice:/bin/sh" ;;
+broken) echo "broken" ;;
+*) exit 2 ;;
+esac`)
+
+	for _, key := range []string{"alice", "1000"} {
+		name, id, found, err := lookupNSSEntry("passwd", key)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.EqualValues(t, "alice", name)
+		assert.EqualValues(t, "1000", id)
+	}
+
+	_, _, found, err := lookupNSSEntry("passwd", "bob")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	_, _, found, err = lookupNSSEntry("passwd", "broken")
+	assert.Error(t, err)
+	assert.False(t, found)
+}
+
+func TestListNSSIDs(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "passwd")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte("root:x:0:0::/root:/bin/sh\n"), 0644))
+
+	fakeGetent(t, `echo "root:x:0:0::/root:/bin/sh"
+echo "alice:x:1000:1000::/home/alice:/bin/sh"`)
+	ids, err := listNSSIDs("passwd", fileName)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []uint32{0, 0, 1000}, ids)
+
+	fakeGetent(t, "exit 1")
+	_, err = listNSSIDs("passwd", fileName)
+	assert.Error(t, err)
+
+	// Only the file is consulted without getent
+	t.Setenv("PATH", "")
+	ids, err = listNSSIDs("passwd", fileName)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []uint32{0}, ids)
+}
@@ -0,0 +1,66 @@
+package fsquota
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// QuotaPlan is a named set of user limits per filesystem
+type QuotaPlan struct {
+	Name string
+	// Limits keyed by a path on the filesystem they apply to
+	Limits map[string]*Limits
+}
+
+// IDRange is an inclusive range of user or group IDs
+type IDRange struct {
+	Min uint32
+	Max uint32
+}
+
+func (r *IDRange) contains(id uint32) bool {
+	return id >= r.Min && id <= r.Max
+}
+
+// AssignmentRule assigns a plan to all users matching every condition set on the rule
+type AssignmentRule struct {
+	// Name of the plan assigned to matching users
+	Plan string
+
+	// Group the user must be a member of, either by name or numeric ID
+	Group string
+	// Range the UID of the user must be within
+	UIDRange *IDRange
+	// Range the primary GID of the user must be within
+	GIDRange *IDRange
+	// Regular expression the whole user name must match
+	NamePattern string
+}
+
+// PlanAssignment records the plan assigned to a user
+type PlanAssignment struct {
+	UID      string
+	Username string
+	Plan     string
+}
+
+// UnresolvedUsersError is returned if some users could not be looked up. Plans are still assigned to all other users.
+type UnresolvedUsersError struct {
+	// Errors keyed by UID
+	Errors map[string]error
+}
+
+func (e *UnresolvedUsersError) Error() string {
+	uids := make([]string, 0, len(e.Errors))
+	for uid := range e.Errors {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	messages := make([]string, len(uids))
+	for i, uid := range uids {
+		messages[i] = fmt.Sprintf("%s: %s", uid, e.Errors[uid])
+	}
+	return "users could not be looked up: " + strings.Join(messages, "; ")
+}
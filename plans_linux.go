@@ -0,0 +1,285 @@
+package fsquota
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/speijnik/go-errortree"
+)
+
+// planAccount holds the properties of a user assignment rules are matched against
+type planAccount struct {
+	uid      uint32
+	gid      uint32
+	username string
+	groupIDs []string
+}
+
+// compiledRule is an assignment rule with its group and pattern resolved
+type compiledRule struct {
+	*AssignmentRule
+	gid     string
+	pattern *regexp.Regexp
+}
+
+func (r *compiledRule) matches(account *planAccount) bool {
+	if r.UIDRange != nil && !r.UIDRange.contains(account.uid) {
+		return false
+	}
+
+	if r.GIDRange != nil && !r.GIDRange.contains(account.gid) {
+		return false
+	}
+
+	if r.pattern != nil && !r.pattern.MatchString(account.username) {
+		return false
+	}
+
+	if r.gid != "" {
+		member := strconv.FormatUint(uint64(account.gid), 10) == r.gid
+		for _, gid := range account.groupIDs {
+			member = member || gid == r.gid
+		}
+		if !member {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compileRules validates rules against the available plans and resolves their groups and patterns
+func compileRules(plans map[string]*QuotaPlan, rules []*AssignmentRule, lookupGroupFn func(string) (string, error)) (compiled []*compiledRule, err error) {
+	for i, rule := range rules {
+		key := strconv.Itoa(i)
+		c := &compiledRule{AssignmentRule: rule}
+
+		if _, ok := plans[rule.Plan]; !ok {
+			err = errortree.Add(err, key, fmt.Errorf("unknown plan %q", rule.Plan))
+		}
+
+		if rule.UIDRange != nil && rule.UIDRange.Min > rule.UIDRange.Max {
+			err = errortree.Add(err, key, errors.New("invalid UID range"))
+		}
+
+		if rule.GIDRange != nil && rule.GIDRange.Min > rule.GIDRange.Max {
+			err = errortree.Add(err, key, errors.New("invalid GID range"))
+		}
+
+		if rule.NamePattern != "" {
+			var compileErr error
+			// The pattern has to match the whole name, like the patterns of desired states
+			if c.pattern, compileErr = regexp.Compile("^(?:" + rule.NamePattern + ")$"); compileErr != nil {
+				err = errortree.Add(err, key, compileErr)
+			}
+		}
+
+		if rule.Group != "" {
+			if _, parseErr := parseID(rule.Group); parseErr == nil {
+				c.gid = rule.Group
+			} else {
+				var lookupErr error
+				if c.gid, lookupErr = lookupGroupFn(rule.Group); lookupErr != nil {
+					err = errortree.Add(err, key, lookupErr)
+				}
+			}
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	if err != nil {
+		compiled = nil
+	}
+	return
+}
+
+// assignPlan returns the plan of the first rule matching account
+func assignPlan(rules []*compiledRule, account *planAccount) (plan string, ok bool) {
+	for _, rule := range rules {
+		if rule.matches(account) {
+			return rule.Plan, true
+		}
+	}
+	return
+}
+
+// planChanges builds the quota changes applying the assigned plans
+func planChanges(plans map[string]*QuotaPlan, assignments []*PlanAssignment) (changes []*QuotaChange) {
+	for _, assignment := range assignments {
+		plan := plans[assignment.Plan]
+
+		paths := make([]string, 0, len(plan.Limits))
+		for path := range plan.Limits {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			change := &QuotaChange{
+				Path: path,
+				Type: QuotaTypeUser,
+				ID:   assignment.UID,
+			}
+			change.Limits.merge(plan.Limits[path])
+			changes = append(changes, change)
+		}
+	}
+	return
+}
+
+// listPlanUserIDs returns the UIDs of all users of the user database, in ascending order.
+// Besides the user database, users are enumerated via the quota reports of paths, which cover all users owning
+// files there.
+func listPlanUserIDs(paths []string) (uids []uint32, err error) {
+	if uids, err = listNSSIDs("passwd", passwdFile); err != nil {
+		return
+	}
+
+	for _, path := range paths {
+		report, reportErr := getUserReport(path)
+		if reportErr != nil {
+			err = errortree.Add(err, path, reportErr)
+			continue
+		}
+
+		for idString := range report.Infos {
+			if id, parseErr := parseID(idString); parseErr == nil {
+				uids = append(uids, id)
+			}
+		}
+	}
+
+	if err != nil {
+		uids = nil
+		return
+	}
+
+	sort.Slice(uids, func(i, j int) bool {
+		return uids[i] < uids[j]
+	})
+
+	unique := uids[:0]
+	for i, uid := range uids {
+		if i == 0 || uid != uids[i-1] {
+			unique = append(unique, uid)
+		}
+	}
+	uids = unique
+	return
+}
+
+// lookupPlanAccounts looks up the users with the given UIDs. Users which cannot be looked up are returned in
+// unresolved, keyed by UID.
+func lookupPlanAccounts(uids []uint32, lookupFn func(uid string) (*user.User, error)) (accounts []*planAccount, unresolved map[string]error) {
+	for _, uid := range uids {
+		uidString := strconv.FormatUint(uint64(uid), 10)
+		u, lookupErr := lookupFn(uidString)
+		if lookupErr != nil {
+			if unresolved == nil {
+				unresolved = make(map[string]error)
+			}
+			unresolved[uidString] = lookupErr
+			continue
+		}
+
+		account := &planAccount{
+			uid:      uid,
+			username: u.Username,
+		}
+
+		gid, _ := parseID(u.Gid)
+		account.gid = gid
+
+		if account.groupIDs, lookupErr = u.GroupIds(); lookupErr != nil {
+			if unresolved == nil {
+				unresolved = make(map[string]error)
+			}
+			unresolved[uidString] = lookupErr
+			continue
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return
+}
+
+func assignQuotaPlans(plans []*QuotaPlan, rules []*AssignmentRule) (planMap map[string]*QuotaPlan, assignments []*PlanAssignment, err error) {
+	planMap = make(map[string]*QuotaPlan, len(plans))
+	for _, plan := range plans {
+		if _, duplicate := planMap[plan.Name]; duplicate {
+			err = errortree.Add(err, plan.Name, errors.New("plan is declared more than once"))
+			continue
+		}
+		planMap[plan.Name] = plan
+	}
+
+	if err != nil {
+		return
+	}
+
+	var compiled []*compiledRule
+	if compiled, err = compileRules(planMap, rules, groupDatabase.lookupFn); err != nil {
+		return
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, plan := range planMap {
+		for path := range plan.Limits {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	var uids []uint32
+	if uids, err = listPlanUserIDs(paths); err != nil {
+		return
+	}
+
+	accounts, unresolved := lookupPlanAccounts(uids, user.LookupId)
+
+	for _, account := range accounts {
+		if plan, ok := assignPlan(compiled, account); ok {
+			assignments = append(assignments, &PlanAssignment{
+				UID:      strconv.FormatUint(uint64(account.uid), 10),
+				Username: account.username,
+				Plan:     plan,
+			})
+		}
+	}
+
+	if unresolved != nil {
+		err = &UnresolvedUsersError{Errors: unresolved}
+	}
+	return
+}
+
+func applyQuotaPlans(plans []*QuotaPlan, rules []*AssignmentRule, opts []SetOption) (assignments []*PlanAssignment, err error) {
+	var planMap map[string]*QuotaPlan
+	var unresolvedErr *UnresolvedUsersError
+	if planMap, assignments, err = assignQuotaPlans(plans, rules); err != nil {
+		var ok bool
+		if unresolvedErr, ok = err.(*UnresolvedUsersError); !ok {
+			assignments = nil
+			return
+		}
+	}
+
+	if err = applyQuotaChanges(planChanges(planMap, assignments), getQuota, optionsSetQuota(opts), policyCheck(opts)); err != nil {
+		assignments = nil
+		return
+	}
+
+	if unresolvedErr != nil {
+		err = unresolvedErr
+	}
+	return
+}
@@ -0,0 +1,127 @@
+package fsquota
+
+import (
+	"errors"
+	"os/user"
+	"testing"
+
+	"github.com/speijnik/go-errortree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLookupGroup(name string) (string, error) {
+	if name == "staff" {
+		return "50", nil
+	}
+	return "", errors.New("unknown group")
+}
+
+func TestCompileRules(t *testing.T) {
+	plans := map[string]*QuotaPlan{
+		"students": {Name: "students"},
+	}
+
+	compiled, err := compileRules(plans, []*AssignmentRule{
+		{Plan: "students", Group: "staff", NamePattern: "^s[0-9]+$"},
+		{Plan: "unknown"},
+		{Plan: "students", UIDRange: &IDRange{Min: 10, Max: 5}},
+		{Plan: "students", GIDRange: &IDRange{Min: 10, Max: 5}},
+		{Plan: "students", NamePattern: "("},
+		{Plan: "students", Group: "nonexistent"},
+	}, testLookupGroup)
+	assert.Nil(t, compiled)
+	if assert.Error(t, err) {
+		assert.Nil(t, errortree.Get(err, "0"))
+		for _, key := range []string{"1", "2", "3", "4", "5"} {
+			assert.Error(t, errortree.Get(err, key), key)
+		}
+	}
+}
+
+func TestAssignPlan(t *testing.T) {
+	plans := map[string]*QuotaPlan{
+		"staff":    {Name: "staff"},
+		"service":  {Name: "service"},
+		"students": {Name: "students"},
+	}
+
+	rules, err := compileRules(plans, []*AssignmentRule{
+		{Plan: "staff", Group: "staff"},
+		{Plan: "service", UIDRange: &IDRange{Min: 900, Max: 999}},
+		{Plan: "students", NamePattern: "s[0-9]+", GIDRange: &IDRange{Min: 100, Max: 100}},
+	}, testLookupGroup)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		account *planAccount
+		plan    string
+	}{
+		{&planAccount{uid: 1000, gid: 100, username: "alice", groupIDs: []string{"100", "50"}}, "staff"},
+		{&planAccount{uid: 950, gid: 50, username: "backup"}, "staff"},
+		{&planAccount{uid: 950, gid: 100, username: "backup"}, "service"},
+		{&planAccount{uid: 2000, gid: 100, username: "s1234"}, "students"},
+		{&planAccount{uid: 2000, gid: 101, username: "s1234"}, ""},
+		{&planAccount{uid: 2000, gid: 100, username: "bob"}, ""},
+		{&planAccount{uid: 2000, gid: 100, username: "s1234x"}, ""},
+		{&planAccount{uid: 2000, gid: 100, username: "xs1234"}, ""},
+	}
+
+	for _, tc := range testCases {
+		plan, ok := assignPlan(rules, tc.account)
+		assert.EqualValues(t, tc.plan != "", ok, tc.account.username)
+		assert.EqualValues(t, tc.plan, plan, tc.account.username)
+	}
+}
+
+func TestPlanChanges(t *testing.T) {
+	home := &Limits{}
+	home.Bytes.SetHard(100)
+	scratch := &Limits{}
+	scratch.Files.SetHard(10)
+
+	plans := map[string]*QuotaPlan{
+		"students": {
+			Name: "students",
+			Limits: map[string]*Limits{
+				"/scratch": scratch,
+				"/home":    home,
+			},
+		},
+	}
+
+	changes := planChanges(plans, []*PlanAssignment{
+		{UID: "1000", Username: "alice", Plan: "students"},
+	})
+	require.Len(t, changes, 2)
+
+	assert.EqualValues(t, "/home", changes[0].Path)
+	assert.EqualValues(t, QuotaTypeUser, changes[0].Type)
+	assert.EqualValues(t, "1000", changes[0].ID)
+	assert.EqualValues(t, 100, changes[0].Limits.Bytes.GetHard())
+
+	assert.EqualValues(t, "/scratch", changes[1].Path)
+	assert.EqualValues(t, 10, changes[1].Limits.Files.GetHard())
+	_, _, haveBytes := changes[1].Limits.Bytes.getValues()
+	assert.False(t, haveBytes)
+}
+
+func TestLookupPlanAccounts(t *testing.T) {
+	lookupErr := errors.New("lookup failed")
+	lookupFn := func(uid string) (*user.User, error) {
+		if uid == "1000" {
+			return nil, lookupErr
+		}
+		return user.LookupId(uid)
+	}
+
+	accounts, unresolved := lookupPlanAccounts([]uint32{0, 1000}, lookupFn)
+	if assert.Len(t, accounts, 1) {
+		assert.EqualValues(t, 0, accounts[0].uid)
+		assert.EqualValues(t, "root", accounts[0].username)
+	}
+	assert.EqualValues(t, map[string]error{"1000": lookupErr}, unresolved)
+
+	err := &UnresolvedUsersError{Errors: unresolved}
+	assert.EqualValues(t, "users could not be looked up: 1000: lookup failed", err.Error())
+}
@@ -0,0 +1,172 @@
+package fsquota
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LimitBounds restricts the values of limits.
+// Zero limits disable a resource's limit and are always allowed; bounds of zero are not enforced.
+type LimitBounds struct {
+	MinBytes uint64
+	MaxBytes uint64
+	MinFiles uint64
+	MaxFiles uint64
+}
+
+// Policy describes the limits considered sane, protecting against accidental misconfiguration
+type Policy struct {
+	// Bounds per quota type
+	Bounds map[QuotaType]*LimitBounds
+
+	// MaxFilesystemFraction caps limits at this fraction of the filesystem's size and inode count.
+	// Zero disables the check.
+	MaxFilesystemFraction float64
+
+	// ProtectedIDs lists the IDs per quota type whose limits must not be changed
+	ProtectedIDs map[QuotaType][]IDRange
+}
+
+// DefaultPolicy returns a policy protecting the system user and group ID range, including ID 0,
+// and rejecting limits larger than the filesystem. As XFS stores its default limits for ID 0, changing them
+// requires overriding this policy.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MaxFilesystemFraction: 1,
+		ProtectedIDs: map[QuotaType][]IDRange{
+			QuotaTypeUser:  {{Min: 0, Max: 999}},
+			QuotaTypeGroup: {{Min: 0, Max: 999}},
+		},
+	}
+}
+
+// PolicyError is returned when a quota change violates the active policy
+type PolicyError struct {
+	Type QuotaType
+	ID   string
+	// Violations describes every rule violated by the change
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s %s: policy violated: %s", e.Type, e.ID, strings.Join(e.Violations, "; "))
+}
+
+var (
+	activePolicyMu sync.RWMutex
+	activePolicy   *Policy
+)
+
+// SetPolicy installs the policy validating all subsequent quota changes. A nil policy disables validation.
+// No policy is installed initially, so changes are only validated once a policy such as DefaultPolicy is installed.
+func SetPolicy(policy *Policy) {
+	activePolicyMu.Lock()
+	defer activePolicyMu.Unlock()
+	activePolicy = policy
+}
+
+// GetPolicy returns the policy currently installed
+func GetPolicy() *Policy {
+	activePolicyMu.RLock()
+	defer activePolicyMu.RUnlock()
+	return activePolicy
+}
+
+// setOptions holds the options of calls changing quotas
+type setOptions struct {
+	overridePolicy bool
+	undoSuperseded bool
+	dryRun         bool
+	previewFn      PreviewFn
+}
+
+// SetOption modifies the behavior of calls changing quotas
+type SetOption func(options *setOptions)
+
+// OverridePolicy skips the validation of a change against the active policy
+func OverridePolicy() SetOption {
+	return func(options *setOptions) {
+		options.overridePolicy = true
+	}
+}
+
+func newSetOptions(opts []SetOption) *setOptions {
+	options := &setOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// checkLimit validates a single soft and hard limit pair
+func checkLimit(name string, soft, hard, min, max, capacity uint64, fraction float64) (violations []string) {
+	if hard != 0 && soft > hard {
+		violations = append(violations, fmt.Sprintf("%s soft limit %d exceeds hard limit %d", name, soft, hard))
+	}
+
+	for _, limit := range []struct {
+		kind  string
+		value uint64
+	}{{"soft", soft}, {"hard", hard}} {
+		if limit.value == 0 {
+			continue
+		}
+
+		if min != 0 && limit.value < min {
+			violations = append(violations, fmt.Sprintf("%s %s limit %d is below minimum %d", name, limit.kind, limit.value, min))
+		}
+
+		if max != 0 && limit.value > max {
+			violations = append(violations, fmt.Sprintf("%s %s limit %d exceeds maximum %d", name, limit.kind, limit.value, max))
+		}
+
+		if fraction > 0 && capacity > 0 && float64(limit.value) > float64(capacity)*fraction {
+			violations = append(violations, fmt.Sprintf("%s %s limit %d exceeds %g of filesystem capacity %d", name, limit.kind, limit.value, fraction, capacity))
+		}
+	}
+
+	return
+}
+
+// protectedBy returns the range protecting an ID, nil if the ID is not protected
+func (p *Policy) protectedBy(t QuotaType, id uint32) *IDRange {
+	for i := range p.ProtectedIDs[t] {
+		if p.ProtectedIDs[t][i].contains(id) {
+			return &p.ProtectedIDs[t][i]
+		}
+	}
+	return nil
+}
+
+// check validates limits for an ID. capacityBytes and capacityFiles describe the size of the filesystem,
+// zero values skip the capacity checks.
+func (p *Policy) check(t QuotaType, id uint32, limits *Limits, capacityBytes, capacityFiles uint64) (err error) {
+	var violations []string
+
+	if r := p.protectedBy(t, id); r != nil {
+		violations = append(violations, fmt.Sprintf("ID is protected by range %d-%d", r.Min, r.Max))
+	}
+
+	bounds := p.Bounds[t]
+	if bounds == nil {
+		bounds = &LimitBounds{}
+	}
+
+	if hard, soft, ok := limits.Bytes.getValues(); ok {
+		violations = append(violations, checkLimit("bytes", soft, hard, bounds.MinBytes, bounds.MaxBytes, capacityBytes, p.MaxFilesystemFraction)...)
+	}
+
+	if hard, soft, ok := limits.Files.getValues(); ok {
+		violations = append(violations, checkLimit("files", soft, hard, bounds.MinFiles, bounds.MaxFiles, capacityFiles, p.MaxFilesystemFraction)...)
+	}
+
+	if len(violations) > 0 {
+		err = &PolicyError{
+			Type:       t,
+			ID:         fmt.Sprint(id),
+			Violations: violations,
+		}
+	}
+	return
+}
@@ -0,0 +1,91 @@
+package fsquota
+
+// enforcePolicy validates a change against the active policy
+func enforcePolicy(t quotaCtlType, path string, idString string, limits *Limits) (err error) {
+	return checkPolicy(GetPolicy(), t, path, idString, limits, getQuota)
+}
+
+// checkPolicy validates a change against policy. Soft or hard limits not provided are taken from the current quota
+// retrieved via getFn first, as they are when the change is applied.
+func checkPolicy(policy *Policy, t quotaCtlType, path string, idString string, limits *Limits, getFn getQuotaFn) (err error) {
+	if policy == nil {
+		return
+	}
+
+	var id uint32
+	if id, err = parseID(idString); err != nil {
+		return
+	}
+
+	if limits, err = completeLimits(t, path, idString, limits, getFn, false); err != nil {
+		return
+	}
+
+	var capacityBytes, capacityFiles uint64
+	if policy.MaxFilesystemFraction > 0 {
+		if capacityBytes, capacityFiles, err = filesystemCapacity(path); err != nil {
+			return
+		}
+	}
+
+	return policy.check(QuotaType(t), id, limits, capacityBytes, capacityFiles)
+}
+
+// checkQuotaFn validates a change before it is applied
+type checkQuotaFn func(t quotaCtlType, path string, idString string, limits *Limits) error
+
+func noopCheckQuota(t quotaCtlType, path string, idString string, limits *Limits) error {
+	return nil
+}
+
+// policyCheck returns the validation to apply to changes, which is a no-op if the policy has been overridden via opts
+func policyCheck(opts []SetOption) checkQuotaFn {
+	if newSetOptions(opts).overridePolicy {
+		return noopCheckQuota
+	}
+	return enforcePolicy
+}
+
+// policySetQuota returns a function setting quotas after validating them against the active policy,
+// unless the policy has been overridden via opts
+func policySetQuota(opts []SetOption) setQuotaFn {
+	check := policyCheck(opts)
+	set := optionsSetQuota(opts)
+
+	return func(t quotaCtlType, path string, idString string, limits *Limits) (info *Info, err error) {
+		if err = check(t, path, idString, limits); err != nil {
+			return
+		}
+		return set(t, path, idString, limits)
+	}
+}
+
+// policyClearQuota returns a function clearing quotas of IDs not protected by the active policy,
+// unless the policy has been overridden via opts
+func policyClearQuota(opts []SetOption) clearQuotaFn {
+	check := policyCheck(opts)
+	clear := optionsClearQuota(opts)
+
+	return func(t quotaCtlType, path string, idString string) (info *Info, err error) {
+		if err = check(t, path, idString, clearLimits()); err != nil {
+			return
+		}
+		return clear(t, path, idString)
+	}
+}
+
+// policyProtected returns a function reporting whether an ID is protected by the active policy.
+// No ID is protected if the policy has been overridden via opts.
+func policyProtected(t quotaCtlType, opts []SetOption) func(idString string) bool {
+	policy := GetPolicy()
+	if policy == nil || newSetOptions(opts).overridePolicy {
+		return func(string) bool {
+			return false
+		}
+	}
+
+	return func(idString string) bool {
+		id, err := parseID(idString)
+		return err == nil && policy.protectedBy(QuotaType(t), id) != nil
+	}
+}
@@ -0,0 +1,68 @@
+package fsquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPolicy(t *testing.T) {
+	getFn := func(t quotaCtlType, path string, idString string) (*Info, error) {
+		info := &Info{}
+		info.Bytes.SetSoft(100)
+		info.Bytes.SetHard(200)
+		return info, nil
+	}
+	policy := &Policy{}
+
+	// The current hard limit applies to a soft-only change
+	softOnly := &Limits{}
+	softOnly.Bytes.SetSoft(300)
+	err := checkPolicy(policy, userQuota, "/", "1000", softOnly, getFn)
+	if assert.IsType(t, &PolicyError{}, err) {
+		assert.Len(t, err.(*PolicyError).Violations, 1)
+	}
+
+	softOnly.Bytes.SetSoft(150)
+	assert.NoError(t, checkPolicy(policy, userQuota, "/", "1000", softOnly, getFn))
+
+	// The current soft limit applies to a hard-only change
+	hardOnly := &Limits{}
+	hardOnly.Bytes.SetHard(50)
+	assert.IsType(t, &PolicyError{}, checkPolicy(policy, userQuota, "/", "1000", hardOnly, getFn))
+
+	// Without a policy nothing is checked
+	assert.NoError(t, checkPolicy(nil, userQuota, "/", "1000", softOnly, getFn))
+}
+
+func TestCompleteLimits(t *testing.T) {
+	getFn := func(t quotaCtlType, path string, idString string) (*Info, error) {
+		return nil, ErrRquotaNoQuota
+	}
+
+	limits := &Limits{}
+	limits.Files.SetHard(10)
+	completed, err := completeLimits(userQuota, "/", "1000", limits, getFn, false)
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 0, completed.Files.GetSoft())
+		assert.EqualValues(t, 10, completed.Files.GetHard())
+	}
+
+	t.Run("All", func(t *testing.T) {
+		getFn := func(t quotaCtlType, path string, idString string) (*Info, error) {
+			return newTestInfo(1, 2), nil
+		}
+
+		completed, err := completeLimits(userQuota, "/", "1000", limits, getFn, false)
+		require.NoError(t, err)
+		_, _, haveBytesLimits := completed.Bytes.getValues()
+		assert.False(t, haveBytesLimits)
+
+		completed, err = completeLimits(userQuota, "/", "1000", limits, getFn, true)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, completed.Bytes.GetSoft())
+		assert.EqualValues(t, 2, completed.Bytes.GetHard())
+		assert.EqualValues(t, 10, completed.Files.GetHard())
+	})
+}
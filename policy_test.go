@@ -0,0 +1,90 @@
+package fsquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &Policy{
+		Bounds: map[QuotaType]*LimitBounds{
+			QuotaTypeUser: {MinBytes: 1024 * 1024, MaxFiles: 1000},
+		},
+		MaxFilesystemFraction: 0.5,
+		ProtectedIDs: map[QuotaType][]IDRange{
+			QuotaTypeUser: {{Min: 0, Max: 999}},
+		},
+	}
+
+	newLimits := func(bytesSoft, bytesHard uint64) *Limits {
+		limits := &Limits{}
+		limits.Bytes.SetSoft(bytesSoft)
+		limits.Bytes.SetHard(bytesHard)
+		return limits
+	}
+
+	violations := func(err error) []string {
+		if err == nil {
+			return nil
+		}
+		policyErr, ok := err.(*PolicyError)
+		require.True(t, ok)
+		return policyErr.Violations
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		assert.NoError(t, policy.check(QuotaTypeUser, 1000, newLimits(2<<20, 4<<20), 1<<30, 0))
+		// Zero limits disable the limit and are not subject to bounds
+		assert.NoError(t, policy.check(QuotaTypeUser, 1000, newLimits(0, 0), 1<<30, 0))
+		// Soft limits without hard limits are allowed
+		assert.NoError(t, policy.check(QuotaTypeUser, 1000, newLimits(2<<20, 0), 1<<30, 0))
+		// Bounds and protected IDs are per type
+		assert.NoError(t, policy.check(QuotaTypeGroup, 0, newLimits(1, 1), 0, 0))
+	})
+
+	t.Run("SoftExceedsHard", func(t *testing.T) {
+		assert.Len(t, violations(policy.check(QuotaTypeUser, 1000, newLimits(4<<20, 2<<20), 0, 0)), 1)
+	})
+
+	t.Run("Bounds", func(t *testing.T) {
+		assert.Len(t, violations(policy.check(QuotaTypeUser, 1000, newLimits(1024, 2048), 0, 0)), 2)
+
+		limits := &Limits{}
+		limits.Files.SetHard(1001)
+		assert.Len(t, violations(policy.check(QuotaTypeUser, 1000, limits, 0, 0)), 1)
+	})
+
+	t.Run("FilesystemFraction", func(t *testing.T) {
+		assert.Len(t, violations(policy.check(QuotaTypeUser, 1000, newLimits(2<<20, 600<<20), 1<<30, 0)), 1)
+	})
+
+	t.Run("ProtectedID", func(t *testing.T) {
+		err := policy.check(QuotaTypeUser, 0, newLimits(2<<20, 4<<20), 0, 0)
+		if assert.Len(t, violations(err), 1) {
+			assert.EqualValues(t, "0", err.(*PolicyError).ID)
+			assert.EqualValues(t, QuotaTypeUser, err.(*PolicyError).Type)
+		}
+	})
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := DefaultPolicy()
+	assert.Error(t, policy.check(QuotaTypeUser, 0, &Limits{}, 0, 0))
+	assert.Error(t, policy.check(QuotaTypeGroup, 999, &Limits{}, 0, 0))
+	assert.NoError(t, policy.check(QuotaTypeUser, 1000, &Limits{}, 0, 0))
+	assert.NoError(t, policy.check(QuotaTypeProject, 0, &Limits{}, 0, 0))
+
+	if r := policy.protectedBy(QuotaTypeUser, 500); assert.NotNil(t, r) {
+		assert.EqualValues(t, 0, r.Min)
+		assert.EqualValues(t, 999, r.Max)
+	}
+	assert.Nil(t, policy.protectedBy(QuotaTypeUser, 1000))
+	assert.Nil(t, policy.protectedBy(QuotaTypeProject, 500))
+}
+
+func TestSetOptions(t *testing.T) {
+	assert.False(t, newSetOptions(nil).overridePolicy)
+	assert.True(t, newSetOptions([]SetOption{OverridePolicy()}).overridePolicy)
+}
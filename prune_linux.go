@@ -0,0 +1,75 @@
+package fsquota
+
+import (
+	"github.com/speijnik/go-errortree"
+)
+
+// idExistsFn reports whether an ID still exists in the user or group database
+type idExistsFn func(id string) (exists bool, err error)
+
+// userExists looks up a UID via NSS, os/user only reads /etc/passwd in builds without cgo
+func userExists(uid string) (exists bool, err error) {
+	_, exists, err = getent("passwd", uid)
+	return
+}
+
+// groupExists looks up a GID via NSS, os/user only reads /etc/group in builds without cgo
+func groupExists(gid string) (exists bool, err error) {
+	_, exists, err = getent("group", gid)
+	return
+}
+
+// pruneCandidates returns the IDs of report which no longer exist, in ascending order.
+// ID 0 is never considered, as it holds the default limits on some filesystems, and neither are protected IDs.
+func pruneCandidates(report *Report, existsFn idExistsFn, protectedFn func(id string) bool) (ids []string, err error) {
+	for id := range report.Infos {
+		if id == "0" || protectedFn(id) {
+			continue
+		}
+
+		exists, lookupErr := existsFn(id)
+		if lookupErr != nil {
+			// Never prune IDs whose existence could not be determined
+			err = errortree.Add(err, id, lookupErr)
+			continue
+		}
+
+		if !exists {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids)
+	return
+}
+
+func pruneQuotas(path string, t quotaCtlType, reportFn func(string) (*Report, error), existsFn idExistsFn, protectedFn func(id string) bool, clearFn clearQuotaFn) (pruned []string, err error) {
+	var report *Report
+	if report, err = reportFn(path); err != nil {
+		return
+	}
+
+	// Nothing is pruned if the user or group database could not be consulted for all IDs
+	var candidates []string
+	if candidates, err = pruneCandidates(report, existsFn, protectedFn); err != nil {
+		return
+	}
+
+	for _, id := range candidates {
+		if _, clearErr := clearFn(t, path, id); clearErr != nil {
+			err = errortree.Add(err, id, clearErr)
+			continue
+		}
+		pruned = append(pruned, id)
+	}
+
+	return
+}
+
+func pruneUserQuotas(path string, opts []SetOption) (pruned []string, err error) {
+	return pruneQuotas(path, userQuota, getUserReport, userExists, policyProtected(userQuota, opts), policyClearQuota(opts))
+}
+
+func pruneGroupQuotas(path string, opts []SetOption) (pruned []string, err error) {
+	return pruneQuotas(path, groupQuota, getGroupReport, groupExists, policyProtected(groupQuota, opts), policyClearQuota(opts))
+}
@@ -0,0 +1,99 @@
+package fsquota
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/speijnik/go-errortree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneCandidates(t *testing.T) {
+	report := &Report{
+		Infos: map[string]*Info{
+			"0":    {},
+			"1000": {},
+			"1001": {},
+			"1002": {},
+			"999":  {},
+		},
+	}
+
+	existing := map[string]bool{
+		"1000": true,
+	}
+	lookupErr := errors.New("lookup failed")
+
+	existsFn := func(id string) (bool, error) {
+		if id == "1002" {
+			return false, lookupErr
+		}
+		return existing[id], nil
+	}
+
+	notProtected := func(string) bool {
+		return false
+	}
+
+	ids, err := pruneCandidates(report, existsFn, notProtected)
+	assert.EqualValues(t, []string{"999", "1001"}, ids)
+	if assert.Error(t, err) {
+		assert.EqualValues(t, lookupErr, errortree.Get(err, "1002"))
+	}
+
+	protected := func(id string) bool {
+		return id == "999"
+	}
+
+	ids, err = pruneCandidates(report, existsFn, protected)
+	assert.EqualValues(t, []string{"1001"}, ids)
+	if assert.Error(t, err) {
+		assert.EqualValues(t, lookupErr, errortree.Get(err, "1002"))
+	}
+}
+
+func TestPruneQuotas(t *testing.T) {
+	report := &Report{
+		Infos: map[string]*Info{
+			"1000": {},
+			"1001": {},
+			"1002": {},
+		},
+	}
+	reportFn := func(path string) (*Report, error) {
+		return report, nil
+	}
+	protectedFn := func(id string) bool {
+		return false
+	}
+
+	var cleared []string
+	clearFn := func(t quotaCtlType, path string, id string) (*Info, error) {
+		cleared = append(cleared, id)
+		return &Info{}, nil
+	}
+
+	t.Run("Pruned", func(t *testing.T) {
+		cleared = nil
+		pruned, err := pruneQuotas("/home", userQuota, reportFn, func(id string) (bool, error) {
+			return id == "1000", nil
+		}, protectedFn, clearFn)
+		assert.NoError(t, err)
+		assert.EqualValues(t, []string{"1001", "1002"}, pruned)
+		assert.EqualValues(t, []string{"1001", "1002"}, cleared)
+	})
+
+	t.Run("LookupFailed", func(t *testing.T) {
+		cleared = nil
+		lookupErr := errors.New("lookup failed")
+		pruned, err := pruneQuotas("/home", userQuota, reportFn, func(id string) (bool, error) {
+			if id == "1002" {
+				return false, lookupErr
+			}
+			return false, nil
+		}, protectedFn, clearFn)
+		assert.Error(t, err)
+		assert.Empty(t, pruned)
+		assert.Empty(t, cleared)
+	})
+}
@@ -9,8 +9,10 @@ import (
 type quotaCtlCmd uintptr
 
 const (
+	// Q_GETINFO
+	cmdGetInfo quotaCtlCmd = 0x00800005
 	// Q_GETQUOTA
-	cmdGetQuota quotaCtlCmd = 0x00800007
+	cmdGetQuota = 0x00800007
 	// Q_SETQUOTA
 	cmdSetQuota = 0x00800008
 	// Q_GETNEXTQUOTA
@@ -24,6 +26,8 @@ const (
 	userQuota quotaCtlType = 0
 	// GRPQUOTA
 	groupQuota = 1
+	// PRJQUOTA
+	projectQuota = 2
 )
 
 const (
@@ -76,6 +80,13 @@ type dqblk struct {
 	dqbValid      uint32
 }
 
+type dqinfo struct {
+	dqiBGrace uint64
+	dqiIGrace uint64
+	dqiFlags  uint32
+	dqiValid  uint32
+}
+
 func (d dqblk) toInfo() (info *Info) {
 	info = &Info{
 		Limits: Limits{
@@ -88,8 +99,10 @@ func (d dqblk) toInfo() (info *Info) {
 				soft: &d.dqbBSoftlimit,
 			},
 		},
-		FilesUsed: d.dqbCurInodes,
-		BytesUsed: d.dqbCurSpace,
+		FilesUsed:         d.dqbCurInodes,
+		BytesUsed:         d.dqbCurSpace,
+		BytesGraceExpires: graceExpiresAt(d.dqbBTime),
+		FilesGraceExpires: graceExpiresAt(d.dqbITime),
 	}
 
 	//info.BytesUsed = info.BytesUsed
@@ -0,0 +1,215 @@
+package fsquota
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// V2_DQMAGICS for user, group and project quota files
+	quotaFileMagicUser    = 0xd9c01f11
+	quotaFileMagicGroup   = 0xd9c01927
+	quotaFileMagicProject = 0xd9c03f14
+
+	// QT_BLKSIZE_BITS
+	quotaTreeBlockSizeBits = 10
+	// QT_BLKSIZE
+	quotaTreeBlockSize = 1 << quotaTreeBlockSizeBits
+	// QT_TREEOFF
+	quotaTreeOffset = 1
+	// QT_TREEDEPTH
+	quotaTreeDepth = 4
+
+	// Size of the v2 disk header (magic and version)
+	quotaFileHeaderSize = 8
+	// Size of struct qt_disk_dqdbheader
+	quotaTreeLeafHeaderSize = 16
+	// Size of struct v2r1_disk_dqblk
+	quotaTreeV2R1EntrySize = 72
+	// Size of struct v2r0_disk_dqblk
+	quotaTreeV2R0EntrySize = 48
+
+	// QUOTABLOCK_SIZE
+	quotaFileBlockSize = 1024
+)
+
+var quotaFileMagics = map[QuotaType]uint32{
+	QuotaTypeUser:    quotaFileMagicUser,
+	QuotaTypeGroup:   quotaFileMagicGroup,
+	QuotaTypeProject: quotaFileMagicProject,
+}
+
+// QuotaFileInfo contains the contents of a quota file
+type QuotaFileInfo struct {
+	// Format version of the quota file
+	Version uint32
+	// Grace period for byte soft limits, in seconds
+	BytesGracePeriod uint32
+	// Grace period for file soft limits, in seconds
+	FilesGracePeriod uint32
+	// Report of all entries present in the quota file
+	Report *Report
+}
+
+// quotaFileReader reads quota files in the v2 tree format (QFMT_VFS_V0 and QFMT_VFS_V1)
+type quotaFileReader struct {
+	r         io.ReaderAt
+	entrySize int
+	visited   map[uint32]bool
+	report    *Report
+}
+
+func readQuotaFile(r io.ReaderAt, t QuotaType) (info *QuotaFileInfo, err error) {
+	header := make([]byte, quotaFileHeaderSize+24)
+	if _, err = r.ReadAt(header, 0); err != nil {
+		return
+	}
+
+	magic := binary.LittleEndian.Uint32(header[0:])
+	if expectedMagic, known := quotaFileMagics[t]; !known || magic != expectedMagic {
+		err = fmt.Errorf("invalid %s quota file magic 0x%08x", t, magic)
+		return
+	}
+
+	qfi := &QuotaFileInfo{
+		Version:          binary.LittleEndian.Uint32(header[4:]),
+		BytesGracePeriod: binary.LittleEndian.Uint32(header[8:]),
+		FilesGracePeriod: binary.LittleEndian.Uint32(header[12:]),
+	}
+
+	reader := &quotaFileReader{
+		r:       r,
+		visited: make(map[uint32]bool),
+		report: &Report{
+			Infos: make(map[string]*Info),
+		},
+	}
+
+	switch qfi.Version {
+	case 0:
+		reader.entrySize = quotaTreeV2R0EntrySize
+	case 1:
+		reader.entrySize = quotaTreeV2R1EntrySize
+	default:
+		err = fmt.Errorf("unsupported quota file version %d", qfi.Version)
+		return
+	}
+
+	if err = reader.walk(quotaTreeOffset, 0); err != nil {
+		return
+	}
+
+	qfi.Report = reader.report
+	info = qfi
+	return
+}
+
+func (q *quotaFileReader) readBlock(block uint32) (data []byte, err error) {
+	data = make([]byte, quotaTreeBlockSize)
+	if _, err = q.r.ReadAt(data, int64(block)<<quotaTreeBlockSizeBits); err != nil {
+		data = nil
+	}
+	return
+}
+
+func (q *quotaFileReader) walk(block uint32, depth int) (err error) {
+	// Guard against loops in corrupt files
+	if q.visited[block] {
+		return
+	}
+	q.visited[block] = true
+
+	var data []byte
+	if data, err = q.readBlock(block); err != nil {
+		return
+	}
+
+	if depth == quotaTreeDepth {
+		return q.readLeaf(data)
+	}
+
+	for i := 0; i < quotaTreeBlockSize/4; i++ {
+		if ref := binary.LittleEndian.Uint32(data[i*4:]); ref != 0 {
+			if err = q.walk(ref, depth+1); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *quotaFileReader) readLeaf(data []byte) (err error) {
+	entries := data[quotaTreeLeafHeaderSize:]
+
+	for offset := 0; offset+q.entrySize <= len(entries); offset += q.entrySize {
+		entry := entries[offset : offset+q.entrySize]
+		if isZero(entry) {
+			continue
+		}
+
+		var id uint32
+		var info *Info
+		if q.entrySize == quotaTreeV2R1EntrySize {
+			id, info = decodeV2R1Entry(entry)
+		} else {
+			id, info = decodeV2R0Entry(entry)
+		}
+
+		q.report.Infos[fmt.Sprint(id)] = info
+	}
+
+	return
+}
+
+func newInfoFromDiskValues(bHard, bSoft, curSpace, iHard, iSoft, curInodes uint64) *Info {
+	bytesHard := bHard * quotaFileBlockSize
+	bytesSoft := bSoft * quotaFileBlockSize
+
+	return &Info{
+		Limits: Limits{
+			Bytes: Limit{
+				hard: &bytesHard,
+				soft: &bytesSoft,
+			},
+			Files: Limit{
+				hard: &iHard,
+				soft: &iSoft,
+			},
+		},
+		BytesUsed: curSpace,
+		FilesUsed: curInodes,
+	}
+}
+
+// decodeV2R1Entry decodes a struct v2r1_disk_dqblk
+func decodeV2R1Entry(entry []byte) (id uint32, info *Info) {
+	le := binary.LittleEndian
+	id = le.Uint32(entry[0:])
+	info = newInfoFromDiskValues(
+		le.Uint64(entry[32:]), le.Uint64(entry[40:]), le.Uint64(entry[48:]),
+		le.Uint64(entry[8:]), le.Uint64(entry[16:]), le.Uint64(entry[24:]),
+	)
+	return
+}
+
+// decodeV2R0Entry decodes a struct v2r0_disk_dqblk
+func decodeV2R0Entry(entry []byte) (id uint32, info *Info) {
+	le := binary.LittleEndian
+	id = le.Uint32(entry[0:])
+	info = newInfoFromDiskValues(
+		uint64(le.Uint32(entry[16:])), uint64(le.Uint32(entry[20:])), le.Uint64(entry[24:]),
+		uint64(le.Uint32(entry[4:])), uint64(le.Uint32(entry[8:])), uint64(le.Uint32(entry[12:])),
+	)
+	return
+}
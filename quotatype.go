@@ -0,0 +1,54 @@
+package fsquota
+
+import "fmt"
+
+// QuotaType identifies the kind of quota, matching the kernel's quota type numbering
+type QuotaType uint32
+
+const (
+	// QuotaTypeUser identifies user quotas (USRQUOTA)
+	QuotaTypeUser QuotaType = 0
+	// QuotaTypeGroup identifies group quotas (GRPQUOTA)
+	QuotaTypeGroup QuotaType = 1
+	// QuotaTypeProject identifies project quotas (PRJQUOTA)
+	QuotaTypeProject QuotaType = 2
+)
+
+// QuotaTypes returns all quota types in ascending order
+func QuotaTypes() []QuotaType {
+	return []QuotaType{QuotaTypeUser, QuotaTypeGroup, QuotaTypeProject}
+}
+
+// String returns the human-readable name of the quota type
+func (t QuotaType) String() string {
+	switch t {
+	case QuotaTypeUser:
+		return "user"
+	case QuotaTypeGroup:
+		return "group"
+	case QuotaTypeProject:
+		return "project"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint32(t))
+}
+
+// MarshalText encodes the quota type by its name
+func (t QuotaType) MarshalText() ([]byte, error) {
+	switch t {
+	case QuotaTypeUser, QuotaTypeGroup, QuotaTypeProject:
+		return []byte(t.String()), nil
+	}
+	return nil, fmt.Errorf("unknown quota type %d", uint32(t))
+}
+
+// UnmarshalText decodes a quota type from its name
+func (t *QuotaType) UnmarshalText(text []byte) error {
+	for _, candidate := range QuotaTypes() {
+		if candidate.String() == string(text) {
+			*t = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown quota type %q", string(text))
+}
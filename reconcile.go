@@ -0,0 +1,68 @@
+package fsquota
+
+// ReconcileAction describes what a reconcile step does to an ID's limits
+type ReconcileAction uint8
+
+const (
+	// ReconcileAdd configures limits for an ID without limits
+	ReconcileAdd ReconcileAction = iota
+	// ReconcileChange replaces the limits of an ID
+	ReconcileChange
+	// ReconcileRemove clears the limits of an ID not present in the desired state
+	ReconcileRemove
+)
+
+var reconcileActionNames = map[ReconcileAction]string{
+	ReconcileAdd:    "add",
+	ReconcileChange: "change",
+	ReconcileRemove: "remove",
+}
+
+func (a ReconcileAction) String() string {
+	if name, ok := reconcileActionNames[a]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// DesiredQuota describes the limits a set of IDs should have.
+// Exactly one of ID, Name or Pattern selects the IDs the limits apply to.
+type DesiredQuota struct {
+	// Numeric ID
+	ID string
+	// User or group name
+	Name string
+	// Regular expression matched against user or group names
+	Pattern string
+
+	// Limits the selected IDs should have, limits not set are treated as zero
+	Limits Limits
+}
+
+// DesiredState describes the desired quota configuration of a filesystem
+type DesiredState struct {
+	// Path on the filesystem
+	Path string
+
+	Users    []*DesiredQuota
+	Groups   []*DesiredQuota
+	Projects []*DesiredQuota
+}
+
+// ReconcileStep is a single change required to reach the desired state
+type ReconcileStep struct {
+	Action ReconcileAction
+	Type   QuotaType
+	ID     string
+
+	// Current quota information, nil if the ID has no quota entry
+	Current *Info
+	// Limits to apply
+	Limits Limits
+}
+
+// ReconcilePlan contains the steps required to bring a filesystem to its desired state
+type ReconcilePlan struct {
+	Path  string
+	Steps []*ReconcileStep
+}
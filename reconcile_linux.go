@@ -0,0 +1,306 @@
+package fsquota
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/speijnik/go-errortree"
+)
+
+// idDatabase provides access to the known IDs and names of a quota type
+type idDatabase struct {
+	// idsFn lists all known IDs
+	idsFn func() ([]uint32, error)
+	// nameFn looks up the name of an ID
+	nameFn func(id string) (name string, ok bool)
+	// lookupFn resolves a name to its ID
+	lookupFn func(name string) (id string, err error)
+}
+
+var userDatabase = &idDatabase{
+	idsFn: func() ([]uint32, error) {
+		return listNSSIDs("passwd", passwdFile)
+	},
+	nameFn: func(id string) (string, bool) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", false
+		}
+		return u.Username, true
+	},
+	lookupFn: func(name string) (string, error) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		return u.Uid, nil
+	},
+}
+
+var groupDatabase = &idDatabase{
+	idsFn: func() ([]uint32, error) {
+		return listNSSIDs("group", groupFile)
+	},
+	nameFn: func(id string) (string, bool) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", false
+		}
+		return g.Name, true
+	},
+	lookupFn: func(name string) (string, error) {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return "", err
+		}
+		return g.Gid, nil
+	},
+}
+
+var projectDatabase = &idDatabase{
+	idsFn: func() ([]uint32, error) {
+		return getIDsFromProjidFile(projidFile)
+	},
+	nameFn: func(id string) (string, bool) {
+		return "", false
+	},
+	lookupFn: func(name string) (string, error) {
+		return "", errors.New("project names are not supported")
+	},
+}
+
+// normalizedLimits returns a copy of limits with all unset limits set to zero
+func normalizedLimits(limits *Limits) *Limits {
+	return snapshotLimits(&Info{}).merge(limits)
+}
+
+// merge copies the limits set on other and returns l
+func (l *Limits) merge(other *Limits) *Limits {
+	l.Bytes.set(&other.Bytes)
+	l.Files.set(&other.Files)
+	return l
+}
+
+// resolveDesired maps every ID selected by entries to its desired limits.
+// Entries selecting IDs explicitly take precedence over patterns; among patterns the first match wins.
+func resolveDesired(entries []*DesiredQuota, report *Report, db *idDatabase) (desired map[string]*Limits, err error) {
+	desired = make(map[string]*Limits)
+
+	type patternEntry struct {
+		re    *regexp.Regexp
+		entry *DesiredQuota
+	}
+	var patterns []patternEntry
+
+	for i, entry := range entries {
+		key := strconv.Itoa(i)
+		selectors := 0
+		for _, s := range []string{entry.ID, entry.Name, entry.Pattern} {
+			if s != "" {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			err = errortree.Add(err, key, errors.New("exactly one of id, name or pattern is required"))
+			continue
+		}
+
+		id := entry.ID
+		switch {
+		case entry.Pattern != "":
+			re, compileErr := regexp.Compile("^(?:" + entry.Pattern + ")$")
+			if compileErr != nil {
+				err = errortree.Add(err, key, compileErr)
+				continue
+			}
+			patterns = append(patterns, patternEntry{re, entry})
+			continue
+		case entry.Name != "":
+			var lookupErr error
+			if id, lookupErr = db.lookupFn(entry.Name); lookupErr != nil {
+				err = errortree.Add(err, key, lookupErr)
+				continue
+			}
+		default:
+			if _, parseErr := parseID(id); parseErr != nil {
+				err = errortree.Add(err, key, parseErr)
+				continue
+			}
+		}
+
+		if _, duplicate := desired[id]; duplicate {
+			err = errortree.Add(err, key, fmt.Errorf("ID %s is declared more than once", id))
+			continue
+		}
+		desired[id] = normalizedLimits(&entry.Limits)
+	}
+
+	if err != nil || len(patterns) == 0 {
+		return
+	}
+
+	// Patterns are matched against the names of all known IDs as well as all IDs with a quota entry
+	candidates := make(map[string]bool)
+	if ids, idsErr := db.idsFn(); idsErr == nil {
+		for _, id := range ids {
+			candidates[strconv.FormatUint(uint64(id), 10)] = true
+		}
+	}
+	for id := range report.Infos {
+		candidates[id] = true
+	}
+
+	for id := range candidates {
+		if _, explicit := desired[id]; explicit {
+			continue
+		}
+
+		name, ok := db.nameFn(id)
+		if !ok {
+			continue
+		}
+
+		for _, p := range patterns {
+			if p.re.MatchString(name) {
+				desired[id] = normalizedLimits(&p.entry.Limits)
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// planReconcile computes the steps required to transition report to the desired limits.
+// With prune set, limits of IDs not present in desired are removed, unless protectedFn reports them as protected.
+func planReconcile(t QuotaType, report *Report, desired map[string]*Limits, prune bool, protectedFn func(id string) bool) (steps []*ReconcileStep) {
+	for id, limits := range desired {
+		current := report.Infos[id]
+
+		step := &ReconcileStep{
+			Type:    t,
+			ID:      id,
+			Current: current,
+		}
+		step.Limits.merge(limits)
+
+		switch {
+		case current == nil || limitsEmpty(&current.Limits):
+			if limitsEmpty(limits) {
+				continue
+			}
+			step.Action = ReconcileAdd
+		case limitsEqual(&current.Limits, limits):
+			continue
+		default:
+			step.Action = ReconcileChange
+		}
+
+		steps = append(steps, step)
+	}
+
+	if prune {
+		for id, current := range report.Infos {
+			// ID 0 holds the default limits on some filesystems, and inherited defaults can not be removed
+			if _, declared := desired[id]; declared || id == "0" || protectedFn(id) || current.InheritsDefaultLimits || limitsEmpty(&current.Limits) {
+				continue
+			}
+
+			step := &ReconcileStep{
+				Action:  ReconcileRemove,
+				Type:    t,
+				ID:      id,
+				Current: current,
+			}
+			step.Limits.merge(normalizedLimits(&Limits{}))
+			steps = append(steps, step)
+		}
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].Type != steps[j].Type {
+			return steps[i].Type < steps[j].Type
+		}
+		a, _ := parseID(steps[i].ID)
+		b, _ := parseID(steps[j].ID)
+		return a < b
+	})
+	return
+}
+
+type reconcileType struct {
+	t        QuotaType
+	entries  []*DesiredQuota
+	db       *idDatabase
+	reportFn func(string) (*Report, error)
+}
+
+func planReconcileState(state *DesiredState, prune bool, opts []SetOption) (plan *ReconcilePlan, err error) {
+	if state.Path == "" {
+		err = errors.New("path must not be empty")
+		return
+	}
+
+	plan = &ReconcilePlan{
+		Path: state.Path,
+	}
+
+	for _, rt := range []reconcileType{
+		{QuotaTypeUser, state.Users, userDatabase, getUserReport},
+		{QuotaTypeGroup, state.Groups, groupDatabase, getGroupReport},
+		{QuotaTypeProject, state.Projects, projectDatabase, getProjectReport},
+	} {
+		// Types without any declarations are left untouched, even when pruning
+		if len(rt.entries) == 0 {
+			continue
+		}
+
+		report, reportErr := rt.reportFn(state.Path)
+		if reportErr != nil {
+			err = errortree.Add(err, rt.t.String(), reportErr)
+			continue
+		}
+
+		desired, resolveErr := resolveDesired(rt.entries, report, rt.db)
+		if resolveErr != nil {
+			err = errortree.Add(err, rt.t.String(), resolveErr)
+			continue
+		}
+
+		plan.Steps = append(plan.Steps, planReconcile(rt.t, report, desired, prune, policyProtected(quotaCtlType(rt.t), opts))...)
+	}
+
+	if err != nil {
+		plan = nil
+	}
+	return
+}
+
+func applyReconcilePlan(plan *ReconcilePlan, opts []SetOption) (err error) {
+	changes := make([]*QuotaChange, len(plan.Steps))
+	for i, step := range plan.Steps {
+		changes[i] = &QuotaChange{
+			Path: plan.Path,
+			Type: step.Type,
+			ID:   step.ID,
+		}
+		changes[i].Limits.merge(&step.Limits)
+	}
+
+	return applyQuotaChanges(changes, getQuota, reconcileSetQuota(optionsSetQuota(opts), optionsClearQuota(opts)), policyCheck(opts))
+}
+
+// reconcileSetQuota returns the function applying the limits of reconcile steps. As steps and the snapshots restored on
+// rollback always set all limits, limits which are all zero remove the quota via clearFn, which resets grace times as well.
+func reconcileSetQuota(setFn setQuotaFn, clearFn clearQuotaFn) setQuotaFn {
+	return func(t quotaCtlType, path string, idString string, limits *Limits) (*Info, error) {
+		if limitsEmpty(limits) {
+			return clearFn(t, path, idString)
+		}
+		return setFn(t, path, idString, limits)
+	}
+}
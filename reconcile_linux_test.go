@@ -0,0 +1,162 @@
+package fsquota
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/speijnik/go-errortree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIDDatabase(names map[string]string) *idDatabase {
+	return &idDatabase{
+		idsFn: func() ([]uint32, error) {
+			var ids []uint32
+			for id := range names {
+				parsed, _ := parseID(id)
+				ids = append(ids, parsed)
+			}
+			return ids, nil
+		},
+		nameFn: func(id string) (string, bool) {
+			name, ok := names[id]
+			return name, ok
+		},
+		lookupFn: func(name string) (string, error) {
+			for id, n := range names {
+				if n == name {
+					return id, nil
+				}
+			}
+			return "", errors.New("unknown name")
+		},
+	}
+}
+
+func newTestInfo(bytesSoft, bytesHard uint64) *Info {
+	info := &Info{}
+	info.Bytes.SetSoft(bytesSoft)
+	info.Bytes.SetHard(bytesHard)
+	info.Files.SetSoft(0)
+	info.Files.SetHard(0)
+	return info
+}
+
+func TestResolveDesired(t *testing.T) {
+	db := newTestIDDatabase(map[string]string{
+		"1000": "alice",
+		"1001": "bob",
+		"2000": "svc-web",
+		"2001": "svc-db",
+	})
+
+	report := &Report{Infos: map[string]*Info{
+		// Unknown to the database, never matched by patterns
+		"3000": {},
+	}}
+
+	t.Run("OK", func(t *testing.T) {
+		explicit := &DesiredQuota{Name: "svc-db"}
+		explicit.Limits.Bytes.SetHard(1)
+		pattern := &DesiredQuota{Pattern: "svc-.*"}
+		pattern.Limits.Bytes.SetHard(2)
+		byID := &DesiredQuota{ID: "1000"}
+		byID.Limits.Files.SetSoft(3)
+
+		desired, err := resolveDesired([]*DesiredQuota{pattern, explicit, byID}, report, db)
+		require.NoError(t, err)
+		assert.Len(t, desired, 3)
+
+		assert.EqualValues(t, 1, desired["2001"].Bytes.GetHard())
+		assert.EqualValues(t, 2, desired["2000"].Bytes.GetHard())
+		assert.EqualValues(t, 3, desired["1000"].Files.GetSoft())
+
+		// Unset limits are normalized to zero
+		_, _, ok := desired["1000"].Bytes.getValues()
+		assert.True(t, ok)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		desired, err := resolveDesired([]*DesiredQuota{
+			{},
+			{ID: "1000", Name: "alice"},
+			{Name: "unknown"},
+			{Pattern: "("},
+			{ID: "invalid"},
+			{ID: "1001"},
+			{Name: "bob"},
+		}, report, db)
+		if assert.Error(t, err) {
+			for _, key := range []string{"0", "1", "2", "3", "4", "6"} {
+				assert.Error(t, errortree.Get(err, key), key)
+			}
+			assert.Nil(t, errortree.Get(err, "5"))
+		}
+		assert.NotNil(t, desired)
+	})
+}
+
+func TestPlanReconcile(t *testing.T) {
+	inherited := newTestInfo(5, 5)
+	inherited.InheritsDefaultLimits = true
+
+	report := &Report{Infos: map[string]*Info{
+		"0":    newTestInfo(5, 5),
+		"1000": newTestInfo(1, 2),
+		"1001": newTestInfo(1, 2),
+		"1002": newTestInfo(1, 2),
+		"1003": newTestInfo(0, 0),
+		"1004": inherited,
+		"1007": newTestInfo(1, 2),
+	}}
+
+	desired := map[string]*Limits{
+		"1000": &newTestInfo(1, 2).Limits,
+		"1001": &newTestInfo(3, 4).Limits,
+		"1003": &newTestInfo(3, 4).Limits,
+		"1005": &newTestInfo(3, 4).Limits,
+		"1006": &newTestInfo(0, 0).Limits,
+	}
+
+	protectedFn := func(id string) bool {
+		return id == "1007"
+	}
+
+	summarize := func(steps []*ReconcileStep) (summary []string) {
+		for _, step := range steps {
+			assert.EqualValues(t, QuotaTypeUser, step.Type)
+			summary = append(summary, step.Action.String()+" "+step.ID)
+		}
+		return
+	}
+
+	t.Run("WithoutPrune", func(t *testing.T) {
+		steps := planReconcile(QuotaTypeUser, report, desired, false, protectedFn)
+		assert.EqualValues(t, []string{"change 1001", "add 1003", "add 1005"}, summarize(steps))
+	})
+
+	t.Run("WithPrune", func(t *testing.T) {
+		steps := planReconcile(QuotaTypeUser, report, desired, true, protectedFn)
+		assert.EqualValues(t, []string{"change 1001", "remove 1002", "add 1003", "add 1005"}, summarize(steps))
+		assert.True(t, limitsEmpty(&steps[1].Limits))
+		assert.Equal(t, report.Infos["1002"], steps[1].Current)
+	})
+}
+
+func TestReconcileSetQuota(t *testing.T) {
+	var calls []string
+	setFn := reconcileSetQuota(func(t quotaCtlType, path string, idString string, limits *Limits) (*Info, error) {
+		calls = append(calls, "set "+idString)
+		return &Info{}, nil
+	}, func(t quotaCtlType, path string, idString string) (*Info, error) {
+		calls = append(calls, "clear "+idString)
+		return &Info{}, nil
+	})
+
+	_, err := setFn(userQuota, "/mnt", "1000", &newTestInfo(1, 2).Limits)
+	require.NoError(t, err)
+	_, err = setFn(userQuota, "/mnt", "1001", &newTestInfo(0, 0).Limits)
+	require.NoError(t, err)
+	assert.EqualValues(t, []string{"set 1000", "clear 1001"}, calls)
+}
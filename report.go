@@ -1,7 +1,34 @@
 package fsquota
 
+import (
+	"sort"
+	"strconv"
+)
+
 // Report contains a quota report
 type Report struct {
 	// Map of user or group to info structure
 	Infos map[string]*Info
 }
+
+// IDs returns the IDs of the report in ascending numeric order
+func (r *Report) IDs() (ids []string) {
+	ids = make([]string, 0, len(r.Infos))
+	for id := range r.Infos {
+		ids = append(ids, id)
+	}
+	sortIDs(ids)
+	return
+}
+
+// sortIDs sorts numeric ID strings in ascending numeric order
+func sortIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, aErr := strconv.ParseUint(ids[i], 10, 64)
+		b, bErr := strconv.ParseUint(ids[j], 10, 64)
+		if aErr != nil || bErr != nil {
+			return ids[i] < ids[j]
+		}
+		return a < b
+	})
+}
@@ -0,0 +1,13 @@
+package fsquota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport_IDs(t *testing.T) {
+	r := &Report{Infos: map[string]*Info{"1000": {}, "2": {}, "10": {}}}
+	assert.EqualValues(t, []string{"2", "10", "1000"}, r.IDs())
+	assert.Empty(t, (&Report{}).IDs())
+}
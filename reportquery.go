@@ -0,0 +1,255 @@
+package fsquota
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ReportColumn identifies the value report entries are sorted by
+type ReportColumn string
+
+// Report columns. Percentages relate usage to the soft limit, or to the hard limit if no soft limit is set.
+const (
+	ReportColumnID           ReportColumn = "id"
+	ReportColumnName         ReportColumn = "name"
+	ReportColumnBytesUsed    ReportColumn = "bytes-used"
+	ReportColumnBytesSoft    ReportColumn = "bytes-soft"
+	ReportColumnBytesHard    ReportColumn = "bytes-hard"
+	ReportColumnBytesPercent ReportColumn = "bytes-percent"
+	ReportColumnFilesUsed    ReportColumn = "files-used"
+	ReportColumnFilesSoft    ReportColumn = "files-soft"
+	ReportColumnFilesHard    ReportColumn = "files-hard"
+	ReportColumnFilesPercent ReportColumn = "files-percent"
+)
+
+// ReportColumns lists all columns report entries can be sorted by
+var ReportColumns = []ReportColumn{
+	ReportColumnID, ReportColumnName,
+	ReportColumnBytesUsed, ReportColumnBytesSoft, ReportColumnBytesHard, ReportColumnBytesPercent,
+	ReportColumnFilesUsed, ReportColumnFilesSoft, ReportColumnFilesHard, ReportColumnFilesPercent,
+}
+
+// ReportQuery selects, sorts and limits the entries of a report. All conditions set must be met; the state
+// conditions are met if they apply to either bytes or files.
+type ReportQuery struct {
+	// OverSoft selects IDs exceeding a soft limit
+	OverSoft bool
+	// InGrace selects IDs exceeding a soft limit whose grace period has not expired yet
+	InGrace bool
+	// GraceExpired selects IDs exceeding a soft limit whose grace period has expired
+	GraceExpired bool
+	// AtHard selects IDs whose usage reached a hard limit
+	AtHard bool
+	// HasLimits selects IDs with any limit set
+	HasLimits bool
+	// HasUsage selects IDs using any bytes or files
+	HasUsage bool
+	// MinPercent selects IDs using at least the given percentage of a limit
+	MinPercent float64
+	// IDRanges selects IDs within any of the ranges
+	IDRanges []IDRange
+	// NamePattern is a regular expression the name of selected IDs must match
+	NamePattern string
+
+	// SortBy is the column entries are sorted by, ReportColumnID if empty. IDs and names are sorted in
+	// ascending order, all other columns in descending order.
+	SortBy ReportColumn
+	// Reverse inverts the sort order
+	Reverse bool
+	// Top limits the result to the given number of entries, all entries are returned if zero
+	Top int
+
+	// NameFn resolves the names of IDs, names are left empty if nil
+	NameFn func(id string) string
+}
+
+// ReportEntry is the quota information of a single ID selected by a ReportQuery
+type ReportEntry struct {
+	ID   string
+	Name string
+	Info *Info
+}
+
+// UsagePercent returns used as percentage of limit, zero if the limit is not set
+func UsagePercent(used, limit uint64) float64 {
+	if limit == 0 {
+		return 0
+	}
+	return float64(used) * 100 / float64(limit)
+}
+
+// usagePercent returns usage relative to the soft limit, or to the hard limit if no soft limit is set.
+// Zero is returned if no limit is set.
+func usagePercent(used uint64, limit *Limit) float64 {
+	hard, soft, _ := limit.getValues()
+	if soft == 0 {
+		soft = hard
+	}
+	return UsagePercent(used, soft)
+}
+
+// BytesPercent returns the byte usage relative to the soft limit, or to the hard limit if no soft limit is set
+func (i *Info) BytesPercent() float64 {
+	return usagePercent(i.BytesUsed, &i.Bytes)
+}
+
+// FilesPercent returns the file usage relative to the soft limit, or to the hard limit if no soft limit is set
+func (i *Info) FilesPercent() float64 {
+	return usagePercent(i.FilesUsed, &i.Files)
+}
+
+func atHard(used uint64, limit *Limit) bool {
+	hard := limit.GetHard()
+	return hard != 0 && used >= hard
+}
+
+// matches reports whether info meets the state and usage conditions of q at now
+func (q *ReportQuery) matches(info *Info, now time.Time) bool {
+	bytesGrace, filesGrace := info.BytesGraceState(now), info.FilesGraceState(now)
+
+	switch {
+	case q.OverSoft && bytesGrace == GraceStateNone && filesGrace == GraceStateNone:
+		return false
+	case q.InGrace && bytesGrace != GraceStateActive && filesGrace != GraceStateActive:
+		return false
+	case q.GraceExpired && bytesGrace != GraceStateExpired && filesGrace != GraceStateExpired:
+		return false
+	case q.AtHard && !atHard(info.BytesUsed, &info.Bytes) && !atHard(info.FilesUsed, &info.Files):
+		return false
+	case q.HasLimits && !hasLimits(info):
+		return false
+	case q.HasUsage && info.BytesUsed == 0 && info.FilesUsed == 0:
+		return false
+	case q.MinPercent > 0 && info.BytesPercent() < q.MinPercent && info.FilesPercent() < q.MinPercent:
+		return false
+	}
+	return true
+}
+
+func hasLimits(info *Info) bool {
+	bytesHard, bytesSoft, _ := info.Bytes.getValues()
+	filesHard, filesSoft, _ := info.Files.getValues()
+	return bytesHard != 0 || bytesSoft != 0 || filesHard != 0 || filesSoft != 0
+}
+
+func (q *ReportQuery) inRanges(id string) bool {
+	if len(q.IDRanges) == 0 {
+		return true
+	}
+
+	numericID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return false
+	}
+
+	for i := range q.IDRanges {
+		if q.IDRanges[i].contains(uint32(numericID)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortValue returns the value of a numeric column for sorting
+func sortValue(entry *ReportEntry, column ReportColumn) float64 {
+	info := entry.Info
+	switch column {
+	case ReportColumnBytesUsed:
+		return float64(info.BytesUsed)
+	case ReportColumnBytesSoft:
+		return float64(info.Bytes.GetSoft())
+	case ReportColumnBytesHard:
+		return float64(info.Bytes.GetHard())
+	case ReportColumnBytesPercent:
+		return info.BytesPercent()
+	case ReportColumnFilesUsed:
+		return float64(info.FilesUsed)
+	case ReportColumnFilesSoft:
+		return float64(info.Files.GetSoft())
+	case ReportColumnFilesHard:
+		return float64(info.Files.GetHard())
+	case ReportColumnFilesPercent:
+		return info.FilesPercent()
+	}
+	return 0
+}
+
+func validColumn(column ReportColumn) bool {
+	for _, c := range ReportColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the entries of the report selected by q at now, sorted and limited as requested
+func (r *Report) Query(q *ReportQuery, now time.Time) (entries []*ReportEntry, err error) {
+	sortBy := q.SortBy
+	if sortBy == "" {
+		sortBy = ReportColumnID
+	}
+	if !validColumn(sortBy) {
+		err = errors.New("unknown report column " + string(sortBy))
+		return
+	}
+
+	var namePattern *regexp.Regexp
+	if q.NamePattern != "" {
+		if namePattern, err = regexp.Compile(q.NamePattern); err != nil {
+			return
+		}
+	}
+
+	// IDs are visited in numeric order, so entries with equal sort values keep a stable order
+	for _, id := range r.IDs() {
+		info := r.Infos[id]
+		if !q.inRanges(id) || !q.matches(info, now) {
+			continue
+		}
+
+		entry := &ReportEntry{
+			ID:   id,
+			Info: info,
+		}
+		if q.NameFn != nil {
+			entry.Name = q.NameFn(id)
+		}
+		if namePattern != nil && !namePattern.MatchString(entry.Name) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	switch sortBy {
+	case ReportColumnID:
+		if q.Reverse {
+			for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+				entries[i], entries[j] = entries[j], entries[i]
+			}
+		}
+	case ReportColumnName:
+		sort.SliceStable(entries, func(i, j int) bool {
+			if q.Reverse {
+				return entries[i].Name > entries[j].Name
+			}
+			return entries[i].Name < entries[j].Name
+		})
+	default:
+		sort.SliceStable(entries, func(i, j int) bool {
+			a, b := sortValue(entries[i], sortBy), sortValue(entries[j], sortBy)
+			if q.Reverse {
+				return a < b
+			}
+			return a > b
+		})
+	}
+
+	if q.Top > 0 && len(entries) > q.Top {
+		entries = entries[:q.Top]
+	}
+	return
+}
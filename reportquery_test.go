@@ -0,0 +1,85 @@
+package fsquota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testQueryReport(now time.Time) *Report {
+	newInfo := func(bytesUsed, bytesSoft, bytesHard uint64, graceExpires time.Time) *Info {
+		info := &Info{BytesUsed: bytesUsed, BytesGraceExpires: graceExpires}
+		info.Bytes.SetSoft(bytesSoft)
+		info.Bytes.SetHard(bytesHard)
+		return info
+	}
+
+	return &Report{Infos: map[string]*Info{
+		"0":    {},
+		"1000": newInfo(50, 100, 200, time.Time{}),
+		"1001": newInfo(150, 100, 200, now.Add(time.Hour)),
+		"1002": newInfo(200, 100, 200, now.Add(-time.Hour)),
+		"1003": newInfo(10, 0, 0, time.Time{}),
+	}}
+}
+
+func queryIDs(t *testing.T, r *Report, q *ReportQuery, now time.Time) (ids []string) {
+	entries, err := r.Query(q, now)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return
+}
+
+func TestReport_Query(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	r := testQueryReport(now)
+
+	assert.EqualValues(t, []string{"0", "1000", "1001", "1002", "1003"}, queryIDs(t, r, &ReportQuery{}, now))
+	assert.EqualValues(t, []string{"1001", "1002"}, queryIDs(t, r, &ReportQuery{OverSoft: true}, now))
+	assert.EqualValues(t, []string{"1001"}, queryIDs(t, r, &ReportQuery{InGrace: true}, now))
+	assert.EqualValues(t, []string{"1002"}, queryIDs(t, r, &ReportQuery{GraceExpired: true}, now))
+	assert.EqualValues(t, []string{"1002"}, queryIDs(t, r, &ReportQuery{AtHard: true}, now))
+	assert.EqualValues(t, []string{"1000", "1001", "1002"}, queryIDs(t, r, &ReportQuery{HasLimits: true}, now))
+	assert.EqualValues(t, []string{"1000", "1001", "1002", "1003"}, queryIDs(t, r, &ReportQuery{HasUsage: true}, now))
+	assert.EqualValues(t, []string{"1001", "1002"}, queryIDs(t, r, &ReportQuery{MinPercent: 100}, now))
+	assert.EqualValues(t, []string{"1001", "1002", "1003"}, queryIDs(t, r, &ReportQuery{IDRanges: []IDRange{{Min: 1001, Max: 1010}}}, now))
+
+	names := map[string]string{"1000": "alice", "1001": "bob", "1002": "carol"}
+	nameFn := func(id string) string {
+		return names[id]
+	}
+	assert.EqualValues(t, []string{"1000", "1002"}, queryIDs(t, r, &ReportQuery{NamePattern: "^(alice|carol)$", NameFn: nameFn}, now))
+	assert.EqualValues(t, []string{"1002", "1001", "1000", "1003", "0"}, queryIDs(t, r, &ReportQuery{SortBy: ReportColumnBytesUsed}, now))
+	assert.EqualValues(t, []string{"1002", "1001"}, queryIDs(t, r, &ReportQuery{SortBy: ReportColumnBytesUsed, Top: 2}, now))
+	assert.EqualValues(t, []string{"0", "1003", "1000", "1001", "1002"}, queryIDs(t, r, &ReportQuery{SortBy: ReportColumnBytesUsed, Reverse: true}, now))
+	assert.EqualValues(t, []string{"1003", "1002", "1001", "1000", "0"}, queryIDs(t, r, &ReportQuery{Reverse: true}, now))
+	assert.EqualValues(t, []string{"1002", "1001", "1000"}, queryIDs(t, r, &ReportQuery{SortBy: ReportColumnName, Reverse: true, HasLimits: true, NameFn: nameFn}, now))
+
+	_, err := r.Query(&ReportQuery{SortBy: "size"}, now)
+	assert.Error(t, err)
+	_, err = r.Query(&ReportQuery{NamePattern: "("}, now)
+	assert.Error(t, err)
+}
+
+func TestUsagePercent(t *testing.T) {
+	assert.EqualValues(t, 0, UsagePercent(50, 0))
+	assert.EqualValues(t, 25, UsagePercent(50, 200))
+	assert.EqualValues(t, 150, UsagePercent(150, 100))
+}
+
+func TestInfo_Percent(t *testing.T) {
+	i := &Info{BytesUsed: 50, FilesUsed: 5}
+	assert.EqualValues(t, 0, i.BytesPercent())
+
+	i.Bytes.SetHard(200)
+	assert.EqualValues(t, 25, i.BytesPercent())
+	i.Bytes.SetSoft(100)
+	assert.EqualValues(t, 50, i.BytesPercent())
+
+	i.Files.SetHard(10)
+	assert.EqualValues(t, 50, i.FilesPercent())
+}
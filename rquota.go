@@ -0,0 +1,286 @@
+package fsquota
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// RQUOTAPROG
+	rquotaProgram = 100011
+	// RQUOTAVERS
+	rquotaVersion = 1
+	// EXT_RQUOTAVERS
+	rquotaExtVersion = 2
+)
+
+const (
+	// RQUOTAPROC_GETQUOTA
+	rquotaProcGetQuota = 1
+	// RQUOTAPROC_GETACTIVEQUOTA
+	rquotaProcGetActiveQuota = 2
+	// RQUOTAPROC_SETQUOTA
+	rquotaProcSetQuota = 3
+	// RQUOTAPROC_SETACTIVEQUOTA
+	rquotaProcSetActiveQuota = 4
+)
+
+const (
+	// Q_OK
+	rquotaStatusOK = 1
+	// Q_NOQUOTA
+	rquotaStatusNoQuota = 2
+	// Q_EPERM
+	rquotaStatusEPerm = 3
+)
+
+// rquotaSetQLim is the Q_SETQLIM command passed in SETQUOTA requests
+const rquotaSetQLim = 0x0700
+
+// rquotaBlockSize is the block size used when sending limits to a server
+const rquotaBlockSize = 1024
+
+var (
+	// ErrRquotaNoQuota is returned when the remote server has no quota configured for the requested ID
+	ErrRquotaNoQuota = errors.New("rquota: no quota for ID")
+	// ErrRquotaPermissionDenied is returned when the remote server denied the request
+	ErrRquotaPermissionDenied = errors.New("rquota: permission denied")
+)
+
+func rquotaStatusError(status uint32) error {
+	switch status {
+	case rquotaStatusOK:
+		return nil
+	case rquotaStatusNoQuota:
+		return ErrRquotaNoQuota
+	case rquotaStatusEPerm:
+		return ErrRquotaPermissionDenied
+	}
+	return fmt.Errorf("rquota: unknown status %d", status)
+}
+
+// rquota mirrors the rquota structure of the rquota protocol
+type rquota struct {
+	bsize      uint32
+	active     bool
+	bHardLimit uint32
+	bSoftLimit uint32
+	curBlocks  uint32
+	fHardLimit uint32
+	fSoftLimit uint32
+	curFiles   uint32
+	bTimeLeft  uint32
+	fTimeLeft  uint32
+}
+
+func (r *rquota) encode(enc *xdrEncoder) {
+	enc.uint32(r.bsize)
+	enc.bool(r.active)
+	r.encodeDqblk(enc)
+}
+
+// encodeDqblk encodes the fields making up the sq_dqblk structure
+func (r *rquota) encodeDqblk(enc *xdrEncoder) {
+	enc.uint32(r.bHardLimit)
+	enc.uint32(r.bSoftLimit)
+	enc.uint32(r.curBlocks)
+	enc.uint32(r.fHardLimit)
+	enc.uint32(r.fSoftLimit)
+	enc.uint32(r.curFiles)
+	enc.uint32(r.bTimeLeft)
+	enc.uint32(r.fTimeLeft)
+}
+
+func decodeRquota(dec *xdrDecoder) (r *rquota, err error) {
+	r = &rquota{
+		bsize:  dec.uint32(),
+		active: dec.bool(),
+	}
+	decodeRquotaDqblk(dec, r)
+
+	if err = dec.err; err != nil {
+		r = nil
+	}
+	return
+}
+
+func decodeRquotaDqblk(dec *xdrDecoder, r *rquota) {
+	r.bHardLimit = dec.uint32()
+	r.bSoftLimit = dec.uint32()
+	r.curBlocks = dec.uint32()
+	r.fHardLimit = dec.uint32()
+	r.fSoftLimit = dec.uint32()
+	r.curFiles = dec.uint32()
+	r.bTimeLeft = dec.uint32()
+	r.fTimeLeft = dec.uint32()
+}
+
+func (r *rquota) toInfo() *Info {
+	bsize := uint64(r.bsize)
+	bytesHard := uint64(r.bHardLimit) * bsize
+	bytesSoft := uint64(r.bSoftLimit) * bsize
+	filesHard := uint64(r.fHardLimit)
+	filesSoft := uint64(r.fSoftLimit)
+
+	return &Info{
+		Limits: Limits{
+			Bytes: Limit{
+				hard: &bytesHard,
+				soft: &bytesSoft,
+			},
+			Files: Limit{
+				hard: &filesHard,
+				soft: &filesSoft,
+			},
+		},
+		BytesUsed: uint64(r.curBlocks) * bsize,
+		FilesUsed: uint64(r.curFiles),
+		// rquota transfers the remaining grace time
+		BytesGraceExpires: graceExpiresAt(secondsFromNow(r.bTimeLeft)),
+		FilesGraceExpires: graceExpiresAt(secondsFromNow(r.fTimeLeft)),
+	}
+}
+
+// secondsFromNow returns the time in seconds since the epoch the given number of seconds from now, zero if seconds is zero
+func secondsFromNow(seconds uint32) uint64 {
+	if seconds == 0 {
+		return 0
+	}
+	return uint64(time.Now().Unix()) + uint64(seconds)
+}
+
+// rquotaBlockSizeFor returns the smallest block size, starting at rquotaBlockSize, which all values fit into 32 bits with
+func rquotaBlockSizeFor(values ...uint64) uint64 {
+	bsize := uint64(rquotaBlockSize)
+	for _, value := range values {
+		for value/bsize > math.MaxUint32 {
+			bsize *= 2
+		}
+	}
+	return bsize
+}
+
+// rquotaFromLimits converts limits for SETQUOTA. Its arguments carry no block size, limits are always transferred in
+// blocks of rquotaBlockSize, so limits not fitting into 32 bits are rejected instead of wrapping.
+func rquotaFromLimits(limits *Limits) (r *rquota, err error) {
+	bytesHard, bytesSoft, _ := limits.Bytes.getValues()
+	filesHard, filesSoft, _ := limits.Files.getValues()
+	if bytesHard/rquotaBlockSize > math.MaxUint32 || bytesSoft/rquotaBlockSize > math.MaxUint32 {
+		err = errors.New("rquota: byte limits exceed the supported range")
+		return
+	}
+	if filesHard > math.MaxUint32 || filesSoft > math.MaxUint32 {
+		err = errors.New("rquota: file limits exceed the supported range")
+		return
+	}
+
+	r = &rquota{
+		bsize:      rquotaBlockSize,
+		active:     true,
+		bHardLimit: uint32(bytesHard / rquotaBlockSize),
+		bSoftLimit: uint32(bytesSoft / rquotaBlockSize),
+		fHardLimit: uint32(filesHard),
+		fSoftLimit: uint32(filesSoft),
+	}
+	return
+}
+
+// rquotaClient queries quotas of NFS exports from a remote rpc.rquotad
+type rquotaClient struct {
+	// Host running the rquota service
+	host string
+	// Port of the portmapper on host
+	portmapperPort uint16
+}
+
+func newRquotaClient(host string) *rquotaClient {
+	return &rquotaClient{
+		host:           host,
+		portmapperPort: portmapPort,
+	}
+}
+
+// call invokes proc using the extended protocol version, falling back to version 1 for user quotas
+func (c *rquotaClient) call(proc uint32, t quotaCtlType, encodeArgs func(enc *xdrEncoder, extended bool)) (results *xdrDecoder, err error) {
+	var client *rpcClient
+	extended := true
+	if client, err = dialRPCProgram(c.host, c.portmapperPort, rquotaProgram, rquotaExtVersion); isRPCProgramMismatch(err) && t == userQuota {
+		// Version 1 only supports user quotas
+		extended = false
+		client, err = dialRPCProgram(c.host, c.portmapperPort, rquotaProgram, rquotaVersion)
+	}
+
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	vers := uint32(rquotaExtVersion)
+	if !extended {
+		vers = rquotaVersion
+	}
+
+	args := &xdrEncoder{}
+	encodeArgs(args, extended)
+
+	return client.call(rquotaProgram, vers, proc, args.bytes())
+}
+
+func (c *rquotaClient) getQuota(t quotaCtlType, exportPath string, id uint32) (info *Info, err error) {
+	var results *xdrDecoder
+	if results, err = c.call(rquotaProcGetQuota, t, func(enc *xdrEncoder, extended bool) {
+		enc.string(exportPath)
+		if extended {
+			enc.uint32(uint32(t))
+		}
+		enc.uint32(id)
+	}); err != nil {
+		return
+	}
+
+	if err = rquotaStatusError(results.uint32()); err != nil {
+		return
+	}
+
+	var r *rquota
+	if r, err = decodeRquota(results); err != nil {
+		return
+	}
+
+	info = r.toInfo()
+	return
+}
+
+func (c *rquotaClient) setQuota(t quotaCtlType, exportPath string, id uint32, limits *Limits) (info *Info, err error) {
+	var requested *rquota
+	if requested, err = rquotaFromLimits(limits); err != nil {
+		return
+	}
+
+	var results *xdrDecoder
+	if results, err = c.call(rquotaProcSetQuota, t, func(enc *xdrEncoder, extended bool) {
+		enc.int32(rquotaSetQLim)
+		enc.string(exportPath)
+		enc.uint32(id)
+		if extended {
+			enc.uint32(uint32(t))
+		}
+		requested.encodeDqblk(enc)
+	}); err != nil {
+		return
+	}
+
+	if err = rquotaStatusError(results.uint32()); err != nil {
+		return
+	}
+
+	var r *rquota
+	if r, err = decodeRquota(results); err != nil {
+		return
+	}
+
+	info = r.toInfo()
+	return
+}
@@ -0,0 +1,258 @@
+package fsquota
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rquotaStub is an in-process portmapper and rquota server used for testing the rquota client
+type rquotaStub struct {
+	t        *testing.T
+	listener net.Listener
+	// extended indicates if version 2 of the rquota protocol is served
+	extended bool
+
+	mu     sync.Mutex
+	quotas map[quotaCtlType]map[uint32]*rquota
+	paths  []string
+}
+
+func newRquotaStub(t *testing.T, extended bool) *rquotaStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	stub := &rquotaStub{
+		t:        t,
+		listener: listener,
+		extended: extended,
+		quotas: map[quotaCtlType]map[uint32]*rquota{
+			userQuota:  make(map[uint32]*rquota),
+			groupQuota: make(map[uint32]*rquota),
+		},
+	}
+	go stub.serve()
+	return stub
+}
+
+func (s *rquotaStub) Close() {
+	s.listener.Close()
+}
+
+func (s *rquotaStub) client() *rquotaClient {
+	client := newRquotaClient("127.0.0.1")
+	client.portmapperPort = uint16(s.listener.Addr().(*net.TCPAddr).Port)
+	return client
+}
+
+func (s *rquotaStub) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			for {
+				record, err := readRPCRecord(conn)
+				if err != nil {
+					return
+				}
+
+				if err = writeRPCRecord(conn, s.handle(record)); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (s *rquotaStub) handle(record []byte) []byte {
+	dec := newXDRDecoder(record)
+	xid := dec.uint32()
+	dec.uint32() // message type
+	dec.uint32() // RPC version
+	prog := dec.uint32()
+	vers := dec.uint32()
+	proc := dec.uint32()
+	dec.uint32() // credentials flavor
+	dec.opaque()
+	dec.uint32() // verifier flavor
+	dec.opaque()
+	require.NoError(s.t, dec.err)
+
+	reply := &xdrEncoder{}
+	reply.uint32(xid)
+	reply.uint32(rpcMsgReply)
+	reply.uint32(rpcReplyAccepted)
+	reply.uint32(rpcAuthNone)
+	reply.opaque(nil)
+
+	switch {
+	case prog == portmapProgram && proc == portmapProcGetPort:
+		requestedProg := dec.uint32()
+		requestedVers := dec.uint32()
+
+		reply.uint32(rpcAcceptSuccess)
+		if requestedProg == rquotaProgram && (requestedVers == rquotaVersion || s.extended) {
+			reply.uint32(uint32(s.listener.Addr().(*net.TCPAddr).Port))
+		} else {
+			reply.uint32(0)
+		}
+	case prog == rquotaProgram:
+		reply.uint32(rpcAcceptSuccess)
+		s.handleRquota(vers == rquotaExtVersion, proc, dec, reply)
+	default:
+		reply.uint32(rpcAcceptProgUnavail)
+	}
+
+	return reply.bytes()
+}
+
+func (s *rquotaStub) handleRquota(extended bool, proc uint32, args *xdrDecoder, reply *xdrEncoder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := userQuota
+	var id uint32
+	var r *rquota
+
+	switch proc {
+	case rquotaProcGetQuota:
+		s.paths = append(s.paths, args.string())
+		if extended {
+			t = quotaCtlType(args.uint32())
+		}
+		id = args.uint32()
+		r = s.quotas[t][id]
+	case rquotaProcSetQuota:
+		args.int32() // command
+		s.paths = append(s.paths, args.string())
+		id = args.uint32()
+		if extended {
+			t = quotaCtlType(args.uint32())
+		}
+		r = &rquota{bsize: rquotaBlockSize, active: true}
+		decodeRquotaDqblk(args, r)
+		s.quotas[t][id] = r
+	}
+	require.NoError(s.t, args.err)
+
+	if r == nil {
+		reply.uint32(rquotaStatusNoQuota)
+		return
+	}
+
+	reply.uint32(rquotaStatusOK)
+	r.encode(reply)
+}
+
+func TestRquotaFromLimits(t *testing.T) {
+	limits := &Limits{}
+	limits.Bytes.SetHard(1 << 40)
+	limits.Bytes.SetSoft(1 << 20)
+	limits.Files.SetHard(100)
+
+	r, err := rquotaFromLimits(limits)
+	require.NoError(t, err)
+	assert.EqualValues(t, rquotaBlockSize, r.bsize)
+	assert.EqualValues(t, 1<<30, r.bHardLimit)
+	assert.EqualValues(t, 1024, r.bSoftLimit)
+	assert.EqualValues(t, 100, r.fHardLimit)
+
+	limits.Bytes.SetHard(5 << 40)
+	_, err = rquotaFromLimits(limits)
+	assert.Error(t, err)
+
+	limits.Bytes.SetHard(1 << 40)
+	limits.Files.SetSoft(1 << 32)
+	_, err = rquotaFromLimits(limits)
+	assert.Error(t, err)
+}
+
+func TestRquotaClient(t *testing.T) {
+	t.Run("GetQuota", func(t *testing.T) {
+		stub := newRquotaStub(t, true)
+		defer stub.Close()
+
+		stub.quotas[groupQuota][1000] = &rquota{
+			bsize:      4096,
+			active:     true,
+			bHardLimit: 20,
+			bSoftLimit: 10,
+			curBlocks:  5,
+			fHardLimit: 200,
+			fSoftLimit: 100,
+			curFiles:   50,
+		}
+
+		info, err := stub.client().getQuota(groupQuota, "/export/home", 1000)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+
+		assert.EqualValues(t, 20*4096, info.Bytes.GetHard())
+		assert.EqualValues(t, 10*4096, info.Bytes.GetSoft())
+		assert.EqualValues(t, 5*4096, info.BytesUsed)
+		assert.EqualValues(t, 200, info.Files.GetHard())
+		assert.EqualValues(t, 100, info.Files.GetSoft())
+		assert.EqualValues(t, 50, info.FilesUsed)
+		assert.EqualValues(t, []string{"/export/home"}, stub.paths)
+	})
+
+	t.Run("NoQuota", func(t *testing.T) {
+		stub := newRquotaStub(t, true)
+		defer stub.Close()
+
+		info, err := stub.client().getQuota(userQuota, "/export/home", 1000)
+		assert.Nil(t, info)
+		assert.EqualValues(t, ErrRquotaNoQuota, err)
+	})
+
+	t.Run("SetQuota", func(t *testing.T) {
+		stub := newRquotaStub(t, true)
+		defer stub.Close()
+
+		limits := &Limits{}
+		limits.Bytes.SetHard(2 * 1024 * 1024)
+		limits.Bytes.SetSoft(1024 * 1024)
+		limits.Files.SetHard(2000)
+		limits.Files.SetSoft(1000)
+
+		info, err := stub.client().setQuota(userQuota, "/export/home", 1000, limits)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+
+		assert.EqualValues(t, 2*1024*1024, info.Bytes.GetHard())
+		assert.EqualValues(t, 1024*1024, info.Bytes.GetSoft())
+		assert.EqualValues(t, 2000, info.Files.GetHard())
+		assert.EqualValues(t, 1000, info.Files.GetSoft())
+
+		stored := stub.quotas[userQuota][1000]
+		require.NotNil(t, stored)
+		assert.EqualValues(t, 2048, stored.bHardLimit)
+		assert.EqualValues(t, 1024, stored.bSoftLimit)
+	})
+
+	t.Run("Version1Fallback", func(t *testing.T) {
+		stub := newRquotaStub(t, false)
+		defer stub.Close()
+
+		stub.quotas[userQuota][1000] = &rquota{
+			bsize:      1024,
+			active:     true,
+			bHardLimit: 2,
+		}
+
+		info, err := stub.client().getQuota(userQuota, "/export/home", 1000)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2048, info.Bytes.GetHard())
+
+		// Group quotas require the extended protocol
+		_, err = stub.client().getQuota(groupQuota, "/export/home", 1000)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,357 @@
+package fsquota
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxRPCDatagramLength defines the size of the buffer used for receiving RPC calls via UDP
+const maxRPCDatagramLength = 64 * 1024
+
+// RquotaCaller describes the client issuing an rquota request
+type RquotaCaller struct {
+	// Network address of the client
+	Addr net.Addr
+	// Authenticated is true if the client provided AUTH_SYS credentials
+	Authenticated bool
+	// UID claimed by the client's AUTH_SYS credentials
+	UID uint32
+	// GID claimed by the client's AUTH_SYS credentials
+	GID uint32
+	// Machine name claimed by the client's AUTH_SYS credentials
+	MachineName string
+}
+
+// RquotaServer exports local quotas via the rquota protocol, serving both version 1 and the extended version 2
+type RquotaServer struct {
+	// Exports maps exported paths as requested by clients to local paths.
+	// If empty, requested paths are looked up locally as-is.
+	Exports map[string]string
+
+	// AllowSetQuota decides whether a caller may set quotas.
+	// If nil, all SETQUOTA requests are denied.
+	AllowSetQuota func(caller *RquotaCaller) bool
+
+	// getQuotaFn, setQuotaFn and activeFn allow replacing the quota backend in tests
+	getQuotaFn func(t quotaCtlType, path string, idString string) (*Info, error)
+	setQuotaFn func(t quotaCtlType, path string, idString string, limits *Limits) (*Info, error)
+	activeFn   func(t quotaCtlType, path string) (bool, error)
+}
+
+func (s *RquotaServer) getQuota(t quotaCtlType, path string, id uint32) (*Info, error) {
+	if s.getQuotaFn != nil {
+		return s.getQuotaFn(t, path, fmt.Sprint(id))
+	}
+	return getQuota(t, path, fmt.Sprint(id))
+}
+
+func (s *RquotaServer) setQuota(t quotaCtlType, path string, id uint32, limits *Limits) (*Info, error) {
+	if s.setQuotaFn != nil {
+		return s.setQuotaFn(t, path, fmt.Sprint(id), limits)
+	}
+	return policySetQuota(nil)(t, path, fmt.Sprint(id), limits)
+}
+
+// quotasActive reports whether quotas of type t are enabled on the filesystem of path
+func (s *RquotaServer) quotasActive(t quotaCtlType, path string) bool {
+	activeFn := quotasSupported
+	if s.activeFn != nil {
+		activeFn = s.activeFn
+	}
+
+	active, err := activeFn(t, path)
+	return err == nil && active
+}
+
+// resolvePath maps a path requested by a client to a local path
+func (s *RquotaServer) resolvePath(requested string) (local string, ok bool) {
+	requested = filepath.Clean(requested)
+	if !filepath.IsAbs(requested) {
+		return
+	}
+
+	if len(s.Exports) == 0 {
+		return requested, true
+	}
+
+	// Use the most specific export containing the requested path
+	var matchedExport, localRoot string
+	for export, root := range s.Exports {
+		export = filepath.Clean(export)
+		if len(export) <= len(matchedExport) {
+			continue
+		}
+
+		if requested == export || strings.HasPrefix(requested, strings.TrimSuffix(export, "/")+"/") {
+			matchedExport, localRoot = export, root
+		}
+	}
+
+	if matchedExport == "" {
+		return
+	}
+
+	return filepath.Join(localRoot, strings.TrimPrefix(requested, matchedExport)), true
+}
+
+// clampUint32 limits v to the range of the 32-bit fields of the rquota protocol
+func clampUint32(v uint64) uint32 {
+	if v > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(v)
+}
+
+// rquotaFromInfo converts an Info structure, choosing a block size large enough for all values to fit.
+// File counts exceeding the protocol's range are clamped.
+func rquotaFromInfo(info *Info, active bool) *rquota {
+	bytesHard, bytesSoft, _ := info.Bytes.getValues()
+	filesHard, filesSoft, _ := info.Files.getValues()
+
+	bsize := rquotaBlockSizeFor(bytesHard, bytesSoft, info.BytesUsed)
+
+	now := time.Now()
+	return &rquota{
+		bTimeLeft:  graceTimeLeft(info.BytesGraceExpires, now),
+		fTimeLeft:  graceTimeLeft(info.FilesGraceExpires, now),
+		bsize:      uint32(bsize),
+		active:     active,
+		bHardLimit: uint32(bytesHard / bsize),
+		bSoftLimit: uint32(bytesSoft / bsize),
+		curBlocks:  uint32(info.BytesUsed / bsize),
+		fHardLimit: clampUint32(filesHard),
+		fSoftLimit: clampUint32(filesSoft),
+		curFiles:   clampUint32(info.FilesUsed),
+	}
+}
+
+func (s *RquotaServer) handleGetQuota(call *rpcCall) (results *xdrEncoder, ok bool) {
+	path := call.args.string()
+	t := userQuota
+	if call.vers == rquotaExtVersion {
+		t = quotaCtlType(call.args.uint32())
+	}
+	id := call.args.uint32()
+
+	if call.args.err != nil {
+		return
+	}
+
+	results = &xdrEncoder{}
+	ok = true
+
+	localPath, pathOK := s.resolvePath(path)
+	if !pathOK {
+		results.uint32(rquotaStatusNoQuota)
+		return
+	}
+
+	// GETACTIVEQUOTA only reports quotas of filesystems with quotas enabled
+	active := s.quotasActive(t, localPath)
+	if !active && call.proc == rquotaProcGetActiveQuota {
+		results.uint32(rquotaStatusNoQuota)
+		return
+	}
+
+	info, err := s.getQuota(t, localPath, id)
+	if err != nil {
+		results.uint32(rquotaStatusNoQuota)
+		return
+	}
+
+	results.uint32(rquotaStatusOK)
+	rquotaFromInfo(info, active).encode(results)
+	return
+}
+
+// rquotaSetStatus maps an error setting a quota to a status. Policy violations and permission errors are reported as
+// Q_EPERM, all other failures as Q_NOQUOTA.
+func rquotaSetStatus(err error) uint32 {
+	if _, isPolicyError := err.(*PolicyError); isPolicyError || os.IsPermission(err) {
+		return rquotaStatusEPerm
+	}
+	return rquotaStatusNoQuota
+}
+
+func (s *RquotaServer) handleSetQuota(call *rpcCall, caller *RquotaCaller) (results *xdrEncoder, ok bool) {
+	call.args.int32() // command, only limits are ever set
+	path := call.args.string()
+	id := call.args.uint32()
+	t := userQuota
+	if call.vers == rquotaExtVersion {
+		t = quotaCtlType(call.args.uint32())
+	}
+
+	requested := &rquota{}
+	decodeRquotaDqblk(call.args, requested)
+
+	if call.args.err != nil {
+		return
+	}
+
+	results = &xdrEncoder{}
+	ok = true
+
+	if s.AllowSetQuota == nil || !s.AllowSetQuota(caller) {
+		results.uint32(rquotaStatusEPerm)
+		return
+	}
+
+	localPath, pathOK := s.resolvePath(path)
+	if !pathOK {
+		results.uint32(rquotaStatusNoQuota)
+		return
+	}
+
+	// SETQUOTA arguments carry no block size, the protocol transfers limits in blocks of rquotaBlockSize
+	limits := &Limits{}
+	limits.Bytes.SetHard(uint64(requested.bHardLimit) * rquotaBlockSize)
+	limits.Bytes.SetSoft(uint64(requested.bSoftLimit) * rquotaBlockSize)
+	limits.Files.SetHard(uint64(requested.fHardLimit))
+	limits.Files.SetSoft(uint64(requested.fSoftLimit))
+
+	info, err := s.setQuota(t, localPath, id, limits)
+	if err != nil {
+		results.uint32(rquotaSetStatus(err))
+		return
+	}
+
+	results.uint32(rquotaStatusOK)
+	rquotaFromInfo(info, s.quotasActive(t, localPath)).encode(results)
+	return
+}
+
+// handle processes a single RPC call record and returns the reply record
+func (s *RquotaServer) handle(record []byte, addr net.Addr) (reply []byte, err error) {
+	var call *rpcCall
+	if call, err = decodeRPCCall(record); err != nil {
+		return
+	}
+
+	if call.prog != rquotaProgram {
+		return encodeRPCReply(call.xid, rpcAcceptProgUnavail, nil), nil
+	} else if call.vers != rquotaVersion && call.vers != rquotaExtVersion {
+		return encodeRPCProgMismatchReply(call.xid, rquotaVersion, rquotaExtVersion), nil
+	}
+
+	caller := &RquotaCaller{
+		Addr: addr,
+	}
+	if call.authSys != nil {
+		caller.Authenticated = true
+		caller.UID = call.authSys.uid
+		caller.GID = call.authSys.gid
+		caller.MachineName = call.authSys.machineName
+	}
+
+	var results *xdrEncoder
+	var ok bool
+	switch call.proc {
+	case 0:
+		// NULL procedure
+		results, ok = &xdrEncoder{}, true
+	case rquotaProcGetQuota, rquotaProcGetActiveQuota:
+		results, ok = s.handleGetQuota(call)
+	case rquotaProcSetQuota, rquotaProcSetActiveQuota:
+		results, ok = s.handleSetQuota(call, caller)
+	default:
+		return encodeRPCReply(call.xid, rpcAcceptProcUnavail, nil), nil
+	}
+
+	if !ok {
+		return encodeRPCReply(call.xid, rpcAcceptGarbageArgs, nil), nil
+	}
+
+	return encodeRPCReply(call.xid, rpcAcceptSuccess, results.bytes()), nil
+}
+
+// ServeTCP accepts connections on listener and serves rquota requests until the listener is closed
+func (s *RquotaServer) ServeTCP(listener net.Listener) (err error) {
+	for {
+		var conn net.Conn
+		if conn, err = listener.Accept(); err != nil {
+			return
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+func (s *RquotaServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		record, err := readRPCRecord(conn)
+		if err != nil {
+			return
+		}
+
+		var reply []byte
+		if reply, err = s.handle(record, conn.RemoteAddr()); err != nil {
+			// Undecodable call: drop the connection
+			return
+		}
+
+		if err = writeRPCRecord(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// ServeUDP serves rquota requests received on conn until it is closed
+func (s *RquotaServer) ServeUDP(conn net.PacketConn) (err error) {
+	buf := make([]byte, maxRPCDatagramLength)
+	for {
+		var n int
+		var addr net.Addr
+		if n, addr, err = conn.ReadFrom(buf); err != nil {
+			return
+		}
+
+		reply, handleErr := s.handle(buf[:n], addr)
+		if handleErr != nil {
+			// Undecodable datagrams are dropped
+			continue
+		}
+
+		if _, err = conn.WriteTo(reply, addr); err != nil {
+			return
+		}
+	}
+}
+
+// RegisterWithPortmapper registers both rquota versions with the local portmapper.
+// Ports set to 0 are not registered.
+func (s *RquotaServer) RegisterWithPortmapper(tcpPort, udpPort uint16) (err error) {
+	for _, vers := range []uint32{rquotaVersion, rquotaExtVersion} {
+		if tcpPort != 0 {
+			if err = rpcPortmapSet(false, rquotaProgram, vers, portmapProtoTCP, tcpPort); err != nil {
+				return
+			}
+		}
+
+		if udpPort != 0 {
+			if err = rpcPortmapSet(false, rquotaProgram, vers, portmapProtoUDP, udpPort); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// UnregisterFromPortmapper removes all rquota registrations from the local portmapper
+func (s *RquotaServer) UnregisterFromPortmapper() (err error) {
+	for _, vers := range []uint32{rquotaVersion, rquotaExtVersion} {
+		if err = rpcPortmapSet(true, rquotaProgram, vers, 0, 0); err != nil {
+			return
+		}
+	}
+
+	return
+}
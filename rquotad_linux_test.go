@@ -0,0 +1,284 @@
+package fsquota
+
+import (
+	"errors"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRquotaServer() (server *RquotaServer, quotas map[string]*Info) {
+	quotas = make(map[string]*Info)
+	server = &RquotaServer{
+		getQuotaFn: func(t quotaCtlType, path string, idString string) (*Info, error) {
+			if info, ok := quotas[path+":"+idString]; ok {
+				return info, nil
+			}
+			return nil, errors.New("no quota")
+		},
+		setQuotaFn: func(t quotaCtlType, path string, idString string, limits *Limits) (*Info, error) {
+			info := &Info{}
+			info.Bytes.set(&limits.Bytes)
+			info.Files.set(&limits.Files)
+			quotas[path+":"+idString] = info
+			return info, nil
+		},
+		activeFn: func(t quotaCtlType, path string) (bool, error) {
+			return true, nil
+		},
+	}
+	return
+}
+
+func startTestRquotaServer(t *testing.T, server *RquotaServer) (client *rpcClient, stop func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.ServeTCP(listener)
+
+	client, err = dialRPC(listener.Addr().String())
+	require.NoError(t, err)
+
+	stop = func() {
+		client.Close()
+		listener.Close()
+	}
+	return
+}
+
+func encodeGetQuotaArgs(path string, t quotaCtlType, id uint32) []byte {
+	args := &xdrEncoder{}
+	args.string(path)
+	args.uint32(uint32(t))
+	args.uint32(id)
+	return args.bytes()
+}
+
+func TestRquotaServer_ResolvePath(t *testing.T) {
+	t.Run("NoExports", func(t *testing.T) {
+		server := &RquotaServer{}
+		local, ok := server.resolvePath("/srv/home/")
+		assert.True(t, ok)
+		assert.EqualValues(t, "/srv/home", local)
+
+		_, ok = server.resolvePath("relative")
+		assert.False(t, ok)
+	})
+
+	t.Run("Exports", func(t *testing.T) {
+		server := &RquotaServer{
+			Exports: map[string]string{
+				"/export":         "/srv",
+				"/export/home":    "/home",
+				"/export/scratch": "/scratch",
+			},
+		}
+
+		local, ok := server.resolvePath("/export/home")
+		assert.True(t, ok)
+		assert.EqualValues(t, "/home", local)
+
+		local, ok = server.resolvePath("/export/home/user")
+		assert.True(t, ok)
+		assert.EqualValues(t, "/home/user", local)
+
+		local, ok = server.resolvePath("/export/homes")
+		assert.True(t, ok)
+		assert.EqualValues(t, "/srv/homes", local)
+
+		_, ok = server.resolvePath("/other")
+		assert.False(t, ok)
+	})
+}
+
+func TestRquotaFromInfo(t *testing.T) {
+	info := &Info{
+		BytesUsed: 1 << 43,
+		FilesUsed: 10,
+	}
+	info.Bytes.SetHard(1 << 44)
+	info.Bytes.SetSoft(1 << 20)
+	info.Files.SetHard(1 << 40)
+
+	r := rquotaFromInfo(info, true)
+	assert.EqualValues(t, 8192, r.bsize)
+	assert.EqualValues(t, 1<<31, r.bHardLimit)
+	assert.EqualValues(t, 1<<30, r.curBlocks)
+	assert.EqualValues(t, 128, r.bSoftLimit)
+	assert.EqualValues(t, 10, r.curFiles)
+	assert.EqualValues(t, uint32(math.MaxUint32), r.fHardLimit)
+	assert.True(t, r.active)
+}
+
+func TestRquotaServer_GetQuota(t *testing.T) {
+	server, quotas := newTestRquotaServer()
+	info := &Info{
+		BytesUsed: 4096,
+		FilesUsed: 3,
+	}
+	info.Bytes.SetHard(8192)
+	quotas["/home:1000"] = info
+
+	server.Exports = map[string]string{"/export/home": "/home"}
+
+	client, stop := startTestRquotaServer(t, server)
+	defer stop()
+
+	t.Run("OK", func(t *testing.T) {
+		results, err := client.call(rquotaProgram, rquotaExtVersion, rquotaProcGetQuota, encodeGetQuotaArgs("/export/home", userQuota, 1000))
+		require.NoError(t, err)
+		require.NoError(t, rquotaStatusError(results.uint32()))
+
+		r, err := decodeRquota(results)
+		require.NoError(t, err)
+		assert.EqualValues(t, 8192, r.toInfo().Bytes.GetHard())
+		assert.EqualValues(t, 4096, r.toInfo().BytesUsed)
+		assert.EqualValues(t, 3, r.toInfo().FilesUsed)
+	})
+
+	t.Run("NoQuota", func(t *testing.T) {
+		results, err := client.call(rquotaProgram, rquotaExtVersion, rquotaProcGetActiveQuota, encodeGetQuotaArgs("/export/home", userQuota, 1001))
+		require.NoError(t, err)
+		assert.EqualValues(t, ErrRquotaNoQuota, rquotaStatusError(results.uint32()))
+	})
+
+	t.Run("Inactive", func(t *testing.T) {
+		server, inactiveQuotas := newTestRquotaServer()
+		inactiveQuotas["/home:1000"] = info
+		server.Exports = map[string]string{"/export/home": "/home"}
+		server.activeFn = func(t quotaCtlType, path string) (bool, error) {
+			return false, nil
+		}
+
+		client, stop := startTestRquotaServer(t, server)
+		defer stop()
+
+		results, err := client.call(rquotaProgram, rquotaExtVersion, rquotaProcGetActiveQuota, encodeGetQuotaArgs("/export/home", userQuota, 1000))
+		require.NoError(t, err)
+		assert.EqualValues(t, ErrRquotaNoQuota, rquotaStatusError(results.uint32()))
+
+		results, err = client.call(rquotaProgram, rquotaExtVersion, rquotaProcGetQuota, encodeGetQuotaArgs("/export/home", userQuota, 1000))
+		require.NoError(t, err)
+		require.NoError(t, rquotaStatusError(results.uint32()))
+
+		r, err := decodeRquota(results)
+		require.NoError(t, err)
+		assert.False(t, r.active)
+	})
+
+	t.Run("UnknownExport", func(t *testing.T) {
+		results, err := client.call(rquotaProgram, rquotaExtVersion, rquotaProcGetQuota, encodeGetQuotaArgs("/home", userQuota, 1000))
+		require.NoError(t, err)
+		assert.EqualValues(t, ErrRquotaNoQuota, rquotaStatusError(results.uint32()))
+	})
+
+	t.Run("VersionMismatch", func(t *testing.T) {
+		_, err := client.call(rquotaProgram, 3, rquotaProcGetQuota, nil)
+		assert.True(t, isRPCProgramMismatch(err))
+	})
+
+	t.Run("GarbageArgs", func(t *testing.T) {
+		_, err := client.call(rquotaProgram, rquotaExtVersion, rquotaProcGetQuota, nil)
+		if assert.Error(t, err) {
+			assert.EqualValues(t, rpcAcceptGarbageArgs, err.(*rpcError).stat)
+		}
+	})
+}
+
+func TestRquotaServer_SetQuota(t *testing.T) {
+	encodeSetQuotaArgs := func() []byte {
+		args := &xdrEncoder{}
+		args.int32(rquotaSetQLim)
+		args.string("/home")
+		args.uint32(1000)
+		args.uint32(uint32(groupQuota))
+		(&rquota{bHardLimit: 4, bSoftLimit: 2, fHardLimit: 20, fSoftLimit: 10}).encodeDqblk(args)
+		return args.bytes()
+	}
+
+	t.Run("Denied", func(t *testing.T) {
+		server, quotas := newTestRquotaServer()
+		client, stop := startTestRquotaServer(t, server)
+		defer stop()
+
+		results, err := client.call(rquotaProgram, rquotaExtVersion, rquotaProcSetQuota, encodeSetQuotaArgs())
+		require.NoError(t, err)
+		assert.EqualValues(t, ErrRquotaPermissionDenied, rquotaStatusError(results.uint32()))
+		assert.Empty(t, quotas)
+	})
+
+	t.Run("Allowed", func(t *testing.T) {
+		server, quotas := newTestRquotaServer()
+
+		var seenCaller *RquotaCaller
+		server.AllowSetQuota = func(caller *RquotaCaller) bool {
+			seenCaller = caller
+			return true
+		}
+
+		client, stop := startTestRquotaServer(t, server)
+		defer stop()
+
+		results, err := client.call(rquotaProgram, rquotaExtVersion, rquotaProcSetQuota, encodeSetQuotaArgs())
+		require.NoError(t, err)
+		require.NoError(t, rquotaStatusError(results.uint32()))
+
+		require.NotNil(t, seenCaller)
+		assert.True(t, seenCaller.Authenticated)
+
+		info := quotas["/home:1000"]
+		require.NotNil(t, info)
+		assert.EqualValues(t, 4096, info.Bytes.GetHard())
+		assert.EqualValues(t, 2048, info.Bytes.GetSoft())
+		assert.EqualValues(t, 20, info.Files.GetHard())
+		assert.EqualValues(t, 10, info.Files.GetSoft())
+	})
+
+	t.Run("PolicyViolated", func(t *testing.T) {
+		server, _ := newTestRquotaServer()
+		server.AllowSetQuota = func(caller *RquotaCaller) bool {
+			return true
+		}
+		server.setQuotaFn = func(t quotaCtlType, path string, idString string, limits *Limits) (*Info, error) {
+			return nil, &PolicyError{Type: QuotaType(t), ID: idString, Violations: []string{"ID is protected"}}
+		}
+
+		client, stop := startTestRquotaServer(t, server)
+		defer stop()
+
+		results, err := client.call(rquotaProgram, rquotaExtVersion, rquotaProcSetQuota, encodeSetQuotaArgs())
+		require.NoError(t, err)
+		assert.EqualValues(t, ErrRquotaPermissionDenied, rquotaStatusError(results.uint32()))
+	})
+}
+
+func TestRquotaServer_ServeUDP(t *testing.T) {
+	server, quotas := newTestRquotaServer()
+	quotas["/home:1000"] = &Info{FilesUsed: 7}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	go server.ServeUDP(conn)
+
+	clientConn, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	_, err = clientConn.Write(encodeRPCCall(42, rquotaProgram, rquotaExtVersion, rquotaProcGetQuota, encodeGetQuotaArgs("/home", userQuota, 1000)))
+	require.NoError(t, err)
+
+	buf := make([]byte, maxRPCDatagramLength)
+	n, err := clientConn.Read(buf)
+	require.NoError(t, err)
+
+	results, err := decodeRPCReply(42, buf[:n])
+	require.NoError(t, err)
+	require.NoError(t, rquotaStatusError(results.uint32()))
+
+	r, err := decodeRquota(results)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, r.curFiles)
+}
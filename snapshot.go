@@ -0,0 +1,184 @@
+package fsquota
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by WriteSnapshot
+const SnapshotVersion = 1
+
+// SnapshotEntry contains the limits and usage of a single ID
+type SnapshotEntry struct {
+	BytesSoft uint64 `json:"bytes_soft"`
+	BytesHard uint64 `json:"bytes_hard"`
+	BytesUsed uint64 `json:"bytes_used"`
+	FilesSoft uint64 `json:"files_soft"`
+	FilesHard uint64 `json:"files_hard"`
+	FilesUsed uint64 `json:"files_used"`
+}
+
+func newSnapshotEntry(info *Info) *SnapshotEntry {
+	bytesHard, bytesSoft, _ := info.Bytes.getValues()
+	filesHard, filesSoft, _ := info.Files.getValues()
+
+	return &SnapshotEntry{
+		BytesSoft: bytesSoft,
+		BytesHard: bytesHard,
+		BytesUsed: info.BytesUsed,
+		FilesSoft: filesSoft,
+		FilesHard: filesHard,
+		FilesUsed: info.FilesUsed,
+	}
+}
+
+func (e *SnapshotEntry) limitsEqual(other *SnapshotEntry) bool {
+	return e.BytesSoft == other.BytesSoft && e.BytesHard == other.BytesHard &&
+		e.FilesSoft == other.FilesSoft && e.FilesHard == other.FilesHard
+}
+
+// Snapshot records the quota reports of a filesystem at a point in time
+type Snapshot struct {
+	Version   int       `json:"version"`
+	Host      string    `json:"host"`
+	Path      string    `json:"path"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Reports contains the entries of every quota type keyed by ID
+	Reports map[QuotaType]map[string]*SnapshotEntry `json:"reports"`
+}
+
+// newSnapshot creates a snapshot from reports
+func newSnapshot(host, path string, timestamp time.Time, reports map[QuotaType]*Report) *Snapshot {
+	snapshot := &Snapshot{
+		Version:   SnapshotVersion,
+		Host:      host,
+		Path:      path,
+		Timestamp: timestamp,
+		Reports:   make(map[QuotaType]map[string]*SnapshotEntry, len(reports)),
+	}
+
+	for t, report := range reports {
+		entries := make(map[string]*SnapshotEntry, len(report.Infos))
+		for id, info := range report.Infos {
+			entries[id] = newSnapshotEntry(info)
+		}
+		snapshot.Reports[t] = entries
+	}
+
+	return snapshot
+}
+
+// WriteSnapshot serializes a snapshot
+func WriteSnapshot(w io.Writer, snapshot *Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// ReadSnapshot deserializes a snapshot written by WriteSnapshot
+func ReadSnapshot(r io.Reader) (snapshot *Snapshot, err error) {
+	snapshot = &Snapshot{}
+	if err = json.NewDecoder(r).Decode(snapshot); err != nil {
+		snapshot = nil
+		return
+	}
+
+	if snapshot.Version != SnapshotVersion {
+		err = fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+		snapshot = nil
+	}
+	return
+}
+
+// SnapshotChange describes how an ID changed between two snapshots
+type SnapshotChange struct {
+	Type QuotaType
+	ID   string
+
+	// Entries of the earlier and later snapshot, Old is nil for new IDs and New is nil for removed IDs
+	Old *SnapshotEntry
+	New *SnapshotEntry
+
+	// Usage differences, new minus old
+	BytesUsedDelta int64
+	FilesUsedDelta int64
+
+	// LimitsChanged is true if any limit differs
+	LimitsChanged bool
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// DiffSnapshots compares two snapshots and returns every ID which changed.
+// Changes are sorted by the size of the change in byte usage, then file usage, largest first.
+func DiffSnapshots(before, after *Snapshot) (changes []*SnapshotChange) {
+	types := make(map[QuotaType]bool)
+	for t := range before.Reports {
+		types[t] = true
+	}
+	for t := range after.Reports {
+		types[t] = true
+	}
+
+	for t := range types {
+		oldEntries, newEntries := before.Reports[t], after.Reports[t]
+
+		ids := make(map[string]bool)
+		for id := range oldEntries {
+			ids[id] = true
+		}
+		for id := range newEntries {
+			ids[id] = true
+		}
+
+		for id := range ids {
+			change := &SnapshotChange{
+				Type: t,
+				ID:   id,
+				Old:  oldEntries[id],
+				New:  newEntries[id],
+			}
+
+			oldEntry, newEntry := change.Old, change.New
+			if oldEntry == nil {
+				oldEntry = &SnapshotEntry{}
+			}
+			if newEntry == nil {
+				newEntry = &SnapshotEntry{}
+			}
+
+			change.BytesUsedDelta = int64(newEntry.BytesUsed) - int64(oldEntry.BytesUsed)
+			change.FilesUsedDelta = int64(newEntry.FilesUsed) - int64(oldEntry.FilesUsed)
+			change.LimitsChanged = !oldEntry.limitsEqual(newEntry)
+
+			if change.Old != nil && change.New != nil && change.BytesUsedDelta == 0 && change.FilesUsedDelta == 0 && !change.LimitsChanged {
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if abs64(a.BytesUsedDelta) != abs64(b.BytesUsedDelta) {
+			return abs64(a.BytesUsedDelta) > abs64(b.BytesUsedDelta)
+		}
+		if abs64(a.FilesUsedDelta) != abs64(b.FilesUsedDelta) {
+			return abs64(a.FilesUsedDelta) > abs64(b.FilesUsedDelta)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+	return
+}
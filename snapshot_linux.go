@@ -0,0 +1,21 @@
+package fsquota
+
+import (
+	"os"
+	"time"
+)
+
+func takeSnapshot(path string) (snapshot *Snapshot, err error) {
+	var host string
+	if host, err = os.Hostname(); err != nil {
+		return
+	}
+
+	var reports map[QuotaType]*Report
+	if reports, err = getEnabledReports(path); err != nil {
+		return
+	}
+
+	snapshot = newSnapshot(host, path, time.Now().UTC(), reports)
+	return
+}
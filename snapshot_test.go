@@ -0,0 +1,76 @@
+package fsquota
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	info := &Info{BytesUsed: 100, FilesUsed: 2}
+	info.Bytes.SetHard(1000)
+	info.Files.SetSoft(10)
+
+	timestamp := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := newSnapshot("host", "/home", timestamp, map[QuotaType]*Report{
+		QuotaTypeGroup: {Infos: map[string]*Info{"100": info}},
+	})
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteSnapshot(buf, snapshot))
+	assert.Contains(t, buf.String(), `"group"`)
+
+	decoded, err := ReadSnapshot(buf)
+	require.NoError(t, err)
+	assert.EqualValues(t, snapshot, decoded)
+	assert.EqualValues(t, &SnapshotEntry{BytesHard: 1000, BytesUsed: 100, FilesSoft: 10, FilesUsed: 2}, decoded.Reports[QuotaTypeGroup]["100"])
+
+	_, err = ReadSnapshot(bytes.NewBufferString(`{"version": 2}`))
+	assert.Error(t, err)
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before := &Snapshot{Reports: map[QuotaType]map[string]*SnapshotEntry{
+		QuotaTypeUser: {
+			"1000": {BytesUsed: 100, BytesHard: 1000},
+			"1001": {BytesUsed: 100},
+			"1002": {BytesUsed: 50},
+			"1003": {BytesUsed: 10, FilesUsed: 1},
+		},
+	}}
+	after := &Snapshot{Reports: map[QuotaType]map[string]*SnapshotEntry{
+		QuotaTypeUser: {
+			"1000": {BytesUsed: 100, BytesHard: 2000},
+			"1001": {BytesUsed: 400},
+			"1003": {BytesUsed: 10, FilesUsed: 1},
+			"1004": {BytesUsed: 70},
+		},
+		QuotaTypeGroup: {
+			"100": {FilesUsed: 5},
+		},
+	}}
+
+	changes := DiffSnapshots(before, after)
+	require.Len(t, changes, 5)
+
+	assert.EqualValues(t, "1001", changes[0].ID)
+	assert.EqualValues(t, 300, changes[0].BytesUsedDelta)
+
+	assert.EqualValues(t, "1004", changes[1].ID)
+	assert.Nil(t, changes[1].Old)
+	assert.EqualValues(t, 70, changes[1].BytesUsedDelta)
+
+	assert.EqualValues(t, "1002", changes[2].ID)
+	assert.Nil(t, changes[2].New)
+	assert.EqualValues(t, -50, changes[2].BytesUsedDelta)
+
+	assert.EqualValues(t, QuotaTypeGroup, changes[3].Type)
+	assert.EqualValues(t, 5, changes[3].FilesUsedDelta)
+
+	assert.EqualValues(t, "1000", changes[4].ID)
+	assert.True(t, changes[4].LimitsChanged)
+	assert.EqualValues(t, 0, changes[4].BytesUsedDelta)
+}
@@ -0,0 +1,446 @@
+package fsquota
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// RPC protocol version as defined by RFC 5531
+	rpcVersion = 2
+
+	rpcMsgCall  = 0
+	rpcMsgReply = 1
+
+	rpcReplyAccepted = 0
+	rpcReplyDenied   = 1
+
+	rpcAuthNone = 0
+	rpcAuthSys  = 1
+)
+
+const (
+	rpcAcceptSuccess      = 0
+	rpcAcceptProgUnavail  = 1
+	rpcAcceptProgMismatch = 2
+	rpcAcceptProcUnavail  = 3
+	rpcAcceptGarbageArgs  = 4
+	rpcAcceptSystemErr    = 5
+)
+
+const (
+	// Program number of the portmapper
+	portmapProgram = 100000
+	// Version of the portmapper protocol
+	portmapVersion = 2
+	// PMAPPROC_SET
+	portmapProcSet = 1
+	// PMAPPROC_UNSET
+	portmapProcUnset = 2
+	// PMAPPROC_GETPORT
+	portmapProcGetPort = 3
+	// Well-known port of the portmapper
+	portmapPort = 111
+
+	// IPPROTO_TCP
+	portmapProtoTCP = 6
+	// IPPROTO_UDP
+	portmapProtoUDP = 17
+)
+
+// rpcLastFragment marks the last fragment of a record as used by record marking over TCP
+const rpcLastFragment = 0x80000000
+
+// maxRPCRecordLength limits the size of RPC records accepted
+const maxRPCRecordLength = 1024 * 1024
+
+// rpcTimeout defines how long a single RPC call may take
+const rpcTimeout = 10 * time.Second
+
+// rpcError is returned when a remote RPC call was rejected or failed
+type rpcError struct {
+	stat uint32
+	msg  string
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc: %s", e.msg)
+}
+
+func isRPCProgramMismatch(err error) bool {
+	rpcErr, isRPCErr := err.(*rpcError)
+	return isRPCErr && (rpcErr.stat == rpcAcceptProgMismatch || rpcErr.stat == rpcAcceptProgUnavail)
+}
+
+// rpcAuthSysCredentials builds an AUTH_SYS credential body for the current process
+func rpcAuthSysCredentials() []byte {
+	hostname, _ := os.Hostname()
+
+	enc := &xdrEncoder{}
+	enc.uint32(uint32(time.Now().Unix()))
+	enc.string(hostname)
+	enc.uint32(uint32(os.Getuid()))
+	enc.uint32(uint32(os.Getgid()))
+
+	gids, _ := os.Getgroups()
+	if len(gids) > 16 {
+		// AUTH_SYS allows at most 16 supplementary groups
+		gids = gids[:16]
+	}
+	enc.uint32(uint32(len(gids)))
+	for _, gid := range gids {
+		enc.uint32(uint32(gid))
+	}
+
+	return enc.bytes()
+}
+
+func encodeRPCCall(xid uint32, prog, vers, proc uint32, args []byte) []byte {
+	enc := &xdrEncoder{}
+	enc.uint32(xid)
+	enc.uint32(rpcMsgCall)
+	enc.uint32(rpcVersion)
+	enc.uint32(prog)
+	enc.uint32(vers)
+	enc.uint32(proc)
+
+	// Credentials
+	enc.uint32(rpcAuthSys)
+	enc.opaque(rpcAuthSysCredentials())
+
+	// Verifier
+	enc.uint32(rpcAuthNone)
+	enc.opaque(nil)
+
+	enc.buf.Write(args)
+	return enc.bytes()
+}
+
+func decodeRPCReply(xid uint32, reply []byte) (results *xdrDecoder, err error) {
+	dec := newXDRDecoder(reply)
+
+	replyXid := dec.uint32()
+	msgType := dec.uint32()
+	replyStat := dec.uint32()
+	if dec.err != nil {
+		err = dec.err
+		return
+	}
+
+	if replyXid != xid {
+		err = errors.New("rpc: reply transaction ID mismatch")
+		return
+	} else if msgType != rpcMsgReply {
+		err = errors.New("rpc: received message is not a reply")
+		return
+	}
+
+	if replyStat == rpcReplyDenied {
+		err = &rpcError{stat: dec.uint32(), msg: "call denied"}
+		return
+	} else if replyStat != rpcReplyAccepted {
+		err = fmt.Errorf("rpc: unknown reply status %d", replyStat)
+		return
+	}
+
+	// Skip the verifier
+	dec.uint32()
+	dec.opaque()
+
+	acceptStat := dec.uint32()
+	if dec.err != nil {
+		err = dec.err
+		return
+	}
+
+	switch acceptStat {
+	case rpcAcceptSuccess:
+		results = dec
+	case rpcAcceptProgUnavail:
+		err = &rpcError{stat: acceptStat, msg: "program unavailable"}
+	case rpcAcceptProgMismatch:
+		err = &rpcError{stat: acceptStat, msg: "program version mismatch"}
+	case rpcAcceptProcUnavail:
+		err = &rpcError{stat: acceptStat, msg: "procedure unavailable"}
+	case rpcAcceptGarbageArgs:
+		err = &rpcError{stat: acceptStat, msg: "garbage arguments"}
+	default:
+		err = &rpcError{stat: acceptStat, msg: "system error"}
+	}
+
+	return
+}
+
+func writeRPCRecord(w io.Writer, record []byte) (err error) {
+	header := make([]byte, 4, 4+len(record))
+	binary.BigEndian.PutUint32(header, rpcLastFragment|uint32(len(record)))
+	_, err = w.Write(append(header, record...))
+	return
+}
+
+func readRPCRecord(r io.Reader) (record []byte, err error) {
+	var header [4]byte
+	for {
+		if _, err = io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+
+		fragmentHeader := binary.BigEndian.Uint32(header[:])
+		fragmentLength := int(fragmentHeader &^ rpcLastFragment)
+		if len(record)+fragmentLength > maxRPCRecordLength {
+			err = errors.New("rpc: record too long")
+			return
+		}
+
+		fragment := make([]byte, fragmentLength)
+		if _, err = io.ReadFull(r, fragment); err != nil {
+			return
+		}
+		record = append(record, fragment...)
+
+		if fragmentHeader&rpcLastFragment != 0 {
+			return
+		}
+	}
+}
+
+// rpcClient is a minimal ONC RPC client using TCP transport
+type rpcClient struct {
+	mu   sync.Mutex
+	conn net.Conn
+	xid  uint32
+}
+
+func dialRPC(address string) (client *rpcClient, err error) {
+	var conn net.Conn
+	if conn, err = net.DialTimeout("tcp", address, rpcTimeout); err != nil {
+		return
+	}
+
+	client = &rpcClient{
+		conn: conn,
+		xid:  rand.Uint32(),
+	}
+	return
+}
+
+func (c *rpcClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *rpcClient) call(prog, vers, proc uint32, args []byte) (results *xdrDecoder, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.xid++
+	xid := c.xid
+
+	if err = c.conn.SetDeadline(time.Now().Add(rpcTimeout)); err != nil {
+		return
+	}
+
+	if err = writeRPCRecord(c.conn, encodeRPCCall(xid, prog, vers, proc, args)); err != nil {
+		return
+	}
+
+	var reply []byte
+	if reply, err = readRPCRecord(c.conn); err != nil {
+		return
+	}
+
+	return decodeRPCReply(xid, reply)
+}
+
+// rpcGetPort asks the portmapper at the given address for the TCP port of an RPC program.
+// A port of 0 indicates the program is not registered.
+func rpcGetPort(portmapAddress string, prog, vers uint32) (port uint16, err error) {
+	var client *rpcClient
+	if client, err = dialRPC(portmapAddress); err != nil {
+		return
+	}
+	defer client.Close()
+
+	args := &xdrEncoder{}
+	args.uint32(prog)
+	args.uint32(vers)
+	args.uint32(portmapProtoTCP)
+	args.uint32(0)
+
+	var results *xdrDecoder
+	if results, err = client.call(portmapProgram, portmapVersion, portmapProcGetPort, args.bytes()); err != nil {
+		return
+	}
+
+	port = uint16(results.uint32())
+	err = results.err
+	return
+}
+
+// dialRPCProgram looks up an RPC program via the portmapper on host and connects to it
+func dialRPCProgram(host string, portmapperPort uint16, prog, vers uint32) (client *rpcClient, err error) {
+	var port uint16
+	if port, err = rpcGetPort(net.JoinHostPort(host, strconv.Itoa(int(portmapperPort))), prog, vers); err != nil {
+		return
+	}
+
+	if port == 0 {
+		err = &rpcError{stat: rpcAcceptProgUnavail, msg: fmt.Sprintf("program %d version %d not registered", prog, vers)}
+		return
+	}
+
+	return dialRPC(net.JoinHostPort(host, strconv.Itoa(int(port))))
+}
+
+// rpcAuthSysParams contains the decoded contents of AUTH_SYS credentials
+type rpcAuthSysParams struct {
+	machineName string
+	uid         uint32
+	gid         uint32
+	gids        []uint32
+}
+
+func decodeRPCAuthSys(body []byte) (params *rpcAuthSysParams, err error) {
+	dec := newXDRDecoder(body)
+	dec.uint32() // stamp
+
+	p := &rpcAuthSysParams{
+		machineName: dec.string(),
+		uid:         dec.uint32(),
+		gid:         dec.uint32(),
+	}
+
+	gidCount := dec.uint32()
+	if gidCount > 16 {
+		err = errors.New("rpc: too many groups in credentials")
+		return
+	}
+
+	for i := uint32(0); i < gidCount; i++ {
+		p.gids = append(p.gids, dec.uint32())
+	}
+
+	if err = dec.err; err == nil {
+		params = p
+	}
+	return
+}
+
+// rpcCall contains a decoded incoming RPC call
+type rpcCall struct {
+	xid  uint32
+	prog uint32
+	vers uint32
+	proc uint32
+	// AUTH_SYS credentials, nil if the call used another flavor
+	authSys *rpcAuthSysParams
+	// Decoder positioned at the call's arguments
+	args *xdrDecoder
+}
+
+func decodeRPCCall(record []byte) (call *rpcCall, err error) {
+	dec := newXDRDecoder(record)
+
+	c := &rpcCall{
+		xid: dec.uint32(),
+	}
+
+	msgType := dec.uint32()
+	version := dec.uint32()
+	c.prog = dec.uint32()
+	c.vers = dec.uint32()
+	c.proc = dec.uint32()
+
+	credFlavor := dec.uint32()
+	credBody := dec.opaque()
+
+	// Skip the verifier
+	dec.uint32()
+	dec.opaque()
+
+	if err = dec.err; err != nil {
+		return
+	}
+
+	if msgType != rpcMsgCall {
+		err = errors.New("rpc: received message is not a call")
+		return
+	} else if version != rpcVersion {
+		err = fmt.Errorf("rpc: unsupported RPC version %d", version)
+		return
+	}
+
+	if credFlavor == rpcAuthSys {
+		if c.authSys, err = decodeRPCAuthSys(credBody); err != nil {
+			return
+		}
+	}
+
+	c.args = dec
+	call = c
+	return
+}
+
+// encodeRPCReply encodes an accepted reply with the given status.
+// results are only appended for rpcAcceptSuccess.
+func encodeRPCReply(xid uint32, acceptStat uint32, results []byte) []byte {
+	enc := &xdrEncoder{}
+	enc.uint32(xid)
+	enc.uint32(rpcMsgReply)
+	enc.uint32(rpcReplyAccepted)
+
+	// Verifier
+	enc.uint32(rpcAuthNone)
+	enc.opaque(nil)
+
+	enc.uint32(acceptStat)
+	enc.buf.Write(results)
+	return enc.bytes()
+}
+
+// encodeRPCProgMismatchReply encodes a reply indicating the supported version range of a program
+func encodeRPCProgMismatchReply(xid uint32, low, high uint32) []byte {
+	versions := &xdrEncoder{}
+	versions.uint32(low)
+	versions.uint32(high)
+	return encodeRPCReply(xid, rpcAcceptProgMismatch, versions.bytes())
+}
+
+// rpcPortmapSet registers (or with unset, unregisters) a program with the local portmapper
+func rpcPortmapSet(unset bool, prog, vers, proto uint32, port uint16) (err error) {
+	var client *rpcClient
+	if client, err = dialRPC(net.JoinHostPort("127.0.0.1", strconv.Itoa(portmapPort))); err != nil {
+		return
+	}
+	defer client.Close()
+
+	proc := uint32(portmapProcSet)
+	if unset {
+		proc = portmapProcUnset
+	}
+
+	args := &xdrEncoder{}
+	args.uint32(prog)
+	args.uint32(vers)
+	args.uint32(proto)
+	args.uint32(uint32(port))
+
+	var results *xdrDecoder
+	if results, err = client.call(portmapProgram, portmapVersion, proc, args.bytes()); err != nil {
+		return
+	}
+
+	if success := results.bool(); results.err != nil {
+		err = results.err
+	} else if !success && !unset {
+		err = fmt.Errorf("rpc: portmapper refused registration of program %d version %d", prog, vers)
+	}
+	return
+}
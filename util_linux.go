@@ -2,6 +2,7 @@ package fsquota
 
 import (
 	"bufio"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -44,6 +45,7 @@ func isKernel46OrLater() (is46OrLater bool, err error) {
 
 const passwdFile = "/etc/passwd"
 const groupFile = "/etc/group"
+const projidFile = "/etc/projid"
 
 func getIDsFromUserOrGroupFile(path string) (ids []uint32, err error) {
 	var f *os.File
@@ -53,7 +55,12 @@ func getIDsFromUserOrGroupFile(path string) (ids []uint32, err error) {
 	}
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	return readIDsFromUserOrGroupFile(f)
+}
+
+// readIDsFromUserOrGroupFile reads the IDs of an /etc/passwd or /etc/group formatted file
+func readIDsFromUserOrGroupFile(r io.Reader) (ids []uint32, err error) {
+	scanner := bufio.NewScanner(r)
 	scanner.Split(bufio.ScanLines)
 
 	for scanner.Scan() {
@@ -75,5 +82,62 @@ func getIDsFromUserOrGroupFile(path string) (ids []uint32, err error) {
 		}
 	}
 
+	err = scanner.Err()
+	return
+}
+
+func getIDsFromProjidFile(path string) (ids []uint32, err error) {
+	var f *os.File
+
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanLines)
+
+	for scanner.Scan() {
+		// Lines are in name:id format
+		lineParts := strings.Split(scanner.Text(), ":")
+		if len(lineParts) != 2 {
+			continue
+		}
+
+		var id uint64
+		var parseErr error
+		if id, parseErr = strconv.ParseUint(lineParts[1], 10, 32); parseErr == nil {
+			ids = append(ids, uint32(id))
+		}
+	}
+
+	return
+}
+
+// getProjectsFromProjidFile reads the IDs of all projects keyed by name
+func getProjectsFromProjidFile(path string) (ids map[string]string, err error) {
+	var f *os.File
+
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+
+	ids = make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanLines)
+
+	for scanner.Scan() {
+		// Lines are in name:id format
+		lineParts := strings.Split(scanner.Text(), ":")
+		if len(lineParts) != 2 {
+			continue
+		}
+
+		if _, parseErr := strconv.ParseUint(lineParts[1], 10, 32); parseErr == nil {
+			ids[lineParts[0]] = lineParts[1]
+		}
+	}
+
 	return
 }
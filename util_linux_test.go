@@ -46,3 +46,52 @@ ignored:ignored:1000:ok1000
 
 	})
 }
+
+func TestGetIDsFromProjidFile(t *testing.T) {
+	dirName, err := ioutil.TempDir("", "fsquota-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirName)
+
+	fileData := `
+# comment
+invalid
+project:unparsable
+web:10
+too:many:parts
+db:42
+`
+
+	fileName := filepath.Join(dirName, "projid")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte(fileData), 0640))
+
+	ids, err := getIDsFromProjidFile(fileName)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []uint32{10, 42}, ids)
+}
+
+func TestGetProjectsFromProjidFile(t *testing.T) {
+	dirName, err := ioutil.TempDir("", "fsquota-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dirName)
+
+	fileData := `
+# comment
+project:unparsable
+web:10
+too:many:parts
+db:42
+`
+
+	fileName := filepath.Join(dirName, "projid")
+	require.NoError(t, ioutil.WriteFile(fileName, []byte(fileData), 0640))
+
+	ids, err := getProjectsFromProjidFile(fileName)
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[string]string{"web": "10", "db": "42"}, ids)
+}
+
+func TestSortIDs(t *testing.T) {
+	ids := []string{"1000", "2", "10", "1"}
+	sortIDs(ids)
+	assert.EqualValues(t, []string{"1", "2", "10", "1000"}, ids)
+}
@@ -0,0 +1,84 @@
+package fsquota
+
+import "fmt"
+
+// WarningKind describes which limit a quota warning refers to
+type WarningKind uint32
+
+const (
+	// WarningNone indicates no warning
+	WarningNone WarningKind = 0
+	// WarningFilesHard indicates the file hard limit has been reached
+	WarningFilesHard WarningKind = 1
+	// WarningFilesSoftLong indicates the file soft limit has been exceeded longer than the grace period
+	WarningFilesSoftLong WarningKind = 2
+	// WarningFilesSoft indicates the file soft limit has been exceeded
+	WarningFilesSoft WarningKind = 3
+	// WarningBytesHard indicates the byte hard limit has been reached
+	WarningBytesHard WarningKind = 4
+	// WarningBytesSoftLong indicates the byte soft limit has been exceeded longer than the grace period
+	WarningBytesSoftLong WarningKind = 5
+	// WarningBytesSoft indicates the byte soft limit has been exceeded
+	WarningBytesSoft WarningKind = 6
+	// WarningFilesHardBelow indicates usage dropped below the file hard limit
+	WarningFilesHardBelow WarningKind = 7
+	// WarningFilesSoftBelow indicates usage dropped below the file soft limit
+	WarningFilesSoftBelow WarningKind = 8
+	// WarningBytesHardBelow indicates usage dropped below the byte hard limit
+	WarningBytesHardBelow WarningKind = 9
+	// WarningBytesSoftBelow indicates usage dropped below the byte soft limit
+	WarningBytesSoftBelow WarningKind = 10
+)
+
+var warningKindDescriptions = map[WarningKind]string{
+	WarningNone:           "no warning",
+	WarningFilesHard:      "file hard limit reached",
+	WarningFilesSoftLong:  "file soft limit exceeded for too long",
+	WarningFilesSoft:      "file soft limit exceeded",
+	WarningBytesHard:      "byte hard limit reached",
+	WarningBytesSoftLong:  "byte soft limit exceeded for too long",
+	WarningBytesSoft:      "byte soft limit exceeded",
+	WarningFilesHardBelow: "file usage dropped below hard limit",
+	WarningFilesSoftBelow: "file usage dropped below soft limit",
+	WarningBytesHardBelow: "byte usage dropped below hard limit",
+	WarningBytesSoftBelow: "byte usage dropped below soft limit",
+}
+
+// String returns a human-readable description of the warning kind
+func (k WarningKind) String() string {
+	if description, ok := warningKindDescriptions[k]; ok {
+		return description
+	}
+
+	return fmt.Sprintf("unknown warning (%d)", uint32(k))
+}
+
+// QuotaWarning contains a quota warning as broadcast by the kernel
+type QuotaWarning struct {
+	// Type of the quota the warning refers to
+	Type QuotaType
+	// ID of the user, group or project whose quota was exceeded
+	ID uint64
+	// Kind of warning
+	Kind WarningKind
+	// Major number of the device the quota belongs to
+	DeviceMajor uint32
+	// Minor number of the device the quota belongs to
+	DeviceMinor uint32
+	// UID of the process which caused the warning
+	CallerUID uint64
+}
+
+// QuotaWarningWatcher delivers the quota warnings broadcast by the kernel
+type QuotaWarningWatcher struct {
+	// Warnings receives every warning, it is closed once the context is done or receiving fails
+	Warnings <-chan *QuotaWarning
+
+	err error
+}
+
+// Err returns the error receiving warnings failed with, once Warnings has been closed.
+// It is nil if listening ended because the context is done.
+func (w *QuotaWarningWatcher) Err() error {
+	return w.err
+}
@@ -0,0 +1,138 @@
+package fsquota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Name of the generic netlink family used by the kernel for quota warnings
+	quotaNlFamilyName = "VFS_DQUOT"
+	// Name of the multicast group quota warnings are sent to
+	quotaNlGroupName = "events"
+)
+
+const (
+	// QUOTA_NL_C_WARNING
+	quotaNlCmdWarning = 1
+)
+
+const (
+	// QUOTA_NL_A_QTYPE
+	quotaNlAttrQType = 1
+	// QUOTA_NL_A_EXCESS_ID
+	quotaNlAttrExcessID = 2
+	// QUOTA_NL_A_WARNING
+	quotaNlAttrWarning = 3
+	// QUOTA_NL_A_DEV_MAJOR
+	quotaNlAttrDevMajor = 4
+	// QUOTA_NL_A_DEV_MINOR
+	quotaNlAttrDevMinor = 5
+	// QUOTA_NL_A_CAUSED_ID
+	quotaNlAttrCausedID = 6
+)
+
+// warningPollInterval defines how often the warning listener checks for context cancellation
+const warningPollInterval = 250 * time.Millisecond
+
+func quotaWarningFromAttrs(attrs []nlAttr) (warning *QuotaWarning) {
+	warning = &QuotaWarning{}
+
+	for _, attr := range attrs {
+		switch attr.typ {
+		case quotaNlAttrQType:
+			warning.Type = QuotaType(attr.uint32())
+		case quotaNlAttrExcessID:
+			warning.ID = attr.uint64()
+		case quotaNlAttrWarning:
+			warning.Kind = WarningKind(attr.uint32())
+		case quotaNlAttrDevMajor:
+			warning.DeviceMajor = attr.uint32()
+		case quotaNlAttrDevMinor:
+			warning.DeviceMinor = attr.uint32()
+		case quotaNlAttrCausedID:
+			warning.CallerUID = attr.uint64()
+		}
+	}
+
+	return
+}
+
+func watchQuotaWarnings(ctx context.Context) (watcher *QuotaWarningWatcher, err error) {
+	var conn *genlConn
+	if conn, err = dialGenl(); err != nil {
+		return
+	}
+
+	var family *genlFamily
+	if family, err = conn.resolveFamily(quotaNlFamilyName); err != nil {
+		conn.Close()
+		err = fmt.Errorf("resolving %s family: %s", quotaNlFamilyName, err)
+		return
+	}
+
+	group, groupFound := family.groups[quotaNlGroupName]
+	if !groupFound {
+		conn.Close()
+		err = errors.New("quota warning multicast group not found")
+		return
+	}
+
+	if err = conn.joinGroup(group); err != nil {
+		conn.Close()
+		return
+	}
+
+	// Use a receive timeout so cancellation of the context is noticed
+	timeout := unix.NsecToTimeval(warningPollInterval.Nanoseconds())
+	if err = unix.SetsockoptTimeval(conn.fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		conn.Close()
+		err = os.NewSyscallError("setsockopt", err)
+		return
+	}
+
+	ch := make(chan *QuotaWarning)
+	watcher = &QuotaWarningWatcher{
+		Warnings: ch,
+	}
+	go func() {
+		defer close(ch)
+		defer conn.Close()
+
+		buf := make([]byte, os.Getpagesize())
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+
+			messages, recvErr := conn.receive(buf)
+			if recvErr == unix.EAGAIN || recvErr == unix.EINTR || recvErr == unix.ENOBUFS {
+				// Timeout, interrupted or dropped messages: keep listening
+				continue
+			} else if recvErr != nil {
+				// Set before the channel is closed, so it is visible to receivers seeing the close
+				watcher.err = recvErr
+				return
+			}
+
+			for _, message := range messages {
+				if message.typ != family.id || message.cmd != quotaNlCmdWarning {
+					continue
+				}
+
+				select {
+				case ch <- quotaWarningFromAttrs(message.attrs):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return
+}
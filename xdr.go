@@ -0,0 +1,119 @@
+package fsquota
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// maxXDROpaqueLength limits the size of variable-length XDR data accepted when decoding
+const maxXDROpaqueLength = 1024 * 1024
+
+var errXDRShortBuffer = errors.New("xdr: unexpected end of data")
+
+// xdrEncoder implements the subset of XDR (RFC 4506) encoding required by the RPC protocols in this package
+type xdrEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *xdrEncoder) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *xdrEncoder) int32(v int32) {
+	e.uint32(uint32(v))
+}
+
+func (e *xdrEncoder) bool(v bool) {
+	if v {
+		e.uint32(1)
+	} else {
+		e.uint32(0)
+	}
+}
+
+func (e *xdrEncoder) opaque(v []byte) {
+	e.uint32(uint32(len(v)))
+	e.buf.Write(v)
+
+	// Pad to a multiple of four bytes
+	if padding := (4 - len(v)%4) % 4; padding > 0 {
+		e.buf.Write(make([]byte, padding))
+	}
+}
+
+func (e *xdrEncoder) string(v string) {
+	e.opaque([]byte(v))
+}
+
+func (e *xdrEncoder) bytes() []byte {
+	return e.buf.Bytes()
+}
+
+// xdrDecoder implements the subset of XDR (RFC 4506) decoding required by the RPC protocols in this package
+type xdrDecoder struct {
+	data []byte
+	err  error
+}
+
+func newXDRDecoder(data []byte) *xdrDecoder {
+	return &xdrDecoder{
+		data: data,
+	}
+}
+
+func (d *xdrDecoder) uint32() (v uint32) {
+	if d.err != nil {
+		return
+	}
+
+	if len(d.data) < 4 {
+		d.err = errXDRShortBuffer
+		return
+	}
+
+	v = binary.BigEndian.Uint32(d.data)
+	d.data = d.data[4:]
+	return
+}
+
+func (d *xdrDecoder) int32() int32 {
+	return int32(d.uint32())
+}
+
+func (d *xdrDecoder) bool() bool {
+	return d.uint32() != 0
+}
+
+func (d *xdrDecoder) opaque() (v []byte) {
+	length := d.uint32()
+	if d.err != nil {
+		return
+	}
+
+	if length > maxXDROpaqueLength {
+		d.err = errors.New("xdr: opaque data too long")
+		return
+	}
+
+	paddedLength := int(length) + (4-int(length)%4)%4
+	if len(d.data) < paddedLength {
+		d.err = errXDRShortBuffer
+		return
+	}
+
+	v = d.data[:length]
+	d.data = d.data[paddedLength:]
+	return
+}
+
+func (d *xdrDecoder) string() string {
+	return string(d.opaque())
+}
+
+// remaining returns the data which has not been decoded yet
+func (d *xdrDecoder) remaining() []byte {
+	return d.data
+}